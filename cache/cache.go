@@ -0,0 +1,304 @@
+// Package cache makes repeated conversions of a large code base
+// incremental. A run records the content hashes of its sources, and of the
+// files it wrote together with the sources each was generated from, in a
+// manifest below the cache directory (cache in java2go.yaml, .java2go/cache
+// by default). Each command, set of input roots and set of output
+// directories has its own manifest, keyed on the configuration and the
+// java2go version, so that changing either converts everything again.
+//
+// A run whose sources are unchanged and whose outputs are still as written
+// is skipped. Otherwise only the services whose sources changed are
+// generated again, e.g. the proto of a controller that changed or that
+// embeds a VO that changed, and only the outputs whose content changed are
+// written. Adding or removing a source may change how the types of the
+// others resolve, so it generates every service again. With --prune, the
+// files generated from a source that was deleted since, e.g. the proto of a
+// deleted controller, are removed unless they were edited.
+package cache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/ir"
+	"gopkg.in/yaml.v3"
+)
+
+// Disabled turns the cache off, set by --no_cache.
+var Disabled bool
+
+// Prune removes the files generated from deleted sources, set by --prune.
+var Prune bool
+
+// A manifest is what the cache keeps of a run.
+type manifest struct {
+	Key     string             `json:"key"`
+	Inputs  map[string]string  `json:"inputs"`  // content hashes by source path.
+	Outputs map[string]*output `json:"outputs"` // the written files by path.
+}
+
+// An output is a file written by a run.
+type output struct {
+	Hash    string   `json:"hash"`
+	Sources []string `json:"sources,omitempty"` // see gen.GeneratedFile.Sources.
+}
+
+// A Cache is the manifest of the last run of a command and the one being
+// built by the current run. The methods of a nil Cache do no caching.
+type Cache struct {
+	path    string
+	last    manifest
+	cur     manifest
+	written map[string]bool // whether the outputs of the last run are unedited, by path.
+}
+
+// Open opens the cache of the run named by name: the command, its input
+// roots and its output directories. It returns nil if the cache is disabled.
+func Open(cfg *config.Config, name ...string) (*Cache, error) {
+	if Disabled || cfg.Cache == "" {
+		return nil, nil
+	}
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cache{
+		path: filepath.Join(cfg.Cache, sum([]byte(strings.Join(name, "\x00")))[:16]+".json"),
+		cur: manifest{
+			Key:     sum(append([]byte(gen.Version+"\x00"), b...)),
+			Inputs:  make(map[string]string),
+			Outputs: make(map[string]*output),
+		},
+		written: make(map[string]bool),
+	}
+	b, err = os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(b, &c.last); err != nil {
+		fmt.Println("缓存已损坏，重新转换：" + c.path)
+		c.last = manifest{}
+	}
+	return c, nil
+}
+
+// Hash records the content hashes of the sources of the run. The readers
+// of srcs are rewound, or replaced if they cannot seek, so that the
+// sources can still be parsed.
+func (c *Cache) Hash(srcs []gen.Source) error {
+	if c == nil {
+		return nil
+	}
+	for i := range srcs {
+		b, err := io.ReadAll(srcs[i].R)
+		if err != nil {
+			return err
+		}
+		if r, ok := srcs[i].R.(io.Seeker); ok {
+			if _, err = r.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		} else {
+			srcs[i].R = bytes.NewReader(b)
+		}
+		c.cur.Inputs[srcs[i].Path] = sum(b)
+	}
+	return nil
+}
+
+// Unchanged reports whether the sources hashed, the configuration and
+// java2go are those of the last run, and the files it wrote are still
+// there unedited. The outputs of the last run are then kept for this one.
+func (c *Cache) Unchanged() bool {
+	if c == nil || !c.same() || c.changed(nil) {
+		return false
+	}
+	for path := range c.last.Outputs {
+		if !c.unedited(path) {
+			return false
+		}
+	}
+	c.cur.Outputs = c.last.Outputs
+	return true
+}
+
+// Fresh reports whether the files generated from the sources srcs need not
+// be generated again: the sources are unchanged since the last run, which
+// generated files from exactly these sources, and the files are still
+// there unedited.
+func (c *Cache) Fresh(srcs []string) bool {
+	if c == nil || len(srcs) == 0 || !c.same() || c.changed(srcs) {
+		return false
+	}
+	key := strings.Join(srcs, "\x00")
+	found := false
+	for path, o := range c.last.Outputs {
+		if strings.Join(o.Sources, "\x00") != key {
+			continue
+		}
+		if !c.unedited(path) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// Stale returns a copy of m holding only the services whose files must be
+// generated again, see Fresh.
+func (c *Cache) Stale(m *ir.Model) *ir.Model {
+	stale := *m
+	stale.Services = make([]*ir.Service, 0, len(m.Services))
+	for _, svc := range m.Services {
+		if !c.Fresh(svc.Sources()) {
+			stale.Services = append(stale.Services, svc)
+		}
+	}
+	return &stale
+}
+
+// same reports whether the configuration, java2go and the set of sources
+// are those of the last run.
+func (c *Cache) same() bool {
+	if c.last.Key != c.cur.Key || len(c.last.Inputs) != len(c.cur.Inputs) {
+		return false
+	}
+	for path := range c.cur.Inputs {
+		if _, ok := c.last.Inputs[path]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// changed reports whether one of the sources srcs, or of all the sources
+// hashed if srcs is empty, changed since the last run. A source that was
+// not hashed counts as changed.
+func (c *Cache) changed(srcs []string) bool {
+	if len(srcs) == 0 {
+		for path := range c.cur.Inputs {
+			srcs = append(srcs, path)
+		}
+	}
+	for _, path := range srcs {
+		if h, ok := c.cur.Inputs[path]; !ok || c.last.Inputs[path] != h {
+			return true
+		}
+	}
+	return false
+}
+
+// unedited reports whether the file at path is one the last run wrote and
+// is still as written.
+func (c *Cache) unedited(path string) bool {
+	ok, seen := c.written[path]
+	if !seen {
+		if o := c.last.Outputs[path]; o != nil {
+			b, err := os.ReadFile(path)
+			ok = err == nil && sum(b) == o.Hash
+		}
+		c.written[path] = ok
+	}
+	return ok
+}
+
+// WriteFiles writes the generated files below dir like gen.WriteFiles,
+// but skips the files whose content is the one written last time. A file
+// java2go wrote and nobody edited since is replaced whatever the overwrite
+// policy; other existing files are handled according to it.
+func (c *Cache) WriteFiles(dir string, files []*gen.GeneratedFile, overwrite string) error {
+	if c == nil {
+		return gen.WriteFiles(dir, files, overwrite)
+	}
+	if dir == "" {
+		dir = "./"
+	}
+	kept := 0
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name)
+		o := &output{Hash: sum(file.Content()), Sources: file.Sources}
+		policy := overwrite
+		if c.unedited(path) {
+			if o.Hash == c.last.Outputs[path].Hash {
+				c.cur.Outputs[path] = o
+				kept++
+				continue
+			}
+			policy = config.OverwriteAlways
+		}
+		if err := gen.WriteFiles(dir, []*gen.GeneratedFile{file}, policy); err != nil {
+			return err
+		}
+		if b, err := os.ReadFile(path); err == nil && sum(b) == o.Hash {
+			c.cur.Outputs[path] = o
+		}
+	}
+	if kept > 0 {
+		fmt.Printf("%d 个文件没有变化：%s\n", kept, dir)
+	}
+	return nil
+}
+
+// Save writes the manifest of the run, after its files were written. The
+// unedited files of the last run that this one did not write are kept if
+// they are fresh. The others are no longer tracked, and removed if Prune
+// is set and the source they were generated from was deleted.
+func (c *Cache) Save() error {
+	if c == nil {
+		return nil
+	}
+	for path, o := range c.last.Outputs {
+		if c.cur.Outputs[path] != nil || !c.unedited(path) {
+			continue
+		}
+		if c.Fresh(o.Sources) {
+			c.cur.Outputs[path] = o
+			continue
+		}
+		if !Prune || !c.deleted(o.Sources) {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		fmt.Println("源码已删除，删除生成的文件：" + path)
+	}
+	b, err := json.MarshalIndent(c.cur, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, b, 0644)
+}
+
+// deleted reports whether the source a file was generated from, the first
+// of srcs, was deleted: it is no longer a source of the run nor a file.
+func (c *Cache) deleted(srcs []string) bool {
+	if len(srcs) == 0 {
+		return false
+	}
+	if _, ok := c.cur.Inputs[srcs[0]]; ok {
+		return false
+	}
+	_, err := os.Stat(srcs[0])
+	return os.IsNotExist(err)
+}
+
+func sum(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+)
+
+func file(name, content string, srcs ...string) *gen.GeneratedFile {
+	f := gen.NewGeneratedFile()
+	f.Name = name
+	f.Sources = srcs
+	f.P(content)
+	return f
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.Cache = filepath.Join(dir, "cache")
+	out := filepath.Join(dir, "api")
+	if err := os.Mkdir(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	read := func(name string) string {
+		b, _ := os.ReadFile(filepath.Join(out, name))
+		return string(b)
+	}
+	// run converts the sources into the files, as a command would: a.proto
+	// is generated from A.java, b.proto from B.java if there is one, and
+	// the files that are fresh are left out. It returns the names of the
+	// files generated, or nil if the run was skipped.
+	run := func(srcs map[string]string, a, b string) []string {
+		t.Helper()
+		c, err := Open(cfg, "test", out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		in := make([]gen.Source, 0)
+		for _, path := range []string{"A.java", "B.java"} {
+			if src, ok := srcs[path]; ok {
+				in = append(in, gen.Source{Path: path, R: bytes.NewReader([]byte(src))})
+			}
+		}
+		if err = c.Hash(in); err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := io.ReadAll(in[0].R); string(b) != srcs["A.java"] {
+			t.Fatalf("source after Hash = %q", b)
+		}
+		if c.Unchanged() {
+			return nil
+		}
+		files := make([]*gen.GeneratedFile, 0)
+		if !c.Fresh([]string{"A.java"}) {
+			files = append(files, file("a.proto", a, "A.java"))
+		}
+		if _, ok := srcs["B.java"]; ok && !c.Fresh([]string{"B.java"}) {
+			files = append(files, file("b.proto", b, "B.java"))
+		}
+		if err = c.WriteFiles(out, files, cfg.Overwrite); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Save(); err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0)
+		for _, f := range files {
+			names = append(names, f.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+	check := func(names []string, want string) {
+		t.Helper()
+		if got := strings.Join(names, ","); names == nil || got != want {
+			t.Errorf("generated %q, want %q", got, want)
+		}
+	}
+
+	srcs := map[string]string{"A.java": "class A {}", "B.java": "class B {}"}
+	check(run(srcs, "a1", "b1"), "a.proto,b.proto")
+	if run(srcs, "a1", "b1") != nil {
+		t.Error("unchanged run not skipped")
+	}
+
+	// only the file of the source that changed is generated again, and a
+	// file java2go wrote is refreshed even though the policy is skip.
+	srcs["A.java"] = "class A { int x; }"
+	check(run(srcs, "a2", "b2"), "a.proto")
+	if read("a.proto") != "a2\n" || read("b.proto") != "b1\n" {
+		t.Errorf("outputs = %q, %q", read("a.proto"), read("b.proto"))
+	}
+	if run(srcs, "a2", "b2") != nil {
+		t.Error("run after partial conversion not skipped")
+	}
+
+	// an edited file is generated again but kept.
+	if err := os.WriteFile(filepath.Join(out, "b.proto"), []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check(run(srcs, "a2", "b2"), "b.proto")
+	if read("b.proto") != "edited\n" {
+		t.Errorf("edited output = %q", read("b.proto"))
+	}
+
+	// another configuration converts everything again.
+	cfg.Overwrite = config.OverwriteAlways
+	check(run(srcs, "a2", "b2"), "a.proto,b.proto")
+	if read("b.proto") != "b2\n" {
+		t.Errorf("output = %q", read("b.proto"))
+	}
+
+	// the file of a deleted source is kept, unless pruning.
+	delete(srcs, "B.java")
+	check(run(srcs, "a2", "b2"), "a.proto")
+	if read("b.proto") != "b2\n" {
+		t.Errorf("output of deleted source = %q", read("b.proto"))
+	}
+	if run(srcs, "a2", "b2") != nil {
+		t.Error("run after deletion not skipped")
+	}
+	srcs["B.java"] = "class B {}"
+	check(run(srcs, "a2", "b2"), "a.proto,b.proto")
+	delete(srcs, "B.java")
+	defer func(prune bool) { Prune = prune }(Prune)
+	Prune = true
+	check(run(srcs, "a2", "b2"), "a.proto")
+	if _, err := os.Stat(filepath.Join(out, "b.proto")); !os.IsNotExist(err) {
+		t.Errorf("output of deleted source not pruned: %v", err)
+	}
+}
+
+// TestDisjointInputs converts two sets of sources into one directory.
+func TestDisjointInputs(t *testing.T) {
+	defer func(prune bool) { Prune = prune }(Prune)
+	Prune = true
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.Cache = filepath.Join(dir, "cache")
+	out := filepath.Join(dir, "api")
+	if err := os.Mkdir(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	run := func(root, name string) {
+		t.Helper()
+		c, err := Open(cfg, "test", root, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := filepath.Join(root, name+".java")
+		if err = c.Hash([]gen.Source{{Path: src, R: strings.NewReader("class " + name + " {}")}}); err != nil {
+			t.Fatal(err)
+		}
+		if c.Unchanged() {
+			return
+		}
+		if err = c.WriteFiles(out, []*gen.GeneratedFile{file(name+".proto", name, src)}, cfg.Overwrite); err != nil {
+			t.Fatal(err)
+		}
+		if err = c.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("a", "A")
+	run("b", "B")
+	run("a", "A")
+	for _, name := range []string{"A.proto", "B.proto"} {
+		if _, err := os.Stat(filepath.Join(out, name)); err != nil {
+			t.Errorf("output of the other sources removed: %v", err)
+		}
+	}
+}
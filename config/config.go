@@ -0,0 +1,324 @@
+// Package config reads java2go.yaml, the project configuration shared by
+// all commands.
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// File is the configuration file read by the commands, set by --config.
+var File = "java2go.yaml"
+
+// Overwrite policies for generated files that already exist.
+const (
+	OverwriteSkip   = "skip"      // keep the existing file.
+	OverwriteAlways = "overwrite" // replace the existing file.
+	OverwriteFail   = "fail"      // stop with an error.
+)
+
+// Strategies for renaming controller methods whose RPC names clash.
+const (
+	RenamePath   = "path"   // append the last segment of the mapping path.
+	RenameParams = "params" // append the parameter types.
+)
+
+// A Config is the content of java2go.yaml.
+type Config struct {
+	Ctl       Ctl     `yaml:"ctl"`
+	Do        Do      `yaml:"do"`
+	Sql       Sql     `yaml:"sql"`
+	Service   Service `yaml:"service"`
+	Mapper    Mapper  `yaml:"mapper"`
+	Proto     Proto   `yaml:"proto"`
+	Go        Go      `yaml:"go"`
+	Types     Types   `yaml:"types"`
+	Naming    Naming  `yaml:"naming"`
+	Audit     Audit   `yaml:"audit"`
+	Overwrite string  `yaml:"overwrite"`
+	// Cache is the directory of the incremental conversion cache, see
+	// package cache. Empty disables the cache.
+	Cache string `yaml:"cache"`
+}
+
+// Ctl configures the ctl command.
+type Ctl struct {
+	Controllers []string `yaml:"controllers"` // controller source roots.
+	Models      []string `yaml:"models"`      // VO and request source roots.
+	// Sources are further roots searched for the classes referenced by the
+	// controllers, e.g. the api and common modules of a Maven project.
+	Sources []string `yaml:"sources"`
+	// APIDocs are saved Swagger 2 or OpenAPI 3 JSON documents, e.g. of
+	// /v2/api-docs, read instead of the sources if set.
+	APIDocs []string `yaml:"api_docs"`
+	// Include and Exclude filter the files below every root, see gen.Filter.
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+	Output  string   `yaml:"output"`
+	// Service is the directory of the Kratos service stubs, which are
+	// only generated if it is set.
+	Service string `yaml:"service"`
+	// OpenAPI is the file of the OpenAPI document of the controllers,
+	// which is only generated if it is set. A .json file is written as
+	// JSON, any other as YAML.
+	OpenAPI string `yaml:"openapi"`
+	// Rename is the strategy for duplicate RPC names, see RenamePath.
+	Rename string `yaml:"rename"`
+}
+
+// Do configures the do command.
+type Do struct {
+	Sources []string `yaml:"sources"`
+	Output  string   `yaml:"output"`
+	// Repo is the directory of the Kratos data-layer repositories, which
+	// are only generated if it is set.
+	Repo string `yaml:"repo"`
+	// Convert is the directory of the functions converting between the
+	// messages of VOs and requests and the ent entities of DOs, and of the
+	// MapStruct mappers, which the project command only generates if it is
+	// set.
+	Convert string `yaml:"convert"`
+}
+
+// Sql configures the sql command.
+type Sql struct {
+	Sources []string `yaml:"sources"`
+	Output  string   `yaml:"output"`
+}
+
+// Service configures the service command.
+type Service struct {
+	Sources []string `yaml:"sources"` // service interface and implementation roots.
+	Models  []string `yaml:"models"`  // roots of the classes used by the services.
+	Output  string   `yaml:"output"`
+}
+
+// Mapper configures the mapper command.
+type Mapper struct {
+	Sources []string `yaml:"sources"` // roots of the mapper XML files and interfaces.
+	Models  []string `yaml:"models"`  // roots of the DO and VO classes used by the mappers.
+	Output  string   `yaml:"output"`
+}
+
+// Proto holds the templates of the generated protobuf options. {segment}
+// is replaced by the first segment of the controller's @RequestMapping,
+// {module} by the Go module path and {package} by the Go package name of
+// the controller's source directory (see Go), or the segment if there is
+// none. The defaults use {segment}: every controller of a module in one
+// proto package only works if their message names do not clash.
+type Proto struct {
+	Package     string `yaml:"package"`
+	GoPackage   string `yaml:"go_package"`
+	JavaPackage string `yaml:"java_package"`
+}
+
+// Go configures the Go side of the output.
+type Go struct {
+	// Module is the Go module path of the output, e.g. example.com/demo.
+	// The project command derives it from pom.xml or settings.gradle when
+	// it is empty.
+	Module string `yaml:"module"`
+	// Packages maps source directories, e.g. Maven modules, to Go package
+	// names. The project command derives them from the build files too.
+	Packages map[string]string `yaml:"packages"`
+	// SchemaPackage is the package name of the generated ent schemas.
+	SchemaPackage string `yaml:"schema_package"`
+	// EntPackage is the import path of the ent code generated from the
+	// schemas, used by the data-layer repositories. {module} is replaced by
+	// Module.
+	EntPackage string `yaml:"ent_package"`
+}
+
+// Types overrides the built-in type mapping. Keys are Java type names or
+// SQL column types, values are IR kinds such as int64, string or time.
+type Types struct {
+	Java map[string]string `yaml:"java"`
+	Sql  map[string]string `yaml:"sql"`
+}
+
+// Naming configures how class and table names are turned into generated
+// names, see naming.Rule. Messages strips nothing by default: services and
+// messages share the names of a proto package, so DeviceVO stripped to
+// Device would take the name of the service of DeviceController.
+type Naming struct {
+	Services naming.Rule `yaml:"services"` // controller classes -> proto services.
+	Messages naming.Rule `yaml:"messages"` // VO and request classes -> proto messages.
+	Entities naming.Rule `yaml:"entities"` // DO classes -> ent schemas.
+	Tables   naming.Rule `yaml:"tables"`   // table names -> ent schemas.
+	Usecases naming.Rule `yaml:"usecases"` // service classes -> biz usecases.
+}
+
+// Audit configures the audit columns. Source columns listed in Columns are
+// not converted; every ent schema gets the CreatedAt, UpdatedAt and
+// DeletedAt fields instead.
+type Audit struct {
+	Columns   []string `yaml:"columns"`
+	CreatedAt string   `yaml:"created_at"`
+	UpdatedAt string   `yaml:"updated_at"`
+	DeletedAt string   `yaml:"deleted_at"`
+}
+
+// Default returns the configuration used when there is no java2go.yaml.
+func Default() *Config {
+	return &Config{
+		Ctl: Ctl{
+			Rename: RenamePath,
+		},
+		Proto: Proto{
+			Package:     "api.{segment}.v1",
+			GoPackage:   "{module}/api/{segment}/v1;v1",
+			JavaPackage: "api.{segment}",
+		},
+		Go: Go{
+			SchemaPackage: "schema",
+			EntPackage:    "{module}/internal/data/ent",
+		},
+		Naming: Naming{
+			Services: naming.Rule{Suffixes: []string{"Controller"}},
+			Entities: naming.Rule{Suffixes: []string{"DO", "Entity"}},
+			Usecases: naming.Rule{Suffixes: []string{"ServiceImpl", "Service"}},
+		},
+		Audit: Audit{
+			Columns:   []string{"create_time", "update_time", "deleted"},
+			CreatedAt: "created_at",
+			UpdatedAt: "updated_at",
+			DeletedAt: "deleted_at",
+		},
+		Overwrite: OverwriteSkip,
+		Cache:     ".java2go/cache",
+	}
+}
+
+// Load reads the configuration file at path on top of the defaults. A
+// missing file is not an error.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = yaml.Unmarshal(b, cfg); err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+	if err = cfg.check(); err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+	return cfg, nil
+}
+
+func (c *Config) check() error {
+	switch c.Overwrite {
+	case OverwriteSkip, OverwriteAlways, OverwriteFail:
+	default:
+		return errors.New("未知的overwrite：" + c.Overwrite)
+	}
+	if c.Go.SchemaPackage == "" {
+		return errors.New("go.schema_package 不能为空")
+	}
+	switch c.Ctl.Rename {
+	case RenamePath, RenameParams:
+	default:
+		return errors.New("未知的ctl.rename：" + c.Ctl.Rename)
+	}
+	for name, kind := range c.Types.Java {
+		if !validKind(kind) {
+			return errors.Errorf("types.java.%s: 未知的类型：%s", name, kind)
+		}
+	}
+	for name, kind := range c.Types.Sql {
+		if !validKind(kind) {
+			return errors.Errorf("types.sql.%s: 未知的类型：%s", name, kind)
+		}
+	}
+	return nil
+}
+
+func validKind(kind string) bool {
+	switch ir.Kind(kind) {
+	case ir.Int32, ir.Int64, ir.Float32, ir.Float64, ir.Bool, ir.String, ir.Bytes, ir.Time:
+		return true
+	}
+	return false
+}
+
+// JavaType is ir.JavaType with the overrides of types.java applied.
+func (c *Config) JavaType(name string) (ir.Type, error) {
+	if kind, ok := c.Types.Java[strings.TrimSpace(name)]; ok {
+		return ir.Scalar(ir.Kind(kind)), nil
+	}
+	return ir.JavaType(name)
+}
+
+// SQLType is ir.SQLType with the overrides of types.sql applied. Overrides
+// match the full column type, e.g. "tinyint(1)", or its base name.
+func (c *Config) SQLType(column string) (ir.Type, error) {
+	typ := strings.ToLower(strings.TrimSpace(column))
+	if i := strings.Index(typ, " "); i >= 0 {
+		typ = typ[:i]
+	}
+	if kind, ok := c.Types.Sql[typ]; ok {
+		return ir.Scalar(ir.Kind(kind)), nil
+	}
+	if i := strings.Index(typ, "("); i >= 0 {
+		if kind, ok := c.Types.Sql[typ[:i]]; ok {
+			return ir.Scalar(ir.Kind(kind)), nil
+		}
+	}
+	return ir.SQLType(column)
+}
+
+// IsAudit reports whether column is one of the audit columns.
+func (c *Config) IsAudit(column string) bool {
+	for _, s := range c.Audit.Columns {
+		if s == column {
+			return true
+		}
+	}
+	return false
+}
+
+// ProtoOption expands one of the proto templates for a segment and the
+// source of a controller. An empty {module} drops the following "/".
+func (c *Config) ProtoOption(template, segment, source string) string {
+	s := strings.Replace(template, "{segment}", segment, -1)
+	s = strings.Replace(s, "{package}", c.Package(source, segment), -1)
+	return c.module(s)
+}
+
+// EntPackage returns the import path of the generated ent code, see
+// Go.EntPackage.
+func (c *Config) EntPackage() string {
+	return c.module(c.Go.EntPackage)
+}
+
+// module replaces {module} in s by the Go module path. An empty module
+// drops the following "/".
+func (c *Config) module(s string) string {
+	if c.Go.Module == "" {
+		s = strings.Replace(s, "{module}/", "", -1)
+	}
+	return strings.Replace(s, "{module}", c.Go.Module, -1)
+}
+
+// Package returns the Go package name of the source directory holding
+// source, see Go.Packages, or def if none does.
+func (c *Config) Package(source, def string) string {
+	source = filepath.ToSlash(filepath.Clean(source))
+	pkg, longest := def, -1
+	for dir, name := range c.Go.Packages {
+		dir = filepath.ToSlash(filepath.Clean(dir))
+		if (source == dir || strings.HasPrefix(source, dir+"/")) && len(dir) > longest {
+			pkg, longest = name, len(dir)
+		}
+	}
+	return pkg
+}
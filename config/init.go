@@ -0,0 +1,192 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// CmdInit represents the init command.
+var CmdInit = &cobra.Command{
+	Use:   "init",
+	Short: "Write a commented java2go.yaml",
+	Long:  "Write a commented java2go.yaml starter configuration. Example: ./j2g.exe init",
+	Run:   runInit,
+}
+
+var force bool
+
+func init() {
+	CmdInit.Flags().BoolVarP(&force, "force", "f", false, "overwrite an existing configuration file")
+}
+
+func runInit(_ *cobra.Command, args []string) {
+	path := File
+	if len(args) > 0 {
+		path = args[0]
+	}
+	if _, err := os.Stat(path); err == nil && !force {
+		fmt.Println("文件已经存在：" + path + "， 如要更新先删除或使用 --force")
+		return
+	}
+	if err := os.WriteFile(path, []byte(starter), 0644); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println("写入文件：" + path)
+}
+
+// starter is the configuration written by init. It spells out the defaults.
+const starter = `# java2go.yaml - configuration shared by all java2go commands.
+# Command line flags and arguments take precedence over this file.
+
+# ctl: Spring controllers (+ VO/request classes) -> Kratos protobuf
+ctl:
+  # directories holding the controllers
+  controllers:
+    - ./src/main/java/com/example/controller
+  # directories holding the VO and request classes used by the controllers
+  models:
+    - ./src/main/java/com/example/vo
+    - ./src/main/java/com/example/request
+  # further source roots searched for referenced classes, e.g. other modules
+  # or Maven sources jars (.jar/.zip archives are read without unpacking)
+  sources:
+    # - ../example-common/src/main/java
+    # - ~/.m2/repository/com/example/example-api/1.0.0/example-api-1.0.0-sources.jar
+  # saved Swagger 2 or OpenAPI 3 JSON documents (e.g. of /v2/api-docs), read
+  # instead of the Java sources when the sources are unavailable
+  api_docs:
+    # - ./api-docs.json
+  # glob patterns matched against the path below each root; ** matches any
+  # number of directories, a pattern without / matches the file name
+  include:
+    # - "**/vo/*.java"
+  exclude:
+    # - "**/internal/**"
+  # output directory of the .proto files
+  output: ./api
+  # directory of the Kratos service stubs; no stubs are generated if empty
+  # service: ./internal/service
+  # OpenAPI 3 document of the controllers, JSON if the name ends in .json;
+  # no document is generated if empty
+  # openapi: ./openapi.yaml
+  # duplicate RPC names (overloads, GET/POST variants) are renamed by
+  # appending the last path segment (path) or the parameter types (params)
+  rename: path
+
+# do: MyBatis-Plus DO classes -> ent schema
+do:
+  # directories or sources jars holding the DO classes
+  sources:
+    - ./src/main/java/com/example/entity
+  output: ./internal/data/ent/schema
+  # directory of the data-layer repositories using the ent client; no
+  # repositories are generated if empty
+  # repo: ./internal/data
+  # directory of the functions converting between the messages of VOs and
+  # requests and the ent entities of DOs, and of the MapStruct mappers
+  # (project command only)
+  # convert: ./internal/data
+
+# sql: CREATE TABLE statements -> ent schema
+sql:
+  sources:
+    - ./src/main/resources/init-schema.sql
+  output: ./internal/data/ent/schema
+
+# service: @Service interfaces and implementations -> Kratos biz layer
+service:
+  sources:
+    - ./src/main/java/com/example/service
+  # classes used in the method signatures, e.g. VOs and DOs
+  models:
+    - ./src/main/java/com/example/vo
+  output: ./internal/biz
+
+# mapper: MyBatis mapper XML files and @Select/@Insert/@Update/@Delete
+# methods of mapper interfaces -> Go query functions (database/sql)
+mapper:
+  sources:
+    - ./src/main/resources/mapper
+    - ./src/main/java/com/example/mapper
+  # DO and VO classes named by parameterType, resultType and resultMap
+  models:
+    - ./src/main/java/com/example/entity
+  output: ./internal/data
+
+# options of the generated .proto files; {segment} is the first segment
+# of the controller's @RequestMapping path, {module} the Go module path and
+# {package} the Go package name of the controller's source directory (see
+# go.packages), or {segment} if no package is configured for it. With
+# {package} the controllers of a module share one proto package, and a
+# request or reply message whose name is taken gets the service name prefix
+proto:
+  package: api.{segment}.v1
+  go_package: "{module}/api/{segment}/v1;v1"
+  java_package: api.{segment}
+
+# the Go side of the output; project derives module and packages from
+# pom.xml or settings.gradle when they are empty. Without a module the
+# import paths of the generated Go code (service stubs, repositories, query
+# functions and converters) lose their {module}/ prefix and do not resolve;
+# the commands warn about it, so set it unless project derives it
+go:
+  module: ""
+  # source directory -> Go package name
+  packages:
+    # ./demo-device-api: device
+  schema_package: schema
+  # import path of the ent client generated from the schemas
+  ent_package: "{module}/internal/data/ent"
+
+# type mapping overrides: java/sql type -> int32, int64, float32, float64,
+# bool, string, bytes or time
+types:
+  java:
+    # LocalDateTime: time
+  sql:
+    # tinyint(1): bool
+
+# prefixes and suffixes stripped from class and table names; names that
+# collapse to the same identifier are reported and keep their original name
+naming:
+  # controller classes -> proto services
+  services:
+    suffixes: [Controller]
+  # VO and request classes -> proto messages, e.g. [VO, DTO, Request, Resp].
+  # Empty by default: DeviceVO would become message Device and clash with
+  # service Device of DeviceController, which is then renamed
+  messages:
+    suffixes: []
+  # DO classes -> ent schemas
+  entities:
+    suffixes: [DO, Entity]
+  # table names -> ent schemas, e.g. prefixes: [t_]
+  tables:
+    prefixes: []
+  # service interfaces and implementations -> biz usecases
+  usecases:
+    suffixes: [ServiceImpl, Service]
+
+# audit columns: the source columns are dropped, every ent schema gets the
+# three fields below instead
+audit:
+  columns:
+    - create_time
+    - update_time
+    - deleted
+  created_at: created_at
+  updated_at: updated_at
+  deleted_at: deleted_at
+
+# what to do with generated files that already exist: skip, overwrite or fail
+overwrite: skip
+
+# directory of the incremental conversion cache: a run whose sources,
+# configuration and java2go version are unchanged is skipped, and only the
+# outputs whose content changed are written. Files written by java2go and not
+# edited since are refreshed even with overwrite: skip. Empty disables it.
+cache: .java2go/cache
+`
@@ -0,0 +1,326 @@
+// Package convert exposes the java2go converters as a library.
+//
+// The functions in this package work on in-memory sources and return the
+// generated files together with the diagnostics of the run, so they can be
+// embedded in other tools without shelling out to the java2go command.
+package convert
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/ctl"
+	"github.com/luobote55/java2go/do"
+	"github.com/luobote55/java2go/emit"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/mapper"
+	"github.com/luobote55/java2go/service"
+	"github.com/luobote55/java2go/sql"
+)
+
+// A Source is one input file of a conversion.
+type Source struct {
+	// Name identifies the source in diagnostics. Its base name is also used
+	// to derive the names of generated files, e.g. DeviceController.java.
+	Name   string
+	Reader io.Reader
+}
+
+// NewSource returns a Source reading from an in-memory buffer.
+func NewSource(name string, src []byte) Source {
+	return Source{Name: name, Reader: bytes.NewReader(src)}
+}
+
+// ReadDir reads the file at path, or every file below path with the given
+// extension (e.g. ".java"), into sources.
+func ReadDir(path string, ext string) ([]Source, error) {
+	srcs, err := gen.ReadSources(path, ext)
+	if err != nil {
+		return nil, err
+	}
+	return fromGen(srcs), nil
+}
+
+func fromGen(srcs []gen.Source) []Source {
+	res := make([]Source, 0, len(srcs))
+	for _, src := range srcs {
+		res = append(res, Source{Name: src.Path, Reader: src.R})
+	}
+	return res
+}
+
+// ReadRoots reads every file with the given extension below the source
+// roots, e.g. the modules of a Maven project. Include and exclude are glob
+// patterns matched against the path of a file below its root; "**"
+// matches any number of directories. Files reached through several roots
+// are read once.
+func ReadRoots(roots []string, ext string, include, exclude []string) ([]Source, error) {
+	srcs, err := gen.ReadSourceRoots(roots, ext, &gen.Filter{Include: include, Exclude: exclude})
+	if err != nil {
+		return nil, err
+	}
+	return fromGen(srcs), nil
+}
+
+// A File is a generated output file.
+type File struct {
+	Name    string // path relative to the output root.
+	Content []byte
+}
+
+// A Diagnostic is a problem found in a source during conversion.
+type Diagnostic struct {
+	File    string
+	Line    int // 1-based; 0 if unknown.
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	return gen.Diagnostic(d).String()
+}
+
+// A Result holds the output of a conversion.
+type Result struct {
+	Files       []File
+	Diagnostics []Diagnostic
+}
+
+// A Converter runs the conversions with a project configuration.
+type Converter struct {
+	Config *config.Config
+}
+
+// New returns a Converter using cfg; nil selects the defaults.
+func New(cfg *config.Config) *Converter {
+	if cfg == nil {
+		cfg = config.Default()
+	}
+	return &Converter{Config: cfg}
+}
+
+// Controllers converts Spring controllers into Kratos protobuf files.
+// Request and reply types used by the controllers are resolved against the
+// VO and request classes given as models.
+func (c *Converter) Controllers(controllers, models []Source) (*Result, error) {
+	out, err := ctl.Generate(sources(controllers), sources(models), c.Config)
+	if err != nil {
+		return nil, err
+	}
+	return result(out), nil
+}
+
+// APIDocs converts saved Swagger 2 or OpenAPI 3 JSON documents of Spring
+// services, e.g. of /v2/api-docs, into the protobuf files Controllers
+// generates from their sources.
+func (c *Converter) APIDocs(docs []Source) (*Result, error) {
+	out, err := ctl.GenerateAPIDocs(sources(docs), c.Config)
+	if err != nil {
+		return nil, err
+	}
+	return result(out), nil
+}
+
+// OpenAPI converts Spring controllers into an OpenAPI 3 document named
+// name, JSON if name ends in .json and YAML otherwise. It describes the
+// HTTP API of the protobuf files Controllers generates.
+func (c *Converter) OpenAPI(controllers, models []Source, name string) (*Result, error) {
+	out := new(gen.Output)
+	m, err := ctl.Parse(sources(controllers), sources(models), c.Config, out)
+	if err != nil {
+		return nil, err
+	}
+	emit.OpenAPI(m, c.Config, name, out)
+	return result(out), nil
+}
+
+// DOs converts MyBatis-Plus DO classes into ent schema files.
+func (c *Converter) DOs(dos []Source) (*Result, error) {
+	out, err := do.Generate(sources(dos), c.Config)
+	if err != nil {
+		return nil, err
+	}
+	return result(out), nil
+}
+
+// DDL converts CREATE TABLE statements into ent schema files.
+func (c *Converter) DDL(ddl []Source) (*Result, error) {
+	out, err := sql.Generate(sources(ddl), c.Config)
+	if err != nil {
+		return nil, err
+	}
+	return result(out), nil
+}
+
+// Services converts Spring service interfaces and implementations into
+// Kratos biz files. The types used by the services are resolved against
+// the classes given as models.
+func (c *Converter) Services(services, models []Source) (*Result, error) {
+	out, err := service.Generate(sources(services), sources(models), c.Config)
+	if err != nil {
+		return nil, err
+	}
+	return result(out), nil
+}
+
+// Mappers converts MyBatis mapper XML files and annotated mapper
+// interfaces, told apart by their .xml and .java names, into Go query
+// functions. The classes named by their statements are resolved against
+// the DO and VO classes given as models.
+func (c *Converter) Mappers(mappers, models []Source) (*Result, error) {
+	out, err := mapper.Generate(sources(mappers), sources(models), c.Config)
+	if err != nil {
+		return nil, err
+	}
+	return result(out), nil
+}
+
+// ParseControllers parses controllers and their models into the
+// intermediate model without generating any output.
+func (c *Converter) ParseControllers(controllers, models []Source) (*ir.Model, []Diagnostic, error) {
+	out := new(gen.Output)
+	m, err := ctl.Parse(sources(controllers), sources(models), c.Config, out)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, result(out).Diagnostics, nil
+}
+
+// ParseDOs parses DO classes into the intermediate model.
+func (c *Converter) ParseDOs(dos []Source) (*ir.Model, []Diagnostic, error) {
+	out := new(gen.Output)
+	m, err := do.Parse(sources(dos), c.Config, out)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, result(out).Diagnostics, nil
+}
+
+// ParseDDL parses CREATE TABLE statements into the intermediate model.
+func (c *Converter) ParseDDL(ddl []Source) (*ir.Model, []Diagnostic, error) {
+	out := new(gen.Output)
+	m, err := sql.Parse(sources(ddl), c.Config, out)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, result(out).Diagnostics, nil
+}
+
+// ParseServices parses service interfaces and implementations, and their
+// models, into the intermediate model.
+func (c *Converter) ParseServices(services, models []Source) (*ir.Model, []Diagnostic, error) {
+	out := new(gen.Output)
+	m, err := service.Parse(sources(services), sources(models), c.Config, out)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, result(out).Diagnostics, nil
+}
+
+// ParseMappers parses mapper XML files and interfaces, and their models,
+// into the intermediate model.
+func (c *Converter) ParseMappers(mappers, models []Source) (*ir.Model, []Diagnostic, error) {
+	out := new(gen.Output)
+	m, err := mapper.Parse(sources(mappers), sources(models), c.Config, out)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m, result(out).Diagnostics, nil
+}
+
+// Emit generates the protobuf files of the services, the ent schemas of
+// the tables and the biz files of the usecases in m, which may have been
+// edited or loaded with ir.Load.
+func (c *Converter) Emit(m *ir.Model) *Result {
+	out := new(gen.Output)
+	emit.Generate(m, c.Config, out)
+	return result(out)
+}
+
+// Controllers is New(nil).Controllers.
+func Controllers(controllers, models []Source) (*Result, error) {
+	return New(nil).Controllers(controllers, models)
+}
+
+// APIDocs is New(nil).APIDocs.
+func APIDocs(docs []Source) (*Result, error) {
+	return New(nil).APIDocs(docs)
+}
+
+// OpenAPI is New(nil).OpenAPI.
+func OpenAPI(controllers, models []Source, name string) (*Result, error) {
+	return New(nil).OpenAPI(controllers, models, name)
+}
+
+// DOs is New(nil).DOs.
+func DOs(dos []Source) (*Result, error) {
+	return New(nil).DOs(dos)
+}
+
+// DDL is New(nil).DDL.
+func DDL(ddl []Source) (*Result, error) {
+	return New(nil).DDL(ddl)
+}
+
+// Services is New(nil).Services.
+func Services(services, models []Source) (*Result, error) {
+	return New(nil).Services(services, models)
+}
+
+// Mappers is New(nil).Mappers.
+func Mappers(mappers, models []Source) (*Result, error) {
+	return New(nil).Mappers(mappers, models)
+}
+
+// ParseControllers is New(nil).ParseControllers.
+func ParseControllers(controllers, models []Source) (*ir.Model, []Diagnostic, error) {
+	return New(nil).ParseControllers(controllers, models)
+}
+
+// ParseDOs is New(nil).ParseDOs.
+func ParseDOs(dos []Source) (*ir.Model, []Diagnostic, error) {
+	return New(nil).ParseDOs(dos)
+}
+
+// ParseDDL is New(nil).ParseDDL.
+func ParseDDL(ddl []Source) (*ir.Model, []Diagnostic, error) {
+	return New(nil).ParseDDL(ddl)
+}
+
+// ParseServices is New(nil).ParseServices.
+func ParseServices(services, models []Source) (*ir.Model, []Diagnostic, error) {
+	return New(nil).ParseServices(services, models)
+}
+
+// ParseMappers is New(nil).ParseMappers.
+func ParseMappers(mappers, models []Source) (*ir.Model, []Diagnostic, error) {
+	return New(nil).ParseMappers(mappers, models)
+}
+
+// Emit is New(nil).Emit.
+func Emit(m *ir.Model) *Result {
+	return New(nil).Emit(m)
+}
+
+func sources(srcs []Source) []gen.Source {
+	res := make([]gen.Source, 0, len(srcs))
+	for _, src := range srcs {
+		res = append(res, gen.Source{Path: src.Name, R: src.Reader})
+	}
+	return res
+}
+
+func result(out *gen.Output) *Result {
+	res := &Result{
+		Files:       make([]File, 0, len(out.Files)),
+		Diagnostics: make([]Diagnostic, 0, len(out.Diagnostics)),
+	}
+	for _, file := range out.Files {
+		res.Files = append(res.Files, File{Name: file.Name, Content: file.Content()})
+	}
+	for _, d := range out.Diagnostics {
+		res.Diagnostics = append(res.Diagnostics, Diagnostic(d))
+	}
+	return res
+}
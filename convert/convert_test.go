@@ -0,0 +1,65 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestControllers(t *testing.T) {
+	controllers, err := ReadDir("../test/ctl/controller", ".java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vos, err := ReadDir("../test/ctl/vo", ".java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests, err := ReadDir("../test/ctl/request", ".java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Controllers(controllers, append(vos, requests...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 1 {
+		t.Fatalf("got %d files, want 1", len(res.Files))
+	}
+	file := res.Files[0]
+	if file.Name != "device_monitor.proto" {
+		t.Errorf("file name = %q", file.Name)
+	}
+	for _, want := range []string{
+		"package api.device.v1;",
+		"service DeviceMonitor {",
+		"rpc SetMonitorConfig(DeviceMonitorRequest) returns (SetMonitorConfigReply){",
+		"message DeviceMonitorVO {",
+	} {
+		if !strings.Contains(string(file.Content), want) {
+			t.Errorf("generated proto does not contain %q", want)
+		}
+	}
+}
+
+func TestDDL(t *testing.T) {
+	src := NewSource("schema.sql", []byte("CREATE TABLE `user_role`\n"+
+		"(\n"+
+		"    `id`      bigint(0) NOT NULL AUTO_INCREMENT COMMENT 'id',\n"+
+		"    `name`    varchar(32) NULL DEFAULT NULL COMMENT '名称',\n"+
+		"    `gender`  geometry NULL COMMENT '未知',\n"+
+		"    PRIMARY KEY (`id`) USING BTREE\n"+
+		") ENGINE = InnoDB COMMENT = '角色' ROW_FORMAT = Dynamic;\n"))
+	res, err := DDL([]Source{src})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 1 || res.Files[0].Name != "user_role.go" {
+		t.Fatalf("got files %+v, want user_role.go", res.Files)
+	}
+	if !strings.Contains(string(res.Files[0].Content), "type UserRole struct {") {
+		t.Errorf("generated schema does not declare UserRole:\n%s", res.Files[0].Content)
+	}
+	if len(res.Diagnostics) != 1 || res.Diagnostics[0].Line != 5 {
+		t.Errorf("got diagnostics %v, want one for line 5", res.Diagnostics)
+	}
+}
@@ -0,0 +1,640 @@
+package ctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/emit"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
+	"github.com/pkg/errors"
+)
+
+// An apiDoc is a Swagger 2 or OpenAPI 3 document as exported by springfox
+// (/v2/api-docs) or springdoc (/v3/api-docs), reduced to the parts the
+// protobuf output needs.
+type apiDoc struct {
+	Swagger     string    `json:"swagger"`
+	OpenAPI     string    `json:"openapi"`
+	Tags        []*apiTag `json:"tags"`
+	Paths       object    `json:"paths"`
+	Definitions object    `json:"definitions"` // Swagger 2.
+	Components  struct {
+		Schemas object `json:"schemas"` // OpenAPI 3.
+	} `json:"components"`
+}
+
+type apiTag struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type apiSchema struct {
+	Ref         string       `json:"$ref"`
+	Type        string       `json:"type"`
+	Format      string       `json:"format"`
+	Title       string       `json:"title"`
+	Description string       `json:"description"`
+	Items       *apiSchema   `json:"items"`
+	Properties  object       `json:"properties"`
+	AllOf       []*apiSchema `json:"allOf"`
+}
+
+type apiParam struct {
+	Ref         string     `json:"$ref"`
+	Name        string     `json:"name"`
+	In          string     `json:"in"`
+	Description string     `json:"description"`
+	Type        string     `json:"type"` // Swagger 2, except for in: body.
+	Format      string     `json:"format"`
+	Items       *apiSchema `json:"items"`
+	Schema      *apiSchema `json:"schema"`
+}
+
+type apiOperation struct {
+	Tags        []string    `json:"tags"`
+	Summary     string      `json:"summary"`
+	Description string      `json:"description"`
+	OperationID string      `json:"operationId"`
+	Parameters  []*apiParam `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]*apiMedia `json:"content"`
+	} `json:"requestBody"` // OpenAPI 3.
+	Responses map[string]*struct {
+		Schema  *apiSchema           `json:"schema"`  // Swagger 2.
+		Content map[string]*apiMedia `json:"content"` // OpenAPI 3.
+	} `json:"responses"`
+}
+
+type apiMedia struct {
+	Schema *apiSchema `json:"schema"`
+}
+
+// An object is a JSON object whose keys keep the order of the document,
+// which is the order of the paths and of the fields of the messages.
+type object struct {
+	keys   []string
+	values map[string]json.RawMessage
+}
+
+func (o *object) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	tok, err := dec.Token()
+	if err != nil || tok == nil {
+		return err
+	}
+	if tok != json.Delim('{') {
+		return errors.Errorf("应该是对象：%s", tok)
+	}
+	o.values = make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err = dec.Token()
+		if err != nil {
+			return err
+		}
+		key := tok.(string)
+		var value json.RawMessage
+		if err = dec.Decode(&value); err != nil {
+			return err
+		}
+		if _, ok := o.values[key]; !ok {
+			o.keys = append(o.keys, key)
+		}
+		o.values[key] = value
+	}
+	return nil
+}
+
+// httpMethods are the operations of a path item that become RPCs.
+var httpMethods = map[string]bool{"get": true, "put": true, "post": true, "delete": true, "patch": true}
+
+// GenerateAPIDocs converts saved Swagger 2 or OpenAPI 3 JSON documents of
+// Spring services into protobuf files, like Generate does for the sources
+// of their controllers.
+func GenerateAPIDocs(docs []gen.Source, cfg *config.Config) (*gen.Output, error) {
+	out := new(gen.Output)
+	m, err := ParseAPIDocs(docs, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	emit.Proto(m, cfg, out)
+	return out, nil
+}
+
+// ParseAPIDocs parses Swagger 2 or OpenAPI 3 JSON documents into the
+// intermediate model. The operations of a tag, i.e. of a controller, make
+// a service whose path is the one their paths share; the definitions make
+// the messages. Requests and replies are built as Parse builds them from
+// controller methods.
+func ParseAPIDocs(docs []gen.Source, cfg *config.Config, out *gen.Output) (*ir.Model, error) {
+	m := new(ir.Model)
+	names := naming.NewNamer()
+	emitted := make(map[string]*ir.Entity)
+	defined := make(map[string]bool)
+	for _, src := range docs {
+		b, err := io.ReadAll(src.R)
+		if err != nil {
+			return nil, err
+		}
+		doc := new(apiDoc)
+		if err = json.Unmarshal(b, doc); err != nil {
+			return nil, errors.Wrap(err, src.Path)
+		}
+		if doc.Swagger == "" && doc.OpenAPI == "" {
+			return nil, errors.New(src.Path + ": 不是 Swagger 或 OpenAPI 文档")
+		}
+		p := &docParser{
+			path:    src.Path,
+			doc:     doc,
+			cfg:     cfg,
+			out:     out,
+			names:   names,
+			emitted: emitted,
+			defined: defined,
+			msgs:    make(map[string]*ir.Entity),
+			byDef:   make(map[string]string),
+		}
+		if err = p.messages(m); err != nil {
+			return nil, errors.Wrap(err, src.Path)
+		}
+		if err = p.services(m); err != nil {
+			return nil, errors.Wrap(err, src.Path)
+		}
+	}
+	return m, nil
+}
+
+// A docParser parses one API document.
+type docParser struct {
+	path    string
+	doc     *apiDoc
+	cfg     *config.Config
+	out     *gen.Output
+	names   *naming.Namer
+	emitted map[string]*ir.Entity // shared messages already defined, by proto package and name.
+	defined map[string]bool       // messages already defined, by proto package and name.
+	msgs    map[string]*ir.Entity // messages by name.
+	byDef   map[string]string     // message names by definition name.
+}
+
+func (p *docParser) warnf(format string, args ...interface{}) {
+	p.out.Warnf(p.path, 0, format, args...)
+}
+
+// definitions returns the schemas of the document and the prefix of the
+// references to them.
+func (p *docParser) definitions() (object, string) {
+	if p.doc.OpenAPI != "" {
+		return p.doc.Components.Schemas, "#/components/schemas/"
+	}
+	return p.doc.Definitions, "#/definitions/"
+}
+
+// genericRe matches the names springfox gives to generic classes, e.g.
+// "DataGrid«DeviceVO»".
+var genericRe = regexp.MustCompile(`^([^«]+)«(.*)»$`)
+
+// messages adds the message of every definition to m. Generic names such
+// as "Result«DeviceVO»" are joined into "ResultDeviceVO".
+func (p *docParser) messages(m *ir.Model) error {
+	defs, _ := p.definitions()
+	for _, def := range defs.keys {
+		simple := strings.NewReplacer("«", "", "»", "", ",", "", " ", "").Replace(def)
+		name, err := p.names.Assign("schema:"+def, p.cfg.Naming.Messages.Apply(simple), simple)
+		if err != nil {
+			p.warnf("%v", err)
+		}
+		p.byDef[def] = name
+	}
+	for _, def := range defs.keys {
+		s := new(apiSchema)
+		if err := json.Unmarshal(defs.values[def], s); err != nil {
+			return errors.Wrap(err, def)
+		}
+		msg := &ir.Entity{Name: p.byDef[def], Comment: s.Description, Source: p.path}
+		if msg.Comment == "" && s.Title != def {
+			msg.Comment = s.Title
+		}
+		fields, err := p.fields(s, 0)
+		if err != nil {
+			return errors.Wrap(err, def)
+		}
+		msg.Fields = fields
+		p.msgs[msg.Name] = msg
+		if m.Entity(msg.Name) == nil {
+			m.Entities = append(m.Entities, msg)
+		}
+	}
+	return nil
+}
+
+// fields returns the fields of an object schema, including those of the
+// schemas it extends with allOf.
+func (p *docParser) fields(s *apiSchema, depth int) ([]*ir.Field, error) {
+	fields := make([]*ir.Field, 0)
+	for _, base := range s.AllOf {
+		if base.Ref != "" && depth < 8 {
+			defs, prefix := p.definitions()
+			raw, ok := defs.values[strings.TrimPrefix(base.Ref, prefix)]
+			if !ok {
+				p.warnf("没有找到这个message：%s", base.Ref)
+				continue
+			}
+			base = new(apiSchema)
+			if err := json.Unmarshal(raw, base); err != nil {
+				return nil, err
+			}
+		}
+		more, err := p.fields(base, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, more...)
+	}
+	for _, name := range s.Properties.keys {
+		prop := new(apiSchema)
+		if err := json.Unmarshal(s.Properties.values[name], prop); err != nil {
+			return nil, errors.Wrap(err, name)
+		}
+		typ, repeated := p.fieldType(prop)
+		fields = append(fields, &ir.Field{
+			Name:     name,
+			Comment:  prop.Description,
+			Type:     typ,
+			Repeated: repeated,
+		})
+	}
+	return fields, nil
+}
+
+// fieldType returns the IR type of a schema. Arrays become repeated fields
+// of their element type; objects without a definition become strings, as
+// JSONObject does in Java sources.
+func (p *docParser) fieldType(s *apiSchema) (ir.Type, bool) {
+	if s == nil {
+		return ir.Scalar(ir.String), false
+	}
+	if s.Ref != "" {
+		return p.ref(s.Ref), false
+	}
+	switch s.Type {
+	case "array":
+		typ, _ := p.fieldType(s.Items)
+		return typ, true
+	case "integer":
+		if s.Format == "int64" {
+			return ir.Scalar(ir.Int64), false
+		}
+		return ir.Scalar(ir.Int32), false
+	case "number":
+		if s.Format == "float" {
+			return ir.Scalar(ir.Float32), false
+		}
+		return ir.Scalar(ir.Float64), false
+	case "boolean":
+		return ir.Scalar(ir.Bool), false
+	case "string":
+		switch s.Format {
+		case "date", "date-time":
+			return ir.Scalar(ir.Time), false
+		case "byte":
+			return ir.Scalar(ir.Bytes), false
+		}
+	}
+	return ir.Scalar(ir.String), false
+}
+
+// ref returns the type of a reference to a definition.
+func (p *docParser) ref(ref string) ir.Type {
+	_, prefix := p.definitions()
+	if name, ok := p.byDef[strings.TrimPrefix(ref, prefix)]; ok {
+		return ir.Ref(name)
+	}
+	p.warnf("没有找到这个message：%s", ref)
+	return ir.Scalar(ir.String)
+}
+
+// A docOperation is an operation of the document with its path.
+type docOperation struct {
+	path   string
+	method string
+	op     *apiOperation
+	params []*apiParam // parameters shared by the operations of the path.
+}
+
+// services adds a service for every tag of the operations of the
+// document to m, in the order the tags are first used.
+func (p *docParser) services(m *ir.Model) error {
+	tags := make([]string, 0)
+	byTag := make(map[string][]*docOperation)
+	for _, path := range p.doc.Paths.keys {
+		item := new(object)
+		if err := json.Unmarshal(p.doc.Paths.values[path], item); err != nil {
+			return errors.Wrap(err, path)
+		}
+		var shared []*apiParam
+		if raw, ok := item.values["parameters"]; ok {
+			if err := json.Unmarshal(raw, &shared); err != nil {
+				return errors.Wrap(err, path)
+			}
+		}
+		for _, method := range item.keys {
+			if !httpMethods[strings.ToLower(method)] {
+				continue
+			}
+			op := new(apiOperation)
+			if err := json.Unmarshal(item.values[method], op); err != nil {
+				return errors.Wrap(err, path)
+			}
+			tag := "default"
+			if len(op.Tags) > 0 {
+				tag = op.Tags[0]
+			}
+			if _, ok := byTag[tag]; !ok {
+				tags = append(tags, tag)
+			}
+			byTag[tag] = append(byTag[tag], &docOperation{path: path, method: strings.ToLower(method), op: op, params: shared})
+		}
+	}
+	for _, tag := range tags {
+		m.Services = append(m.Services, p.service(tag, byTag[tag]))
+	}
+	return nil
+}
+
+// identRe matches the tags springfox derives from controller class names,
+// e.g. "device-monitor-controller".
+var identRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9 _-]*$`)
+
+// service returns the service of the operations of a tag. Tags derived
+// from class names name the service; other tags, such as the Chinese
+// titles of @Api(tags = ...), become its comment and the service is named
+// after its path instead, see pathClass.
+func (p *docParser) service(tag string, ops []*docOperation) *ir.Service {
+	paths := make([]string, 0, len(ops))
+	for _, o := range ops {
+		paths = append(paths, o.path)
+	}
+	svc := &ir.Service{Path: commonPath(paths), Source: p.path}
+	class := ""
+	if identRe.MatchString(tag) {
+		class = strs.GoCamelCase(strings.NewReplacer("-", "_", " ", "_").Replace(tag))
+		for _, t := range p.doc.Tags {
+			if t.Name == tag {
+				svc.Comment = t.Description
+			}
+		}
+	} else {
+		svc.Comment = tag
+		class = pathClass(svc.Path)
+	}
+	name, err := p.names.Assign(p.path+"#"+tag, p.cfg.Naming.Services.Apply(class), class)
+	if err != nil {
+		p.warnf("%v", err)
+	}
+	svc.Name = name
+	g := &Generator{
+		state: &state{
+			cfg:     p.cfg,
+			out:     p.out,
+			names:   p.names,
+			msgs:    p.msgs,
+			emitted: p.emitted,
+			defined: p.defined,
+		},
+		path:     p.path,
+		svc:      svc,
+		needMsgs: make(map[string]*ir.Entity),
+		rpcs:     make(map[string]bool),
+	}
+	for _, o := range ops {
+		p.endpoint(g, o)
+	}
+	svc.SortMessages()
+	return svc
+}
+
+// pathClass returns the class name of a service derived from its path:
+// the first and last segments, e.g. "DeviceMonitor" for
+// "/device/api/monitor".
+func pathClass(path string) string {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	class := strs.GoCamelCase(strings.Replace(segs[0], "-", "_", -1))
+	if len(segs) > 1 {
+		class += strs.GoCamelCase(strings.Replace(segs[len(segs)-1], "-", "_", -1))
+	}
+	if class == "" {
+		return "Default"
+	}
+	return class
+}
+
+// commonPath returns the directory shared by paths, which becomes the
+// path of their service, e.g. "/device/api/monitor" for
+// "/device/api/monitor/get-config" and "/device/api/monitor/{id}".
+func commonPath(paths []string) string {
+	var common []string
+	for i, path := range paths {
+		segs := strings.Split(strings.Trim(path, "/"), "/")
+		segs = segs[:len(segs)-1]
+		if i == 0 {
+			common = segs
+		}
+		n := 0
+		for n < len(common) && n < len(segs) && common[n] == segs[n] && !strings.Contains(segs[n], "{") {
+			n++
+		}
+		common = common[:n]
+	}
+	if len(common) == 0 {
+		return ""
+	}
+	return "/" + strings.Join(common, "/")
+}
+
+// usingRe matches the suffixes springfox and springdoc append to the
+// operation ids of overloaded methods and methods mapped to several HTTP
+// methods, e.g. "UsingGET_1" and "_1".
+var usingRe = regexp.MustCompile(`(Using[A-Z]+)?(_\d+)?$`)
+
+// endpoint adds the endpoint of an operation to the service of g.
+func (p *docParser) endpoint(g *Generator, o *docOperation) {
+	ep := &ir.Endpoint{
+		Comment: o.op.Summary,
+		Method:  o.method,
+		Path:    strings.TrimPrefix(o.path, g.svc.Path),
+	}
+	if ep.Comment == "" {
+		ep.Comment = o.op.Description
+	}
+	id := usingRe.ReplaceAllString(o.op.OperationID, "")
+	if id == "" {
+		id = o.method + pathSuffix(o.path)
+	}
+	ep.Name = strs.GoCamelCase(id)
+	ep.Params = p.params(o)
+	g.rename(ep)
+	p.reply(g, ep, o.op)
+	g.runRequest(ep)
+	g.svc.Endpoints = append(g.svc.Endpoints, ep)
+}
+
+// params returns the parameters of an operation. Headers and cookies are
+// not part of the API, as servlet objects are not; form data is read like
+// query parameters.
+func (p *docParser) params(o *docOperation) []*ir.Param {
+	all := append(append([]*apiParam{}, o.params...), o.op.Parameters...)
+	params := make([]*ir.Param, 0)
+	index := make(map[string]int)
+	for _, ap := range all {
+		if ap.Ref != "" {
+			p.warnf("暂不支持引用的参数，已忽略：%s", ap.Ref)
+			continue
+		}
+		in := ap.In
+		switch in {
+		case "header", "cookie":
+			continue
+		case "formData":
+			in = "query"
+		}
+		s := ap.Schema
+		if s == nil {
+			s = &apiSchema{Type: ap.Type, Format: ap.Format, Items: ap.Items}
+		}
+		param := &ir.Param{Name: ap.Name, In: in}
+		param.Type, param.Repeated = p.fieldType(s)
+		// Parameters of the operation replace the shared ones of the same
+		// name.
+		if i, ok := index[in+":"+ap.Name]; ok {
+			params[i] = param
+			continue
+		}
+		index[in+":"+ap.Name] = len(params)
+		params = append(params, param)
+	}
+	if body := o.op.RequestBody; body != nil {
+		if media := pickMedia(body.Content); media != nil {
+			param := &ir.Param{Name: "body", In: "body"}
+			param.Type, param.Repeated = p.fieldType(media.Schema)
+			params = append(params, param)
+		}
+	}
+	return params
+}
+
+// pickMedia returns the JSON content of a request or response, or else
+// the first content by media type.
+func pickMedia(content map[string]*apiMedia) *apiMedia {
+	if media, ok := content["application/json"]; ok {
+		return media
+	}
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	if len(types) == 0 {
+		return nil
+	}
+	return content[types[0]]
+}
+
+// response returns the schema of the successful response of an
+// operation, or nil if it has none.
+func response(op *apiOperation) *apiSchema {
+	codes := make([]string, 0, len(op.Responses))
+	for code := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	if len(codes) == 0 {
+		codes = append(codes, "default")
+	}
+	resp := op.Responses[codes[0]]
+	if resp == nil {
+		return nil
+	}
+	if resp.Schema != nil {
+		return resp.Schema
+	}
+	if media := pickMedia(resp.Content); media != nil {
+		return media.Schema
+	}
+	return nil
+}
+
+// reply sets the reply message of ep from the response of an operation,
+// as runReply does from the Java return type: DataGrid«T» becomes the
+// page of T, arrays and List«T» the list of T, and responses without
+// content and HttpWrapper«T» a string.
+func (p *docParser) reply(g *Generator, ep *ir.Endpoint, op *apiOperation) {
+	ep.Reply = g.synthesized(ep.Name + "Reply")
+	replyMsg := &ir.Entity{Name: ep.Reply, Comment: ep.Reply}
+	s := response(op)
+	_, prefix := p.definitions()
+	switch {
+	case s == nil:
+		replyMsg.Fields = append(replyMsg.Fields, &ir.Field{Name: "data", Type: ir.Scalar(ir.String)})
+	case s.Type == "array":
+		typ, _ := p.fieldType(s.Items)
+		replyMsg.Fields = append(replyMsg.Fields, refField(g.needList(typ).Name))
+	case s.Ref != "":
+		def := strings.TrimPrefix(s.Ref, prefix)
+		outer, inner := "", ""
+		if match := genericRe.FindStringSubmatch(def); match != nil {
+			outer, inner = match[1], match[2]
+		}
+		switch outer {
+		case "DataGrid":
+			replyMsg.Fields = append(replyMsg.Fields, refField(g.needPage(p.elemType(inner)).Name))
+		case "List":
+			replyMsg.Fields = append(replyMsg.Fields, refField(g.needList(p.elemType(inner)).Name))
+		case "HttpWrapper":
+			replyMsg.Fields = append(replyMsg.Fields, &ir.Field{Name: "data", Type: ir.Scalar(ir.String)})
+		default:
+			typ := p.ref(s.Ref)
+			if typ.Kind == ir.Message {
+				g.needMsg(typ.Name)
+				replyMsg.Fields = append(replyMsg.Fields, refField(typ.Name))
+			} else {
+				replyMsg.Fields = append(replyMsg.Fields, &ir.Field{Name: "data", Type: typ})
+			}
+		}
+	default:
+		typ, _ := p.fieldType(s)
+		replyMsg.Fields = append(replyMsg.Fields, &ir.Field{Name: "data", Type: typ})
+	}
+	g.define(replyMsg, false)
+}
+
+// docTypes are the names springfox gives to Java scalar type arguments,
+// e.g. the long of "DataGrid«long»".
+var docTypes = map[string]string{
+	"string": "String", "int": "Integer", "long": "Long", "boolean": "Boolean",
+	"float": "Float", "double": "Double", "bigdecimal": "BigDecimal", "date": "Date",
+}
+
+// elemType returns the type of the type argument of a generic definition
+// name, a definition or a Java scalar.
+func (p *docParser) elemType(arg string) ir.Type {
+	if name, ok := p.byDef[arg]; ok {
+		return ir.Ref(name)
+	}
+	if class, ok := docTypes[strings.ToLower(arg)]; ok {
+		if typ, err := p.cfg.JavaType(class); err == nil {
+			return typ
+		}
+	}
+	p.warnf("没有找到这个message：%s", arg)
+	return ir.Scalar(ir.String)
+}
@@ -0,0 +1,111 @@
+package ctl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+)
+
+func TestAPIDocs(t *testing.T) {
+	controllers, err := gen.ReadSources("../test/ctl/controller", ".java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	models, err := gen.ReadSources("../test/ctl", ".java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	java, err := Generate(controllers, models, config.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := gen.ReadSources("../test/apidocs/api-docs.json", ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := GenerateAPIDocs(docs, config.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 1 {
+		t.Fatalf("got %d files, want 1", len(res.Files))
+	}
+	if res.Files[0].Name != java.Files[0].Name {
+		t.Errorf("file name = %q, want %q", res.Files[0].Name, java.Files[0].Name)
+	}
+	got := string(res.Files[0].Content())
+	// The document describes the controller of the sources and two more
+	// endpoints; every message of the sources comes out the same.
+	for _, block := range strings.Split(string(java.Files[0].Content()), "\n\n")[1:] {
+		if strings.HasPrefix(block, "service ") {
+			continue
+		}
+		if !strings.Contains(got, block) {
+			t.Errorf("proto from api-docs does not contain\n%s", block)
+		}
+	}
+	for _, want := range []string{
+		"rpc GetMonitorConfig(GetMonitorConfigRequest) returns (GetMonitorConfigReply){",
+		"rpc SetMonitorConfig(DeviceMonitorRequest) returns (SetMonitorConfigReply){",
+		"PageDeviceMonitorVO pageDeviceMonitorVO = 1;",
+		"  repeated int32 status = 2;",
+		"delete: \"/device/api/monitor/{id}\"",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("proto from api-docs does not contain %q", want)
+		}
+	}
+}
+
+func TestOpenAPIDocs(t *testing.T) {
+	doc := source("api-docs.json", `{
+  "openapi": "3.0.1",
+  "tags": [{"name": "user-controller", "description": "用户"}],
+  "paths": {
+    "/user/{id}": {
+      "put": {
+        "tags": ["user-controller"],
+        "summary": "修改用户",
+        "operationId": "updateUser",
+        "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64"}}],
+        "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/UserVO"}}}},
+        "responses": {"200": {"description": "OK", "content": {"*/*": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/UserVO"}}}}}}
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "UserVO": {"type": "object", "description": "用户信息", "properties": {
+        "name": {"type": "string", "description": "姓名"},
+        "birthday": {"type": "string", "format": "date-time"}
+      }}
+    }
+  }
+}`)
+	res, err := GenerateAPIDocs([]gen.Source{doc}, config.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 1 {
+		t.Fatalf("got %d files, want 1", len(res.Files))
+	}
+	if res.Files[0].Name != "user.proto" {
+		t.Errorf("file name = %q", res.Files[0].Name)
+	}
+	got := string(res.Files[0].Content())
+	for _, want := range []string{
+		"service User {",
+		"rpc UpdateUser(UserVO) returns (UpdateUserReply){",
+		"put: \"/user/{id}\"",
+		"body: \"*\"",
+		"// 用户信息\nmessage UserVO {",
+		"google.protobuf.Timestamp birthday = 2;",
+		"ListUserVO listUserVO = 1;",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("proto does not contain %q\n%s", want, got)
+		}
+	}
+}
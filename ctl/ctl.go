@@ -3,99 +3,294 @@ package ctl
 import (
 	"bytes"
 	"fmt"
+	"github.com/luobote55/java2go/cache"
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/emit"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
 	"github.com/spf13/cobra"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 )
 
 // CmdCtl represents the source command.
 var CmdCtl = &cobra.Command{
-	Use:   "ctl",
+	Use:   "ctl [java_dir|api_docs.json] [proto_dir]",
 	Short: "Generate the protobuf code from xxxController.java",
-	Long:  "Generate the protobuf code from xxxController.java. Example: ./j2g.exe ctl ./test/ctl ./test/ctl ",
+	Long:  "Generate the protobuf code from xxxController.java, or from a saved Swagger 2 or OpenAPI 3 JSON document of the service. Example: ./j2g.exe ctl ./test/ctl ./test/ctl ",
+	Args:  cobra.MaximumNArgs(2),
 	Run:   run,
 }
 
 var (
-	controllerPath string
-	voPath         string
-	requestPath    string
-	protoPath      string
+	controllerPaths []string
+	voPaths         []string
+	requestPaths    []string
+	sourcePaths     []string
+	includes        []string
+	excludes        []string
+	protoPath       string
+	servicePath     string
+	openapiPath     string
+	apiDocPaths     []string
 )
 
 func init() {
-	CmdCtl.Flags().StringVarP(&controllerPath, "controller_path", "c", "./", "java controller source directory")
-	CmdCtl.Flags().StringVarP(&voPath, "vo_path", "v", "./", "java vo source directory")
-	CmdCtl.Flags().StringVarP(&requestPath, "request_path", "r", "./", "java request source directory")
+	CmdCtl.Flags().StringSliceVarP(&controllerPaths, "controller_path", "c", []string{"./"}, "java controller source directories")
+	CmdCtl.Flags().StringSliceVarP(&voPaths, "vo_path", "v", []string{"./"}, "java vo source directories")
+	CmdCtl.Flags().StringSliceVarP(&requestPaths, "request_path", "r", []string{"./"}, "java request source directories")
+	CmdCtl.Flags().StringSliceVarP(&sourcePaths, "source", "s", nil, "further source roots or sources jars searched for referenced classes")
+	CmdCtl.Flags().StringSliceVar(&includes, "include", nil, "glob patterns of the files to read below each root")
+	CmdCtl.Flags().StringSliceVar(&excludes, "exclude", nil, "glob patterns of the files to skip below each root")
 	CmdCtl.Flags().StringVarP(&protoPath, "proto_path", "p", "./", "protobuf file directory")
+	CmdCtl.Flags().StringVar(&servicePath, "service_path", "", "Kratos service stub directory, no stubs if empty")
+	CmdCtl.Flags().StringSliceVarP(&apiDocPaths, "api_docs", "a", nil, "saved Swagger 2 or OpenAPI 3 JSON documents (/v2/api-docs) read instead of the java sources")
+	CmdCtl.Flags().StringVar(&openapiPath, "openapi", "", "OpenAPI document file, JSON if it ends in .json and YAML otherwise; no document if empty")
+	CmdCtl.Flags().BoolVar(&cache.Disabled, "no_cache", false, "convert every source again, ignoring the cache")
+	CmdCtl.Flags().BoolVar(&cache.Prune, "prune", false, "remove the unedited files generated from deleted sources")
 }
 
-func run(_ *cobra.Command, args []string) {
-	if len(controllerPath) == 0 {
-		fmt.Println("Please enter the controllerPath")
+// run resolves the paths from, in order of precedence, the arguments, the
+// flags given on the command line, java2go.yaml and the flag defaults.
+func run(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load(config.File)
+	if err != nil {
+		fmt.Println(err)
 		return
 	}
-	if len(voPath) == 0 {
-		fmt.Println("Please enter the voPath")
+	flags := cmd.Flags()
+	controllerRoots := cfg.Ctl.Controllers
+	if flags.Changed("controller_path") || len(controllerRoots) == 0 {
+		controllerRoots = controllerPaths
+	}
+	modelRoots := cfg.Ctl.Models
+	if flags.Changed("vo_path") || flags.Changed("request_path") || len(modelRoots) == 0 {
+		modelRoots = append(append([]string{}, voPaths...), requestPaths...)
+	}
+	sourceRoots := cfg.Ctl.Sources
+	if flags.Changed("source") {
+		sourceRoots = sourcePaths
+	}
+	filter := &gen.Filter{Include: cfg.Ctl.Include, Exclude: cfg.Ctl.Exclude}
+	if flags.Changed("include") {
+		filter.Include = includes
+	}
+	if flags.Changed("exclude") {
+		filter.Exclude = excludes
+	}
+	output := cfg.Ctl.Output
+	if flags.Changed("proto_path") || output == "" {
+		output = protoPath
+	}
+	serviceOutput := cfg.Ctl.Service
+	if flags.Changed("service_path") {
+		serviceOutput = servicePath
+	}
+	openapiOutput := cfg.Ctl.OpenAPI
+	if flags.Changed("openapi") {
+		openapiOutput = openapiPath
+	}
+	apiDocs := cfg.Ctl.APIDocs
+	if flags.Changed("api_docs") {
+		apiDocs = apiDocPaths
+	}
+	if len(args) > 0 && strings.HasSuffix(args[0], ".json") {
+		apiDocs = args[:1]
+	} else if len(args) > 0 {
+		controllerRoots = args[:1]
+		modelRoots = args[:1]
+	}
+	if len(args) > 1 {
+		output = args[1]
+	}
+	var controllers, models, docs []gen.Source
+	if len(apiDocs) > 0 {
+		docs, err = gen.ReadSourceRoots(apiDocs, ".json", nil)
+	} else {
+		controllers, models, err = readSources(controllerRoots, append(modelRoots, sourceRoots...), filter)
+	}
+	if err != nil {
+		fmt.Println(err)
 		return
 	}
-	if len(requestPath) == 0 {
-		fmt.Println("Please enter the requestPath")
+	inputs := []string{strings.Join(apiDocs, ",")}
+	if len(apiDocs) == 0 {
+		inputs = []string{strings.Join(controllerRoots, ","), strings.Join(modelRoots, ","), strings.Join(sourceRoots, ","),
+			strings.Join(filter.Include, ","), strings.Join(filter.Exclude, ",")}
+	}
+	c, err := cache.Open(cfg, append(append([]string{"ctl"}, inputs...), output, openapiOutput, serviceOutput)...)
+	if err == nil {
+		err = c.Hash(append(append(docs, controllers...), models...))
+	}
+	if err != nil {
+		fmt.Println(err)
 		return
 	}
-	if len(protoPath) == 0 {
-		fmt.Println("Please enter the protoPath")
+	if c.Unchanged() {
+		fmt.Println("输入没有变化，跳过")
 		return
 	}
-	var err error
-	msgs := make(map[string]*Message, 0)
-	ctrlNeedMsgs := make(map[string]*Message, 0)
-	err = walkVo(voPath, msgs, ctrlNeedMsgs)
+	out := new(gen.Output)
+	var m *ir.Model
+	if len(apiDocs) > 0 {
+		m, err = ParseAPIDocs(docs, cfg, out)
+	} else {
+		m, err = Parse(controllers, models, cfg, out)
+	}
 	if err != nil {
 		fmt.Println(err)
+		return
 	}
-	err = walkVo(requestPath, msgs, ctrlNeedMsgs)
-	if err != nil {
+	stale := c.Stale(m)
+	emit.Proto(stale, cfg, out)
+	gen.PrintDiagnostics(out.Diagnostics)
+	if err = c.WriteFiles(output, out.Files, cfg.Overwrite); err != nil {
 		fmt.Println(err)
+		return
 	}
-	err = walk(controllerPath, protoPath, msgs, ctrlNeedMsgs)
-	if err != nil {
+	if openapiOutput != "" {
+		doc := new(gen.Output)
+		emit.OpenAPI(m, cfg, filepath.Base(openapiOutput), doc)
+		gen.PrintDiagnostics(doc.Diagnostics)
+		if err = os.MkdirAll(filepath.Dir(openapiOutput), 0755); err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err = c.WriteFiles(filepath.Dir(openapiOutput), doc.Files, cfg.Overwrite); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	if serviceOutput != "" {
+		stubs := new(gen.Output)
+		emit.Service(stale, cfg, stubs)
+		gen.PrintDiagnostics(stubs.Diagnostics)
+		if err = os.MkdirAll(serviceOutput, 0755); err != nil {
+			fmt.Println(err)
+			return
+		}
+		if err = c.WriteFiles(serviceOutput, stubs.Files, cfg.Overwrite); err != nil {
+			fmt.Println(err)
+			return
+		}
+	}
+	if err = c.Save(); err != nil {
 		fmt.Println(err)
 	}
 }
 
-func look(name ...string) error {
-	for _, n := range name {
-		if _, err := exec.LookPath(n); err != nil {
-			return err
-		}
+// readSources reads the controllers and models below the given roots.
+func readSources(controllerRoots, modelRoots []string, filter *gen.Filter) (controllers, models []gen.Source, err error) {
+	if models, err = gen.ReadSourceRoots(modelRoots, ".java", filter); err != nil {
+		return nil, nil, err
 	}
-	return nil
+	if controllers, err = gen.ReadSourceRoots(controllerRoots, ".java", filter); err != nil {
+		return nil, nil, err
+	}
+	return controllers, models, nil
+}
+
+// Generate converts the controllers into protobuf files. Request and reply
+// types are looked up in the VO and request classes given as models.
+func Generate(controllers, models []gen.Source, cfg *config.Config) (*gen.Output, error) {
+	out := new(gen.Output)
+	m, err := Parse(controllers, models, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	emit.Proto(m, cfg, out)
+	return out, nil
 }
 
-func walkVo(dir string, msgs, ctrlNeedMsgs map[string]*Message) error {
-	if dir == "" {
-		dir = "."
+// Parse parses the controllers and models into the intermediate model.
+// Problems found in the sources are recorded in out. The files are scanned
+// concurrently, see gen.Parallel, and then added to the model in the order
+// given, so that the model does not depend on scheduling.
+func Parse(controllers, models []gen.Source, cfg *config.Config, out *gen.Output) (*ir.Model, error) {
+	m := new(ir.Model)
+	vos := make([]*class, len(models))
+	err := gen.Parallel(len(models), func(i int) (err error) {
+		vos[i], err = generateVo(models[i], cfg)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	st := &state{
+		cfg:     cfg,
+		out:     out,
+		names:   naming.NewNamer(),
+		classes: newClasses(),
+		msgs:    make(map[string]*ir.Entity),
+		emitted: make(map[string]*ir.Entity),
+		defined: make(map[string]bool),
+	}
+	for _, cl := range vos {
+		if cl != nil {
+			st.classes.add(cl)
+		}
 	}
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if ext := filepath.Ext(path); ext != ".java" {
-			return nil
+	// messages are named once every class is known, so that classes of the
+	// same simple name get distinct messages.
+	for _, cl := range st.classes.list {
+		simple := cl.msg.Name
+		name, err := st.names.Assign(cl.name, cfg.Naming.Messages.Apply(simple), simple, packageName(cl.name))
+		if err != nil {
+			out.Warnf(cl.msg.Source, cl.line, "%v", err)
 		}
-		if info.IsDir() {
-			walkVo(path, msgs, ctrlNeedMsgs)
+		cl.msg.Name = name
+		st.msgs[name] = cl.msg
+		m.Entities = append(m.Entities, cl.msg)
+	}
+	for _, cl := range st.classes.list {
+		for _, f := range cl.msg.Fields {
+			if f.Type.Kind == ir.Message {
+				f.Type.Name = st.classes.message(cl.scope, f.Type.Name, func(format string, args ...interface{}) {
+					out.Warnf(cl.msg.Source, cl.line, format, args...)
+				})
+			}
 		}
-		return generateVo(path, msgs, ctrlNeedMsgs)
+	}
+	gens := make([]*Generator, len(controllers))
+	err = gen.Parallel(len(controllers), func(i int) (err error) {
+		gens[i], err = generate(controllers[i], st)
+		return err
 	})
+	if err != nil {
+		return nil, err
+	}
+	// services are named, and their messages defined, in source order:
+	// the first controller to use a shared message defines it.
+	for _, g := range gens {
+		if g != nil && g.resolve() {
+			m.Services = append(m.Services, g.svc)
+		}
+	}
+	return m, nil
+}
+
+func look(name ...string) error {
+	for _, n := range name {
+		if _, err := exec.LookPath(n); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func generateVo(path string, msgs, ctrlNeedMsgs map[string]*Message) error {
-	protoBytes, err := os.ReadFile(path)
-	if err != nil && len(protoBytes) < 0 {
+// generateVo parses the specified VO or request class.
+func generateVo(src gen.Source, cfg *config.Config) (*class, error) {
+	protoBytes, err := io.ReadAll(src.R)
+	if err != nil {
+		return nil, err
 	}
 	g := &GeneratorMessage{
 		r:        bytes.NewReader(protoBytes),
-		path:     path,
+		path:     src.Path,
 		dir:      "",
 		file:     "",
 		target:   "",
@@ -103,45 +298,34 @@ func generateVo(path string, msgs, ctrlNeedMsgs map[string]*Message) error {
 		commands: nil,
 		lineNum:  0,
 		env:      nil,
+		cfg:      cfg,
 	}
-	g.run(msgs)
-	return nil
-}
-
-func walk(dir string, goo string, msgs, ctrlNeedMsgs map[string]*Message) error {
-	if dir == "" {
-		dir = "."
-	}
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if ext := filepath.Ext(path); ext != ".java" {
-			return nil
-		}
-		if info.IsDir() {
-			walk(path, goo, msgs, ctrlNeedMsgs)
-		}
-		return generate(path, goo, msgs, ctrlNeedMsgs)
-	})
+	return g.run(), nil
 }
 
-// generate is used to execute the generate command for the specified proto file
-func generate(java string, goo string, msgs, ctrlNeedMsgs map[string]*Message) error {
-	protoBytes, err := os.ReadFile(java)
-	if err != nil && len(protoBytes) < 0 {
+// generate scans the specified controller. It returns nil if the file
+// does not declare a class.
+func generate(src gen.Source, st *state) (*Generator, error) {
+	protoBytes, err := io.ReadAll(src.R)
+	if err != nil {
+		return nil, err
 	}
 	g := &Generator{
+		state:    st,
 		r:        bytes.NewReader(protoBytes),
-		path:     java,
+		path:     src.Path,
 		dir:      "",
 		file:     "",
 		target:   "",
-		goo:      goo,
 		pkg:      "",
 		commands: nil,
 		lineNum:  0,
 		env:      nil,
 	}
-	g.run(msgs, ctrlNeedMsgs)
-	return nil
+	if !g.run() {
+		return nil, nil
+	}
+	return g, nil
 }
 
 func pathExists(path string) bool {
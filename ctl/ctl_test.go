@@ -0,0 +1,260 @@
+package ctl
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+)
+
+// source returns an in-memory source named path.
+func source(path, src string) gen.Source {
+	return gen.Source{Path: path, R: bytes.NewReader([]byte(src))}
+}
+
+func TestOverloads(t *testing.T) {
+	src := source("DeviceController.java", "@RestController\n"+
+		"@RequestMapping(\"/device\")\n"+
+		"public class DeviceController {\n"+
+		"    @GetMapping(\"/{id}\")\n"+
+		"    public String getDevice(@PathVariable Long id) {\n"+
+		"    }\n"+
+		"    @GetMapping(\"/by-name\")\n"+
+		"    public String getDevice(@RequestParam String name) {\n"+
+		"    }\n"+
+		"    @PostMapping(\"/by-name\")\n"+
+		"    public String getDevice(@RequestParam String name, @RequestParam Integer page) {\n"+
+		"    }\n"+
+		"}\n")
+	out, err := Generate([]gen.Source{src}, nil, config.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Files) != 1 {
+		t.Fatalf("got %d files, want 1", len(out.Files))
+	}
+	content := string(out.Files[0].Content())
+	for _, want := range []string{
+		"rpc GetDevice(GetDeviceRequest) returns (GetDeviceReply){",
+		"rpc GetDeviceByName(GetDeviceByNameRequest) returns (GetDeviceByNameReply){",
+		"rpc GetDevicePost(GetDevicePostRequest) returns (GetDevicePostReply){",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("generated proto lacks %q:\n%s", want, content)
+		}
+	}
+	if len(out.Diagnostics) != 2 {
+		t.Errorf("got diagnostics %v, want one per rename", out.Diagnostics)
+	}
+}
+
+func TestImports(t *testing.T) {
+	vo := func(pkg, comment string) gen.Source {
+		return source(pkg+"/DeviceVO.java", "package com.example."+pkg+";\n"+
+			"@ApiModel(value = \""+comment+"\")\n"+
+			"public class DeviceVO {\n"+
+			"    @ApiModelProperty(value = \"id\")\n"+
+			"    private Long id;\n"+
+			"}\n")
+	}
+	ctl := func(pkg string, imp string) gen.Source {
+		return source(pkg+"/DeviceController.java", "package com.example."+pkg+";\n"+
+			"import "+imp+";\n"+
+			"@RestController\n"+
+			"@RequestMapping(\"/"+pkg+"\")\n"+
+			"public class DeviceController {\n"+
+			"    @GetMapping(\"/get\")\n"+
+			"    public DeviceVO getDevice(@RequestParam Long id) {\n"+
+			"    }\n"+
+			"}\n")
+	}
+	out, err := Generate([]gen.Source{
+		ctl("admin", "com.example.admin.*"),
+		ctl("user", "com.example.user.DeviceVO"),
+	}, []gen.Source{vo("admin", "管理端设备"), vo("user", "用户端设备")}, config.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Files) != 2 {
+		t.Fatalf("got %d files, want 2", len(out.Files))
+	}
+	admin, user := string(out.Files[0].Content()), string(out.Files[1].Content())
+	if !strings.Contains(admin, "// 管理端设备\nmessage DeviceVO {") {
+		t.Errorf("admin proto does not define the admin DeviceVO:\n%s", admin)
+	}
+	if !strings.Contains(user, "// 用户端设备\nmessage UserDeviceVO {") {
+		t.Errorf("user proto does not define the user DeviceVO as UserDeviceVO:\n%s", user)
+	}
+	if len(out.Diagnostics) < 2 {
+		t.Errorf("got diagnostics %v, want the message and service clashes", out.Diagnostics)
+	}
+}
+
+func TestServiceCalls(t *testing.T) {
+	src := source("DeviceController.java", "@RestController\n"+
+		"@RequestMapping(\"/device\")\n"+
+		"public class DeviceController {\n"+
+		"    @GetMapping(\"/get\")\n"+
+		"    public String getDevice(@RequestParam Long id) {\n"+
+		"        Valid.notNull(id);\n"+
+		"        log.info(\"{}\", auditService.record(id));\n"+
+		"        return deviceService.getDevice(id, Math.max(1, 2));\n"+
+		"    }\n"+
+		"\n"+
+		"    @PostMapping(\"/ping\")\n"+
+		"    public void ping() {\n"+
+		"    }\n"+
+		"}\n")
+	m, err := Parse([]gen.Source{src}, nil, config.Default(), new(gen.Output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	eps := m.Services[0].Endpoints
+	if got, want := eps[0].Call, "deviceService.getDevice(id, Math.max(1, 2))"; got != want {
+		t.Errorf("call = %q, want %q", got, want)
+	}
+	if eps[1].Call != "" {
+		t.Errorf("call of ping = %q", eps[1].Call)
+	}
+}
+
+func TestParallel(t *testing.T) {
+	// every package has a DeviceVO and a DeviceController, so that the names
+	// given out depend on the order the files are added in.
+	sources := func() (controllers, models []gen.Source) {
+		for i := 0; i < 40; i++ {
+			pkg := fmt.Sprintf("p%d", i)
+			models = append(models, source(pkg+"/DeviceVO.java", "package com.example."+pkg+";\n"+
+				"public class DeviceVO {\n"+
+				"    @ApiModelProperty(value = \"id\")\n"+
+				"    private Long id;\n"+
+				"}\n"))
+			controllers = append(controllers, source(pkg+"/DeviceController.java", "package com.example."+pkg+";\n"+
+				"@RestController\n"+
+				"@RequestMapping(\"/device\")\n"+
+				"public class DeviceController {\n"+
+				"    @GetMapping(\"/list\")\n"+
+				"    public List<DeviceVO> listDevice(@RequestParam Long id) {\n"+
+				"    }\n"+
+				"}\n"))
+		}
+		return controllers, models
+	}
+	run := func(workers int) string {
+		defer func(n int) { gen.Workers = n }(gen.Workers)
+		gen.Workers = workers
+		controllers, models := sources()
+		res, err := Generate(controllers, models, config.Default())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var b strings.Builder
+		for _, f := range res.Files {
+			b.WriteString(f.Name + "\n" + string(f.Content()))
+		}
+		for _, d := range res.Diagnostics {
+			b.WriteString(d.String() + "\n")
+		}
+		return b.String()
+	}
+	want := run(1)
+	if !strings.Contains(want, "message P39DeviceVO {") {
+		t.Fatalf("sequential output does not rename the clashing VOs:\n%s", want)
+	}
+	for i := 0; i < 5; i++ {
+		if got := run(8); got != want {
+			t.Fatalf("parallel output differs from sequential output:\n%s\nwant:\n%s", got, want)
+		}
+	}
+}
+
+func TestModulePackage(t *testing.T) {
+	ctl := func(name, path string) gen.Source {
+		return source("demo/"+name+"Controller.java", "package com.example.demo;\n"+
+			"@RestController\n"+
+			"@RequestMapping(\"/"+path+"\")\n"+
+			"public class "+name+"Controller {\n"+
+			"    @GetMapping(\"/get\")\n"+
+			"    public ItemVO get(@RequestParam Long id, @RequestParam String name) {\n"+
+			"    }\n"+
+			"}\n")
+	}
+	vo := source("demo/ItemVO.java", "package com.example.demo;\n"+
+		"public class ItemVO {\n"+
+		"    @ApiModelProperty(value = \"id\")\n"+
+		"    private Long id;\n"+
+		"}\n")
+	cfg := config.Default()
+	cfg.Go.Packages = map[string]string{"demo": "demo"}
+	cfg.Proto.Package = "api.{package}.v1"
+	out, err := Generate([]gen.Source{ctl("Device", "device"), ctl("User", "user")}, []gen.Source{vo}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Files) != 2 {
+		t.Fatalf("got %d files, want 2", len(out.Files))
+	}
+	// every message of the package is defined once.
+	defined := make(map[string]string)
+	for _, f := range out.Files {
+		content := string(f.Content())
+		if !strings.Contains(content, "package api.demo.v1;") {
+			t.Errorf("%s is not in package api.demo.v1:\n%s", f.Name, content)
+		}
+		for _, line := range strings.Split(content, "\n") {
+			if !strings.HasPrefix(line, "message ") {
+				continue
+			}
+			name := strings.Fields(line)[1]
+			if other, ok := defined[name]; ok {
+				t.Errorf("message %s defined in %s and %s", name, other, f.Name)
+			}
+			defined[name] = f.Name
+		}
+	}
+	for _, name := range []string{"GetRequest", "GetReply", "ItemVO", "UserGetRequest", "UserGetReply"} {
+		if defined[name] == "" {
+			t.Errorf("message %s not defined, got %v", name, defined)
+		}
+	}
+}
+
+func TestMessageNames(t *testing.T) {
+	const controller = "@RestController\n" +
+		"@RequestMapping(\"/device\")\n" +
+		"public class DeviceController {\n" +
+		"    @GetMapping(\"/list\")\n" +
+		"    public DataGrid<ItemVO> list(@RequestBody DeviceDTO query) {\n" +
+		"    }\n" +
+		"}\n"
+	generate := func(cfg *config.Config) string {
+		t.Helper()
+		out, err := Generate([]gen.Source{source("DeviceController.java", controller)}, []gen.Source{
+			source("ItemVO.java", "public class ItemVO {\n    @ApiModelProperty(value = \"id\")\n    private Long id;\n}\n"),
+			source("DeviceDTO.java", "public class DeviceDTO {\n    @ApiModelProperty(value = \"名称\")\n    private String name;\n}\n"),
+		}, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out.Files[0].Content())
+	}
+	// by default messages keep the names of their classes.
+	got := generate(config.Default())
+	for _, want := range []string{"service Device {", "rpc List(DeviceDTO) returns (ListReply){", "message ItemVO {", "message PageItemVO {", "message DeviceDTO {"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("default proto does not contain %q:\n%s", want, got)
+		}
+	}
+	// stripping VO and DTO renames the service that then clashes.
+	cfg := config.Default()
+	cfg.Naming.Messages.Suffixes = []string{"VO", "DTO"}
+	got = generate(cfg)
+	for _, want := range []string{"service DeviceController {", "rpc List(Device) returns (ListReply){", "message Item {", "message PageItem {"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("proto does not contain %q:\n%s", want, got)
+		}
+	}
+}
@@ -2,50 +2,72 @@ package ctl
 
 import (
 	"bufio"
-	"fmt"
-	"github.com/luobote55/java2go/gen"
-	"github.com/luobote55/java2go/internal/match"
-	"github.com/luobote55/java2go/internal/strs"
-	"github.com/pkg/errors"
 	"io"
-	"os"
 	"path/filepath"
-	"sort"
+	"regexp"
+	"strconv"
 	"strings"
-)
 
-const (
-	version = "1.0.0"
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/gen/proto"
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/internal/match"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
 )
 
-// A Generator represents the state of a single Go source file
-// being scanned for generator commands.
+// A state holds what the files of a Parse run share: the parsed classes,
+// the names given out and the messages defined so far. Files are scanned
+// concurrently but resolved against the state one at a time, in source
+// order.
+type state struct {
+	cfg     *config.Config
+	out     *gen.Output
+	names   *naming.Namer
+	classes *classes
+	msgs    map[string]*ir.Entity // VO and request classes by name.
+	emitted map[string]*ir.Entity // shared messages already defined, by proto package and name.
+	defined map[string]bool       // messages already defined, by proto package and name.
+}
+
+// A Generator represents the state of a single controller file
+// being scanned for endpoints.
 type Generator struct {
+	*state
 	r        io.Reader
 	path     string // full rooted path name.
 	dir      string // full rooted directory of file.
 	file     string // base name of file.
 	target   string
-	goo      string
 	pkg      string
 	commands map[string][]string
 	lineNum  int // current line number.
 	env      []string
+	scope    *java.Scope
+
+	class     string  // simple name of the controller class.
+	classLine int     // line of the class declaration.
+	decls     []*decl // endpoints whose signatures are to be resolved.
+
+	svc      *ir.Service
+	needMsgs map[string]*ir.Entity // messages used by this file, by name.
+	rpcs     map[string]bool       // RPC names of the service.
 }
 
-type Rpc struct {
-	Name       string
-	Service    string
-	Comment    string
-	Http       string
-	HttpUrl    string
-	Rpc        string
-	RequestTyp string
-	ReplyTyp   string
+// A decl is an endpoint scanned from a controller, with the method
+// signature it is completed from.
+type decl struct {
+	ep   *ir.Endpoint
+	sig  string
+	line int // line where the signature ends.
 }
 
-// run runs the generators in the current file.
-func (g *Generator) run(msgs, ctrlNeedMsgs map[string]*Message) (ok bool) {
+// run scans the controller into g.svc and g.decls. It only reads the
+// shared state, so that files can be scanned concurrently. It reports
+// whether the file declares a class.
+func (g *Generator) run() (ok bool) {
 	// Processing below here calls g.errorf on failure, which does panic(stop).
 	// If we encounter an error, we abort the package.
 	defer func() {
@@ -59,582 +81,422 @@ func (g *Generator) run(msgs, ctrlNeedMsgs map[string]*Message) (ok bool) {
 	}()
 	g.dir, g.file = filepath.Split(g.path)
 	g.dir = filepath.Clean(g.dir) // No final separator please.
-	g.target = strs.JSONSnakeCase(strings.Replace(strings.Replace(g.file, "DO", "", -1), "java", "proto", -1))
-	if g.goo == "" {
-		g.goo = "./"
-	}
+	g.svc = &ir.Service{Source: g.path}
+	g.needMsgs = make(map[string]*ir.Entity)
+	g.rpcs = make(map[string]bool)
+	g.scope = java.NewScope()
 
-	// 检查路径是否存在
-	if _, err := os.Stat(g.goo); os.IsNotExist(err) {
-		fmt.Println("目标目录并不存在：" + g.goo)
-		return false
-	}
-
-	replyMsgs := make(map[string]*Message)   // 组合路径和文件名
-	requestMsgs := make(map[string]*Message) // 组合路径和文件名
-	filepath := filepath.Join(g.goo, g.target)
-	// 检查文件是否存在
-	if _, err := os.Stat(filepath); err == nil || os.IsExist(err) {
-		fmt.Println("文件已经存在：" + filepath + "， 如要更新先删除")
-		return false
-	}
-	// Scan for lines that start "//go:generate".
-	// Can't use bufio.Scanner because it can't handle long lines,
-	// which are likely to appear when using generate.
+	// Can't use bufio.Scanner because it can't handle long lines.
 	input := bufio.NewReader(g.r)
 	var err error
 	// One line per loop.
-	file := gen.NewGeneratedFile()
-
-	var rpc *Rpc = nil
+	var ep *ir.Endpoint = nil
+	var body *ir.Endpoint = nil      // endpoint whose method body is being read.
+	sig := ""                        // method signature, which may span several lines.
+	annotations := make([]string, 0) // annotations of the next declaration.
 	for {
 		g.lineNum++ // 1-indexed.
 		var buf []byte
 		buf, err = input.ReadSlice('\n')
 		if err != nil {
-			// Check for marker at EOF without final \n.
-			if err == io.EOF {
-				err = io.ErrUnexpectedEOF
-			}
 			break
 		}
+		if sig != "" {
+			sig += " " + strings.TrimSpace(string(buf))
+			if signatureDone(sig) {
+				g.decls = append(g.decls, &decl{ep: ep, sig: sig, line: g.lineNum})
+				body, ep, sig = ep, nil, ""
+			}
+			continue
+		}
+		if body != nil {
+			line := strings.TrimSpace(string(buf))
+			if call := serviceCall(line); call != "" && (body.Call == "" || strings.HasPrefix(line, "return ")) {
+				body.Call = call
+			}
+		}
+		if line := strings.TrimSpace(string(buf)); strings.HasPrefix(line, "@") {
+			annotations = append(annotations, line)
+		}
+		if g.class == "" && g.scope.Line(string(buf)) {
+			continue
+		}
 		if strings.HasPrefix(string(buf), "@Api(tags = ") {
-			file.ApiModel = match.FindDoubleQuotes(string(buf))
+			g.svc.Comment = unquotes(match.FindDoubleQuotes(string(buf)))
 		} else if strings.HasPrefix(string(buf), "@RequestMapping(") {
-			file.SetUrl(match.FindDoubleQuote(string(buf)))
+			g.svc.Path = mappingPath(string(buf))
 		} else if strings.HasPrefix(string(buf), "public class ") {
-			file.ServiceName = strings.Replace(match.FindFix(string(buf), `public class (.*?) {`), "Controller", "", 1)
-			g.header(file)
+			g.class = match.FindFix(string(buf), `public class (.*?) `)
+			g.svc.Class = g.scope.Qualify(g.class)
+			g.classLine = g.lineNum
+			g.svc.Annotations, annotations = annotations, make([]string, 0)
 		} else if strings.HasPrefix(string(buf), "    @GetMapping(") {
-			rpc = new(Rpc)
-			rpc.Http = "get"
-			rpc.HttpUrl = strings.Replace(match.FindDoubleQuote(string(buf)), "\"", "", -1)
+			body = nil
+			ep = &ir.Endpoint{Method: "get", Path: mappingPath(string(buf))}
 		} else if strings.HasPrefix(string(buf), "    @PostMapping(") {
-			rpc = new(Rpc)
-			rpc.Http = "post"
-			rpc.HttpUrl = strings.Replace(match.FindDoubleQuote(string(buf)), "\"", "", -1)
+			body = nil
+			ep = &ir.Endpoint{Method: "post", Path: mappingPath(string(buf))}
 		} else if strings.HasPrefix(string(buf), "    @ApiOperation(") {
-			if rpc == nil {
-				continue
-			}
-			rpc.Comment = match.FindDoubleQuote(string(buf))
-		} else if strings.Contains(string(buf), "@RequestBody") {
-			if rpc == nil {
-				continue
-			}
-			if rpc.Rpc == "" {
-				g.runRpc(rpc, buf)
-			}
-			if rpc.ReplyTyp == "" {
-				g.runReply(rpc, msgs, ctrlNeedMsgs, replyMsgs, buf)
-			}
-			g.runRequestBody(rpc, msgs, ctrlNeedMsgs, requestMsgs, buf)
-			g.rpc(file, rpc)
-			rpc = nil
-		} else if strings.Contains(string(buf), "@RequestParam") {
-			if rpc == nil {
+			if ep == nil {
 				continue
 			}
-			if rpc.Rpc == "" {
-				g.runRpc(rpc, buf)
-			}
-			if rpc.ReplyTyp == "" {
-				g.runReply(rpc, msgs, ctrlNeedMsgs, replyMsgs, buf)
-			}
-			g.runRequestParam(rpc, msgs, ctrlNeedMsgs, requestMsgs, buf)
-			g.rpc(file, rpc)
-			rpc = nil
-		} else if strings.Contains(string(buf), "@PathVariable") {
-			if rpc == nil {
+			ep.Comment = unquote(match.FindDoubleQuote(string(buf)))
+		} else if strings.HasPrefix(strings.TrimSpace(string(buf)), "public ") {
+			if ep == nil {
+				annotations = make([]string, 0)
 				continue
 			}
-			if rpc.Rpc == "" {
-				g.runRpc(rpc, buf)
+			ep.Annotations, annotations = annotations, make([]string, 0)
+			sig = strings.TrimSpace(string(buf))
+			if signatureDone(sig) {
+				g.decls = append(g.decls, &decl{ep: ep, sig: sig, line: g.lineNum})
+				body, ep, sig = ep, nil, ""
 			}
-			if rpc.ReplyTyp == "" {
-				g.runReply(rpc, msgs, ctrlNeedMsgs, replyMsgs, buf)
-			}
-			g.runPathVariable(rpc, msgs, ctrlNeedMsgs, requestMsgs, buf)
-			g.rpc(file, rpc)
-			rpc = nil
-		} else if strings.Contains(string(buf), "()") {
-			if rpc == nil {
-				continue
-			}
-			if rpc.Rpc == "" {
-				g.runRpc(rpc, buf)
-			}
-			if rpc.ReplyTyp == "" {
-				g.runReply(rpc, msgs, ctrlNeedMsgs, replyMsgs, buf)
-			}
-			g.runRequestVoid(rpc, msgs, ctrlNeedMsgs, requestMsgs, buf)
-			g.rpc(file, rpc)
-			rpc = nil
-		} else {
-			if rpc == nil {
-				continue
-			}
-			if rpc.Comment == "" {
-				continue
-			}
-			if strings.Contains(string(buf), "@EventLog") {
-				continue
-			}
-			if strings.Contains(string(buf), "@RequireRole") {
-				continue
-			}
-			g.runRpc(rpc, buf)
-			g.runReply(rpc, msgs, ctrlNeedMsgs, replyMsgs, buf)
-			requests := match.FindFix(string(buf), `\((.*?)\) {`)
-			if requests == "" {
-				continue
-			}
-			request := strings.Split(requests, " ")[0]
-			typ, err := JaveType(request)
-			if err != nil {
-				typ = request
-				rpc.RequestTyp = typ
-				g.needMsg(msgs, ctrlNeedMsgs, requestMsgs, request)
-			} else {
-				rpc.RequestTyp = typ
-				g.needRequest(requestMsgs, rpc, request)
-			}
-			g.rpc(file, rpc)
-			rpc = nil
 		}
 	}
-	file.P("}")
-	file.P("")
-	g.message(file, requestMsgs, replyMsgs)
+	return g.class != ""
+}
 
-	err = file.WriteFile(filepath)
+// resolve names the service and completes its endpoints from their
+// signatures, defining the messages they use. It writes the shared state,
+// so files are resolved one at a time. It reports whether the file is a
+// controller.
+func (g *Generator) resolve() bool {
+	g.lineNum = g.classLine
+	name, err := g.names.Assign(g.svc.Class, g.cfg.Naming.Services.Apply(g.class), g.class, packageName(g.svc.Class))
 	if err != nil {
-		fmt.Println(err)
+		g.warnf("%v", err)
 	}
-	return true
+	g.svc.Name = name
+	for _, d := range g.decls {
+		g.lineNum = d.line
+		g.endpoint(d.ep, d.sig)
+	}
+	g.svc.SortMessages()
+	return len(g.svc.Endpoints) > 0 || isController(g.svc.Annotations)
 }
 
-func (g *Generator) runRpc(rpc *Rpc, buf []byte) {
-	rpcBufs := match.FindFix(string(buf), ` (.*?)\(@RequestBody `)
-	if rpcBufs == "" {
-		rpcBufs = match.FindFix(string(buf), ` (.*?)\(@RequestParam `)
-	}
-	if rpcBufs == "" {
-		rpcBufs = match.FindFix(string(buf), ` (.*?)\(@PathVariable `)
-	}
-	if rpcBufs == "" {
-		rpcBufs = match.FindFix(string(buf), ` (.*?)\(\) `)
+// isController reports whether a class is annotated as a Spring controller.
+func isController(annotations []string) bool {
+	for _, a := range annotations {
+		if strings.HasPrefix(a, "@RestController") || strings.HasPrefix(a, "@Controller") {
+			return true
+		}
 	}
-	if rpcBufs == "" {
-		rpcBufs = match.FindFix(string(buf), ` (.*?)\(`)
+	return false
+}
+
+func (g *Generator) warnf(format string, args ...interface{}) {
+	g.out.Warnf(g.path, g.lineNum, format, args...)
+}
+
+// mappingPath returns the path of a @XxxMapping annotation.
+func mappingPath(s string) string {
+	paths := match.FindDoubleQuotes(s)
+	if len(paths) == 0 {
+		return ""
 	}
-	rpcBufss := strings.Split(rpcBufs, " ")
-	rpc.Rpc = strs.GoCamelCase(rpcBufss[len(rpcBufss)-1])
+	return unquote(paths[0])
 }
 
-func (g *Generator) runReply(rpc *Rpc, msgs, ctrlNeedMsgs map[string]*Message, replyMsgs map[string]*Message, buf []byte) {
-	replyBufs := match.FindFix(string(buf), `    public (.*?) `)
-	replyBufss := strings.Split(replyBufs, " ")
-	reply := replyBufss[0]
-	if strings.Contains(reply, "DataGrid<") {
-		pageStr := match.FindFix(reply, `(.*?)<`)
-		if pageStr == "DataGrid" {
-		} else {
-			fmt.Println("未识别的变量：" + pageStr)
-		}
-		value := match.FindFix(reply, `<(.*?)>`)
-		typ, err := JaveType(value)
-		if err != nil {
-			typ = value
-		}
-		pageMsg, err := g.needPageMsg(msgs, ctrlNeedMsgs, replyMsgs, typ)
-		if err != nil {
-			return
-		}
-		rpc.ReplyTyp = rpc.Rpc + "Reply"
-		replyMsg := GenMessage(pageMsg, rpc.ReplyTyp).SetChild(pageMsg.StructName)
-		replyMsg.ApiModel = []string{rpc.ReplyTyp}
-		replyMsg.StructName = rpc.ReplyTyp
-		rpcStructer(replyMsg)
-		field := &MessageField{
-			Name:     pageMsg.StructName,
-			Comment:  pageMsg.StructName,
-			Typ:      pageMsg.StructName,
-			Repeated: "",
-		}
-		rpcField(replyMsg, field)
-		replyMsg.P("}")
-		replyMsgs[rpc.ReplyTyp] = replyMsg
-	} else if strings.Contains(reply, "List<") {
-		pageStr := match.FindFix(reply, `(.*?)<`)
-		value := match.FindFix(reply, `<(.*?)>`)
-		if pageStr == "List" {
-		} else {
-			fmt.Println("未识别的变量：" + pageStr)
-		}
-		typ, err := JaveType(value)
-		if err != nil {
-			typ = value
-		}
-		pageMsg, err := g.needListMsg(msgs, ctrlNeedMsgs, replyMsgs, typ)
-		if err != nil {
-			return
-		}
-		rpc.ReplyTyp = rpc.Rpc + "Reply"
-		replyMsg := GenMessage(pageMsg, rpc.ReplyTyp).SetChild(typ)
-		replyMsg.ApiModel = []string{rpc.ReplyTyp}
-		replyMsg.StructName = rpc.ReplyTyp
-		rpcStructer(replyMsg)
-		field := &MessageField{
-			Name:     pageMsg.StructName,
-			Comment:  pageMsg.StructName,
-			Typ:      pageMsg.StructName,
-			Repeated: "",
-		}
-		rpcField(replyMsg, field)
-		replyMsg.P("}")
-		replyMsgs[rpc.ReplyTyp] = replyMsg
-	} else if reply == "int" {
-		g.needReply(replyMsgs, rpc, "int32")
-	} else if reply == "Integer" {
-		g.needReply(replyMsgs, rpc, "int32")
-	} else if reply == "String" {
-		g.needReply(replyMsgs, rpc, "string")
-	} else if reply == "Long" {
-		g.needReply(replyMsgs, rpc, "int64")
-	} else if reply == "Float" {
-		g.needReply(replyMsgs, rpc, "float")
-	} else if reply == "Double" {
-		g.needReply(replyMsgs, rpc, "double")
-	} else if reply == "Boolean" {
-		g.needReply(replyMsgs, rpc, "bool")
-	} else if reply == "HttpWrapper<?>" {
-		g.needReply(replyMsgs, rpc, "string")
-	} else if reply == "void" {
-		g.needReply(replyMsgs, rpc, "string")
-	} else {
-		var msg *Message
-		replyTyp, err := JaveType(reply)
-		if err != nil {
-			replyTyp = reply
-			msg, err = g.needMsg(msgs, ctrlNeedMsgs, replyMsgs, replyTyp)
-			if err != nil {
-				return
-			}
-		}
-		rpc.ReplyTyp = rpc.Rpc + "Reply"
-		replyMsg := GenMessage(msg, rpc.ReplyTyp).SetChild(replyTyp)
-		replyMsg.ApiModel = []string{rpc.ReplyTyp}
-		replyMsg.StructName = rpc.ReplyTyp
-		rpcStructer(replyMsg)
-		if msg == nil {
-			field := &MessageField{
-				Name:     "data",
-				Comment:  "",
-				Typ:      replyTyp,
-				Repeated: "",
-			}
-			rpcField(replyMsg, field)
-		} else {
-			field := &MessageField{
-				Name:     msg.StructName,
-				Comment:  msg.StructName,
-				Typ:      msg.StructName,
-				Repeated: "",
+var serviceCallRe = regexp.MustCompile(`\b[a-z]\w*Service\.\w+\(`)
+
+// serviceCall returns the first call of a Spring service in a line of a
+// method body, e.g. "deviceMonitorService.getMonitorConfig(deviceId)" for
+// `return deviceMonitorService.getMonitorConfig(deviceId);`. A call whose
+// arguments continue on the next line is cut at the end of the line.
+func serviceCall(line string) string {
+	loc := serviceCallRe.FindStringIndex(line)
+	if loc == nil {
+		return ""
+	}
+	depth := 0
+	for i := loc[1] - 1; i < len(line); i++ {
+		switch line[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return line[loc[0] : i+1]
 			}
-			rpcField(replyMsg, field)
 		}
-		replyMsg.P("}")
-		replyMsgs[rpc.ReplyTyp] = replyMsg
 	}
-	return
+	return strings.TrimSuffix(line[loc[0]:], ";")
 }
 
-func (g *Generator) runRequestBody(rpc *Rpc, msgs, ctrlNeedMsgs map[string]*Message, requestMsgs map[string]*Message, buf []byte) {
-	request := match.FindFix(string(buf), `@RequestBody (.*?) `)
-	if request == "" {
-		request = findParam("@RequestBody", string(buf))
-	}
-	typ, err := JaveType(request)
-	if err != nil {
-		typ = request
-		rpc.RequestTyp = typ
-		g.needMsg(msgs, ctrlNeedMsgs, requestMsgs, request)
-	} else {
-		rpc.RequestTyp = typ
-		g.needRequest(requestMsgs, rpc, request)
-	}
+// signatureDone reports whether sig holds a complete parameter list.
+func signatureDone(sig string) bool {
+	return strings.Contains(sig, "(") && strings.Count(sig, "(") == strings.Count(sig, ")")
 }
 
-func (g *Generator) runRequestParam(rpc *Rpc, msgs, ctrlNeedMsgs map[string]*Message, requestMsgs map[string]*Message, buf []byte) {
-	request := match.FindFix(string(buf), `@RequestParam (.*?) `)
-	if request == "" {
-		request = findParam("@RequestParam", string(buf))
+// endpoint completes ep from its method signature, e.g.
+// `public DeviceVO getDevice(@RequestParam Long id) {`.
+func (g *Generator) endpoint(ep *ir.Endpoint, sig string) {
+	open := strings.Index(sig, "(")
+	end := strings.LastIndex(sig, ")")
+	head := strings.TrimSpace(sig[:open])
+	index := strings.LastIndex(head, " ")
+	if index < 0 {
+		g.warnf("无法识别的方法：%s", sig)
+		return
 	}
-	typ, err := JaveType(request)
-	if err != nil {
-		typ = request
-		rpc.RequestTyp = typ
-		g.needMsg(msgs, ctrlNeedMsgs, requestMsgs, request)
-	} else {
-		rpc.RequestTyp = typ
-		g.needRequest(requestMsgs, rpc, request)
+	ep.Name = strs.GoCamelCase(head[index+1:])
+	reply := strings.TrimSpace(strings.TrimPrefix(head[:index], "public"))
+	params := make([]*ir.Param, 0)
+	for _, s := range java.SplitParams(sig[open+1 : end]) {
+		if p := g.param(s); p != nil {
+			params = append(params, p)
+		}
 	}
+	ep.Params = params
+	g.rename(ep)
+	g.runReply(ep, reply)
+	g.runRequest(ep)
+	g.svc.Endpoints = append(g.svc.Endpoints, ep)
 }
 
-func (g *Generator) runPathVariable(rpc *Rpc, msgs, ctrlNeedMsgs map[string]*Message, requestMsgs map[string]*Message, buf []byte) {
-	request := match.FindFix(string(buf), `@PathVariable (.*?) `)
-	if request == "" {
-		request = findParam("@PathVariable", string(buf))
+// rename makes the RPC name of ep unique within the service. Overloaded
+// methods and GET/POST variants share a Java name, which protobuf rejects.
+func (g *Generator) rename(ep *ir.Endpoint) {
+	name := ep.Name
+	if !g.rpcs[name] {
+		g.rpcs[name] = true
+		return
 	}
-	typ, err := JaveType(request)
-	if err != nil {
-		typ = request
-		rpc.RequestTyp = typ
-		g.needMsg(msgs, ctrlNeedMsgs, requestMsgs, request)
+	candidates := make([]string, 0)
+	if g.cfg.Ctl.Rename == config.RenameParams {
+		candidates = append(candidates, name+ir.ParamsSuffix(ep.Params))
 	} else {
-		rpc.RequestTyp = typ
-		g.needRequest(requestMsgs, rpc, request)
+		candidates = append(candidates, name+pathSuffix(ep.Path))
 	}
+	candidates = append(candidates, name+strs.GoCamelCase(ep.Method))
+	for _, c := range candidates {
+		if c != name && !g.rpcs[c] {
+			ep.Name = c
+			break
+		}
+	}
+	for i := 2; g.rpcs[ep.Name]; i++ {
+		ep.Name = name + strconv.Itoa(i)
+	}
+	g.rpcs[ep.Name] = true
+	g.warnf("重复的rpc：%s 改为 %s", name, ep.Name)
 }
 
-func findParam(fix string, s string) string {
-	ii := strings.Index(s, fix)
-	ss := strings.Split(s[ii+len(fix):], " ")
-	return ss[1]
-}
-
-func (g *Generator) runRequestVoid(rpc *Rpc, msgs, ctrlNeedMsgs map[string]*Message, requestMsgs map[string]*Message, buf []byte) {
-	g.needRequest(requestMsgs, rpc, rpc.Name)
-}
-
-func (g *Generator) needRequest(requestMsgs map[string]*Message, rpc *Rpc, s string) {
-	rpc.RequestTyp = rpc.Rpc + "Request"
-	reqMsg := NewMessage()
-	reqMsg.ApiModel = []string{rpc.RequestTyp}
-	reqMsg.StructName = rpc.RequestTyp
-	rpcStructer(reqMsg)
-	reqMsg.P("}")
-	requestMsgs[rpc.RequestTyp] = reqMsg
-}
-
-func (g *Generator) needReply(replyMsgs map[string]*Message, rpc *Rpc, s string) {
-	rpc.ReplyTyp = rpc.Rpc + "Reply"
-	replyMsg := NewMessage()
-	replyMsg.ApiModel = []string{rpc.ReplyTyp}
-	replyMsg.StructName = rpc.ReplyTyp
-	rpcStructer(replyMsg)
-	field := &MessageField{
-		Name:     "data",
-		Comment:  "",
-		Typ:      s,
-		Repeated: "",
-	}
-	rpcField(replyMsg, field)
-	replyMsg.P("}")
-	replyMsgs[rpc.ReplyTyp] = replyMsg
+// pathSuffix returns the name suffix derived from the last segment of a
+// mapping path, e.g. "ById" for "/device/{id}" and "ListAll" for "/list-all".
+func pathSuffix(path string) string {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	seg := segs[len(segs)-1]
+	seg = strings.Replace(seg, "-", "_", -1)
+	if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+		return "By" + strs.GoCamelCase(strings.Trim(seg, "{}"))
+	}
+	return strs.GoCamelCase(seg)
 }
 
-func (g *Generator) needPageMsg(msgs, ctrlNeedMsgs map[string]*Message, needMsgs map[string]*Message, reply string) (*Message, error) {
-	msg, err := g.needMsg(msgs, ctrlNeedMsgs, needMsgs, reply)
-	if err != nil {
-		return nil, err
-	}
-	pageReply := "Page" + reply
-	pageMsg, ok := needMsgs[pageReply]
-	if ok {
-		return pageMsg, nil
-	}
-	pageMsg, ok = ctrlNeedMsgs[pageReply]
-	if ok {
-		return pageMsg, nil
-	}
-	pageMsg = GenMessage(msg, pageReply).SetChild(reply)
-	pageMsg.ApiModel = []string{pageReply}
-	pageMsg.StructName = pageReply
-	rpcStructer(pageMsg)
-	field := &MessageField{
-		Name:     msg.StructName,
-		Comment:  msg.StructName,
-		Typ:      msg.StructName,
-		Repeated: "repeated ",
-	}
-	rpcField(pageMsg, field)
-	rpcPage(pageMsg, field)
-	pageMsg.P("}")
-	needMsgs[pageReply] = pageMsg
-	ctrlNeedMsgs[pageReply] = pageMsg
-	return pageMsg, nil
+// param parses a method parameter such as `@RequestParam(required = false) Long id`.
+// Servlet objects are not part of the API and yield nil.
+func (g *Generator) param(s string) *ir.Param {
+	p := &ir.Param{In: "query"}
+	annotations, s := java.StripAnnotations(s)
+	for _, a := range annotations {
+		switch a {
+		case "RequestBody":
+			p.In = "body"
+		case "PathVariable":
+			p.In = "path"
+		}
+	}
+	s = strings.TrimPrefix(s, "final ")
+	index := strings.LastIndex(s, " ")
+	if index < 0 {
+		return nil
+	}
+	typ := strings.TrimSpace(s[:index])
+	if strings.HasPrefix(typ, "HttpServlet") {
+		return nil
+	}
+	p.Name = s[index+1:]
+	p.Type, p.Repeated = g.fieldType(typ)
+	return p
 }
 
-func (g *Generator) needListMsg(msgs, ctrlNeedMsgs map[string]*Message, needMsgs map[string]*Message, reply string) (*Message, error) {
-	var msg *Message
-	replyTyp, err := JaveType(reply)
-	if err != nil {
-		replyTyp = reply
-		msg, err = g.needMsg(msgs, ctrlNeedMsgs, needMsgs, replyTyp)
-		if err != nil {
-			return nil, err
+// runRequest sets the request message of ep. A request body or a single
+// class parameter is used as is; otherwise the parameters are collected
+// into a synthesized XxxRequest message.
+func (g *Generator) runRequest(ep *ir.Endpoint) {
+	for _, p := range ep.Params {
+		if p.Type.Kind != ir.Message || p.Repeated {
+			continue
+		}
+		if p.In == "body" || len(ep.Params) == 1 {
+			ep.Request = p.Type.Name
+			g.needMsg(p.Type.Name)
+			return
 		}
 	}
-	pageReply := "List" + replyTyp
-	pageMsg, ok := needMsgs[pageReply]
-	if ok {
-		return pageMsg, nil
-	}
-	pageMsg, ok = ctrlNeedMsgs[pageReply]
-	if ok {
-		for index := 1; index < 100; index++ {
-			pageReplyIndex := fmt.Sprintf("%s%d", pageReply, index)
-			pageMsg, ok = ctrlNeedMsgs[pageReplyIndex]
-			if !ok {
-				pageReply = pageReplyIndex
-				break
-			}
+	ep.Request = g.synthesized(ep.Name + "Request")
+	reqMsg := &ir.Entity{Name: ep.Request, Comment: ep.Request}
+	for _, p := range ep.Params {
+		if p.Type.Kind == ir.Message {
+			g.needMsg(p.Type.Name)
 		}
+		reqMsg.Fields = append(reqMsg.Fields, &ir.Field{
+			Name:     p.Name,
+			Type:     p.Type,
+			Repeated: p.Repeated,
+		})
 	}
-	pageMsg = GenMessage(msg, pageReply).SetChild(replyTyp)
-	pageMsg.ApiModel = []string{pageReply}
-	pageMsg.StructName = pageReply
-	rpcStructer(pageMsg)
-	if msg == nil {
-		field := &MessageField{
-			Name:     "data",
-			Comment:  "",
-			Typ:      replyTyp,
-			Repeated: "repeated ",
+	g.define(reqMsg, false)
+}
+
+// runReply sets the reply message of ep from the Java return type.
+func (g *Generator) runReply(ep *ir.Endpoint, reply string) {
+	ep.Reply = g.synthesized(ep.Name + "Reply")
+	replyMsg := &ir.Entity{Name: ep.Reply, Comment: ep.Reply}
+	if strings.Contains(reply, "DataGrid<") {
+		pageStr := match.FindFix(reply, `(.*?)<`)
+		if pageStr != "DataGrid" {
+			g.warnf("未识别的变量：%s", pageStr)
 		}
-		rpcField(pageMsg, field)
+		pageMsg := g.needPageMsg(match.FindFix(reply, `DataGrid<(.*)>`))
+		replyMsg.Fields = append(replyMsg.Fields, refField(pageMsg.Name))
+	} else if strings.Contains(reply, "List<") {
+		pageStr := match.FindFix(reply, `(.*?)<`)
+		if pageStr != "List" {
+			g.warnf("未识别的变量：%s", pageStr)
+		}
+		listMsg := g.needListMsg(match.FindFix(reply, `List<(.*)>`))
+		replyMsg.Fields = append(replyMsg.Fields, refField(listMsg.Name))
+	} else if reply == "void" || strings.HasPrefix(reply, "HttpWrapper<") {
+		replyMsg.Fields = append(replyMsg.Fields, &ir.Field{Name: "data", Type: ir.Scalar(ir.String)})
 	} else {
-		field := &MessageField{
-			Name:     msg.StructName,
-			Comment:  msg.StructName,
-			Typ:      msg.StructName,
-			Repeated: "repeated ",
+		typ, err := g.cfg.JavaType(reply)
+		if err != nil {
+			g.needMsg(g.message(reply))
+			replyMsg.Fields = append(replyMsg.Fields, refField(g.message(reply)))
+		} else {
+			replyMsg.Fields = append(replyMsg.Fields, &ir.Field{Name: "data", Type: typ})
 		}
-		rpcField(pageMsg, field)
 	}
-	pageMsg.P("}")
-	needMsgs[pageReply] = pageMsg
-	ctrlNeedMsgs[pageReply] = pageMsg
-	return pageMsg, nil
+	g.define(replyMsg, false)
 }
 
-func (g *Generator) needMsg(msgs, ctrlNeedMsgs map[string]*Message, needMsgs map[string]*Message, reply string) (*Message, error) {
-	msg, ok := needMsgs[reply]
-	if ok {
-		return msg, nil
-	}
-	msg, ok = ctrlNeedMsgs[reply]
-	if ok {
-		return msg, nil
-	}
-	_, err := JaveType(reply)
-	if err == nil {
-		return nil, nil
-	}
+// fieldType is javaFieldType with class references renamed to the names
+// of their messages.
+func (g *Generator) fieldType(decl string) (ir.Type, bool) {
+	typ, repeated := javaFieldType(g.cfg, decl)
+	if typ.Kind == ir.Message {
+		typ.Name = g.message(typ.Name)
+	}
+	return typ, repeated
+}
 
-	msg, ok = msgs[reply]
-	if !ok {
-		fmt.Println("没有找到这个message：" + reply)
-		return nil, errors.New("没有找到这个message：" + reply)
-	}
-	needMsgs[reply] = msg.GenSort()
-	ctrlNeedMsgs[reply] = msg
-	for _, s := range msg.Child {
-		g.needMsg(msgs, ctrlNeedMsgs, needMsgs, s)
-	}
-	return msg, nil
+// message returns the message name of a VO or request class used in the
+// controller.
+func (g *Generator) message(class string) string {
+	return g.classes.message(g.scope, class, g.warnf)
 }
 
-func rpcPage(pageMsg *Message, field *MessageField) {
-	field = &MessageField{
-		Name:     "pages",
-		Comment:  "pages",
-		Typ:      "int32",
-		Repeated: "",
-	}
-	rpcField(pageMsg, field)
-	field = &MessageField{
-		Name:     "offset",
-		Comment:  "offset",
-		Typ:      "int32",
-		Repeated: "",
-	}
-	rpcField(pageMsg, field)
-	field = &MessageField{
-		Name:     "total",
-		Comment:  "total",
-		Typ:      "int32",
-		Repeated: "",
-	}
-	rpcField(pageMsg, field)
-	field = &MessageField{
-		Name:     "prePage",
-		Comment:  "prePage",
-		Typ:      "int32",
-		Repeated: "",
-	}
-	rpcField(pageMsg, field)
-	field = &MessageField{
-		Name:     "nextPage",
-		Comment:  "nextPage",
-		Typ:      "int32",
-		Repeated: "",
-	}
-	rpcField(pageMsg, field)
+// refField returns a field holding the named message.
+func refField(name string) *ir.Field {
+	return &ir.Field{Name: name, Comment: name, Type: ir.Ref(name)}
 }
 
-func (g *Generator) header(file *gen.GeneratedFile) {
-	file.P("// Code generated by j2g. DO NOT EDIT.")
-	file.P("// versions:")
-	file.P("// - j2g v", version)
-	file.P("syntax = \"proto3\";")
-	file.P("")
-	file.P("package api." + file.Urls[0] + ".v1;")
-	file.P("")
-	file.P("import \"google/api/annotations.proto\";")
-	file.P("//import \"google/protobuf/timestamp.proto\";")
-	file.P("")
-	file.P("option go_package = \"api/" + file.Urls[0] + "/v1;v1\";")
-	file.P("option java_multiple_files = true;")
-	file.P("option java_package = \"api." + file.Urls[0] + "\";")
-	file.P("")
-	file.P("service " + file.ServiceName + " {")
+func (g *Generator) needPageMsg(value string) *ir.Entity {
+	typ, _ := g.fieldType(value)
+	return g.needPage(typ)
 }
 
-func (g *Generator) rpc(file *gen.GeneratedFile, rpc *Rpc) {
-	file.P("  // " + rpc.Comment)
-	if rpc.HttpUrl == "" {
-		file.P("  rpc " + rpc.Rpc + "(" + rpc.RequestTyp + ") returns (" + rpc.ReplyTyp + ");")
-		return
+// needPage makes the page of typ, and the message of its elements, part
+// of the output.
+func (g *Generator) needPage(typ ir.Type) *ir.Entity {
+	pageMsg := ir.Page(typ)
+	if msg := g.lookup(pageMsg.Name); msg != nil {
+		return msg
+	}
+	if typ.Kind == ir.Message {
+		g.needMsg(typ.Name)
+	}
+	g.define(pageMsg, true)
+	return pageMsg
+}
+
+func (g *Generator) needListMsg(value string) *ir.Entity {
+	typ, _ := g.fieldType(value)
+	return g.needList(typ)
+}
+
+// needList makes the list of typ, and the message of its elements, part
+// of the output.
+func (g *Generator) needList(typ ir.Type) *ir.Entity {
+	listMsg := ir.List(typ)
+	if msg := g.lookup(listMsg.Name); msg != nil {
+		return msg
+	}
+	if typ.Kind == ir.Message {
+		g.needMsg(typ.Name)
+	}
+	g.define(listMsg, true)
+	return listMsg
+}
+
+// needMsg makes the named VO or request class, and the classes it refers
+// to, part of the output.
+func (g *Generator) needMsg(reply string) *ir.Entity {
+	if msg := g.lookup(reply); msg != nil {
+		return msg
+	}
+	msg, ok := g.msgs[reply]
+	if !ok {
+		g.warnf("没有找到这个message：%s", reply)
+		return nil
 	}
-	file.P("  rpc " + rpc.Rpc + "(" + rpc.RequestTyp + ") returns (" + rpc.ReplyTyp + "){")
-	file.P("    option (google.api.http) = {")
-	file.P("      " + rpc.Http + ": \"" + file.Url + rpc.HttpUrl + "\"")
-	if rpc.Http == "post" {
-		file.P("      body: \"*\"")
+	g.define(msg, true)
+	for _, s := range msg.Deps() {
+		g.needMsg(s)
 	}
-	file.P("    };")
-	file.P("  };")
+	return msg
 }
 
-func (g *Generator) message(file *gen.GeneratedFile, requestMsgs map[string]*Message, replyMsgs map[string]*Message) {
-	msgs := make([]*Message, 0)
-	for _, msg := range requestMsgs {
-		msgs = append(msgs, msg)
+// lookup returns the named message if this file, or another controller of
+// the same proto package, already defines it.
+func (g *Generator) lookup(name string) *ir.Entity {
+	if msg, ok := g.needMsgs[name]; ok {
+		return msg
 	}
-	for _, msg := range replyMsgs {
-		msgs = append(msgs, msg)
+	if msg, ok := g.emitted[proto.Package(g.svc, g.cfg)+"."+name]; ok {
+		g.needMsgs[name] = msg
+		return msg
 	}
-	sort.Slice(msgs, func(ii, jj int) bool {
-		return msgs[ii].sortNum < msgs[jj].sortNum
-	})
-	for _, msg := range msgs {
-		file.P(msg.buf.String())
-	}
-	file.Timestamp()
+	return nil
 }
 
-func (g *Generator) fill(file *gen.GeneratedFile, msg *Message) {
+// synthesized returns the name of a request or reply message synthesized
+// for an endpoint: name, or the service name followed by name if the
+// proto package already defines a message of that name.
+func (g *Generator) synthesized(name string) string {
+	if !g.defined[proto.Package(g.svc, g.cfg)+"."+name] {
+		return name
+	}
+	renamed := g.svc.Name + name
+	g.warnf("proto 包 %s 中已有 message %s，改名为 %s", proto.Package(g.svc, g.cfg), name, renamed)
+	return renamed
+}
+
+// define adds msg to the output of the service. Shared messages are only
+// defined once per proto package.
+func (g *Generator) define(msg *ir.Entity, shared bool) {
+	if _, ok := g.needMsgs[msg.Name]; ok {
+		return
+	}
+	g.needMsgs[msg.Name] = msg
+	key := proto.Package(g.svc, g.cfg) + "." + msg.Name
+	g.defined[key] = true
+	if shared {
+		g.emitted[key] = msg
+	}
+	g.svc.Messages = append(g.svc.Messages, msg)
 }
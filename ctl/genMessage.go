@@ -2,106 +2,42 @@ package ctl
 
 import (
 	"bufio"
-	"bytes"
-	"fmt"
-	"github.com/luobote55/java2go/internal/match"
-	"github.com/luobote55/java2go/internal/strs"
-	"github.com/pkg/errors"
 	"io"
 	"path/filepath"
 	"strings"
-)
-
-var sortNum int
-
-type Message struct {
-	sortNum    int
-	num        int
-	ApiModel   []string
-	StructName string
-	buf        bytes.Buffer
-	WithPage   bool
-	Child      []string
-}
-
-func NewMessage() *Message {
-	sortNum++
-	return &Message{
-		sortNum:    sortNum,
-		ApiModel:   nil,
-		StructName: "",
-		buf:        bytes.Buffer{},
-		WithPage:   false,
-		Child:      []string{},
-	}
-}
-
-func GenMessage(msg *Message, reply string) *Message {
-	sortNum++
-	return &Message{
-		sortNum:    sortNum,
-		ApiModel:   nil,
-		StructName: reply,
-		buf:        bytes.Buffer{},
-		WithPage:   false,
-		Child:      []string{},
-	}
-}
-
-func (i *Message) GenSort() *Message {
-	i.sortNum = sortNum
-	sortNum++
-	return i
-}
-
-// P prints a line to the generated output. It converts each parameter to a
-// string following the same rules as fmt.Print. It never inserts spaces
-// between parameters.
-func (g *Message) P(v ...interface{}) {
-	for _, x := range v {
-		switch x := x.(type) {
-		default:
-			fmt.Fprint(&g.buf, x)
-		}
-	}
-	fmt.Fprintln(&g.buf)
-}
-
-func (i *Message) SetChild(msg string) *Message {
-	i.Child = append(i.Child, msg)
-	return i
-}
 
-type MessageField struct {
-	Name     string
-	Comment  string
-	Typ      string
-	Repeated string
-}
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/internal/match"
+	"github.com/luobote55/java2go/ir"
+)
 
-// A GeneratorMessage represents the state of a single Go source file
-// being scanned for generator commands.
+// A GeneratorMessage represents the state of a single VO or request class
+// being scanned for message fields.
 type GeneratorMessage struct {
 	r        io.Reader
 	path     string // full rooted path name.
 	dir      string // full rooted directory of file.
 	file     string // base name of file.
 	target   string
-	goo      string
 	pkg      string
 	commands map[string][]string
 	lineNum  int // current line number.
 	env      []string
+	cfg      *config.Config
 }
 
-// run runs the generators in the current file.
-func (g *GeneratorMessage) run(msgs map[string]*Message) (ok bool) {
+// run parses the class into an entity. Its field types are left as written
+// in the file, to be resolved against the scope of the class once every
+// class is known. It returns nil if the file does not declare a class or
+// declares a controller.
+func (g *GeneratorMessage) run() (cl *class) {
 	// Processing below here calls g.errorf on failure, which does panic(stop).
 	// If we encounter an error, we abort the package.
 	defer func() {
 		e := recover()
 		if e != nil {
-			ok = false
+			cl = nil
 			if e != stop {
 				panic(e)
 			}
@@ -110,136 +46,85 @@ func (g *GeneratorMessage) run(msgs map[string]*Message) (ok bool) {
 	g.dir, g.file = filepath.Split(g.path)
 	g.dir = filepath.Clean(g.dir) // No final separator please.
 
-	// Scan for lines that start "//go:generate".
-	// Can't use bufio.Scanner because it can't handle long lines,
-	// which are likely to appear when using generate.
+	// Can't use bufio.Scanner because it can't handle long lines.
 	input := bufio.NewReader(g.r)
 	var err error
 	// One line per loop.
-	msg := NewMessage()
+	msg := &ir.Entity{Source: g.path}
+	cl = &class{msg: msg, scope: java.NewScope()}
 
-	var field *MessageField = nil
+	var field *ir.Field = nil
+	annotations := make([]string, 0) // annotations of the next declaration.
 	for {
 		g.lineNum++ // 1-indexed.
 		var buf []byte
 		buf, err = input.ReadSlice('\n')
 		if err != nil {
-			// Check for marker at EOF without final \n.
-			if err == io.EOF {
-				err = io.ErrUnexpectedEOF
-			}
 			break
 		}
+		if line := strings.TrimSpace(string(buf)); strings.HasPrefix(line, "@") {
+			annotations = append(annotations, line)
+		}
+		if msg.Name == "" && cl.scope.Line(string(buf)) {
+			continue
+		}
 
 		if strings.HasPrefix(string(buf), "@ApiModel") {
-			msg.ApiModel = match.FindDoubleQuotes(string(buf))
+			msg.Comment = unquotes(match.FindDoubleQuotes(string(buf)))
 		} else if strings.HasPrefix(string(buf), "public class ") {
-			msg.StructName = match.FindFix(string(buf), `public class (.*?) `)
-			rpcStructer(msg)
-			//msg.StructName = "asd"
+			msg.Name = match.FindFix(string(buf), `public class (.*?) `)
+			cl.line = g.lineNum
+			msg.Annotations, annotations = annotations, make([]string, 0)
 		} else if strings.Contains(string(buf), "@ApiModelProperty") {
-			field = new(MessageField)
-			field.Comment = strings.Replace(match.FindDoubleQuote(string(buf)), "\"", "", -1)
+			field = new(ir.Field)
+			field.Comment = unquote(match.FindDoubleQuote(string(buf)))
 		} else if strings.Contains(string(buf), "private ") {
 			if field == nil {
+				annotations = make([]string, 0)
 				continue
 			}
-			index := strings.LastIndex(string(buf), " ")
-			field.Name = match.FindFix(string(buf[index:]), ` (.*?);`)
-			if strings.Contains(string(buf), "List<") {
-				field.Repeated = "repeated "
-				value := match.FindFix(string(buf), `List<(.*?)>`)
-				field.Typ, err = JaveType(value)
-				if err != nil {
-					field.Typ = value
-					msg.SetChild(value)
-				}
-			} else {
-				field.Typ, err = JaveType(string(buf))
-				if err != nil {
-					field.Typ = strings.Split(match.FindFix(string(buf), `private (.*?);`), " ")[0]
-					msg.SetChild(field.Typ)
-				}
+			field.Annotations, annotations = annotations, make([]string, 0)
+			decl := strings.TrimSpace(match.FindFix(string(buf), `private (.*?);`))
+			index := strings.LastIndex(decl, " ")
+			if index < 0 {
+				field = nil
+				continue
 			}
-			rpcField(msg, field)
+			field.Name = decl[index+1:]
+			field.Type, field.Repeated = javaFieldType(g.cfg, decl[:index])
+			msg.Fields = append(msg.Fields, field)
 			field = nil
 		}
 	}
-	//if err != nil && err != io.EOF {
-	//	g.errorf("error reading %s: %s", ShortPath(g.path), err)
-	//}
-	msg.P("}")
-	msgs[msg.StructName] = msg
-
-	return true
+	if msg.Name == "" || isController(msg.Annotations) {
+		return nil
+	}
+	cl.name = cl.scope.Qualify(msg.Name)
+	msg.Class = cl.name
+	return cl
 }
 
-func JaveType(value string) (string, error) {
-	if strings.Contains(value, "int") {
-		return "int32", nil
-	} else if strings.Contains(value, "Integer") {
-		return "int32", nil
-	} else if strings.Contains(value, "String") {
-		return "string", nil
-	} else if strings.Contains(value, "string") {
-		return "string", nil
-	} else if strings.Contains(value, "JSONObject") {
-		return "string", nil
-	} else if strings.Contains(value, "JSONArray") {
-		return "string", nil
-	} else if strings.Contains(value, "MultipartFile") {
-		return "string", nil
-	} else if strings.Contains(value, "HttpServletRequest") {
-		return "string", nil
-	} else if strings.Contains(value, "?") {
-		return "string", nil
-	} else if strings.Contains(value, "Long") {
-		return "int64", nil
-	} else if strings.Contains(value, "int64") {
-		return "int64", nil
-	} else if strings.Contains(value, "Float") {
-		return "float", nil
-	} else if strings.Contains(value, "Double") {
-		return "double", nil
-	} else if strings.Contains(value, "Boolean") {
-		return "bool", nil
-	} else if strings.Contains(value, "boolean") {
-		return "bool", nil
-	} else if strings.Contains(value, "Date") {
-		return "google.protobuf.Timestamp", nil
+// javaFieldType returns the IR type of a declared Java type. Lists become
+// repeated fields of their element type; unknown types reference classes.
+func javaFieldType(cfg *config.Config, decl string) (typ ir.Type, repeated bool) {
+	decl = strings.TrimSpace(decl)
+	if value := match.FindFix(decl, `List<(.*?)>`); value != "" {
+		decl, repeated = value, true
 	}
-	return "", errors.New("没有这个类型：" + value)
+	typ, err := cfg.JavaType(decl)
+	if err != nil {
+		typ = ir.Ref(decl)
+	}
+	return typ, repeated
 }
 
-func rpcStructer(msg *Message) {
-	if len(msg.ApiModel) > 0 {
-		comment := msg.ApiModel[0]
-		for index := 1; index < len(msg.ApiModel); index++ {
-			comment += ", " + msg.ApiModel[index]
-		}
-		msg.P("// " + comment)
-	}
-	msg.P("message " + match.Remove(msg.StructName, "VO") + " {")
+func unquote(s string) string {
+	return strings.Trim(s, "\"")
 }
 
-func rpcField(msg *Message, field *MessageField) {
-	if field.Name == "" {
-		return
-	}
-	commontIndex := 50
-	msg.num++
-	buf := fmt.Sprintf("  %s%s %s = %d;",
-		field.Repeated,
-		match.Remove(field.Typ, "VO"),
-		strs.LetterCamelCase(match.Remove(field.Name, "VO")),
-		msg.num)
-	printBufLen := len(buf)
-	if printBufLen < commontIndex {
-		printBufLen = commontIndex
+func unquotes(ss []string) string {
+	for i := range ss {
+		ss[i] = unquote(ss[i])
 	}
-	// 定义初始值
-	initialValue := byte(' ')
-	printBuf := bytes.Repeat([]byte{initialValue}, printBufLen)
-	copy(printBuf, buf)
-	msg.P(string(printBuf) + "// " + match.Remove(field.Comment, "VO"))
+	return strings.Join(ss, ", ")
 }
@@ -0,0 +1,91 @@
+package ctl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+)
+
+// A class is a parsed VO or request class.
+type class struct {
+	name  string // qualified name.
+	line  int    // line of the class declaration.
+	msg   *ir.Entity
+	scope *java.Scope
+}
+
+// classes indexes the VO and request classes by qualified and simple name.
+type classes struct {
+	list     []*class
+	byName   map[string]*class
+	bySimple map[string][]*class
+}
+
+func newClasses() *classes {
+	return &classes{
+		byName:   make(map[string]*class),
+		bySimple: make(map[string][]*class),
+	}
+}
+
+func (c *classes) add(cl *class) {
+	if _, ok := c.byName[cl.name]; ok {
+		return
+	}
+	c.list = append(c.list, cl)
+	c.byName[cl.name] = cl
+	simple := java.SimpleName(cl.name)
+	c.bySimple[simple] = append(c.bySimple[simple], cl)
+}
+
+// resolve binds a class name used in a file to a parsed class. Qualified
+// names, single-type imports, the file's own package and wildcard imports
+// are tried in this order; failing those, a simple name that only one
+// class has is accepted, which keeps files without package declarations
+// working. Otherwise resolve returns nil and the ambiguous candidates, if
+// any.
+func (c *classes) resolve(s *java.Scope, name string) (*class, []string) {
+	for _, q := range s.Candidates(name) {
+		if cl, ok := c.byName[q]; ok {
+			return cl, nil
+		}
+	}
+	candidates := c.bySimple[java.SimpleName(name)]
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	names := make([]string, 0, len(candidates))
+	for _, cl := range candidates {
+		names = append(names, cl.name)
+	}
+	sort.Strings(names)
+	return nil, names
+}
+
+// message returns the message name of a class name used in a file. Names
+// that do not resolve are returned as written, ambiguous ones are reported
+// through warnf.
+func (c *classes) message(s *java.Scope, name string, warnf func(format string, args ...interface{})) string {
+	cl, candidates := c.resolve(s, name)
+	if cl != nil {
+		return cl.msg.Name
+	}
+	if len(candidates) > 1 {
+		warnf("类型不明确：%s 可能是 %s", name, strings.Join(candidates, "、"))
+	}
+	return name
+}
+
+// packageName returns a class name prefixed with the last segment of its
+// package, e.g. "AdminDeviceVO" for "com.example.admin.DeviceVO". It names
+// messages whose simple names clash.
+func packageName(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return name
+	}
+	return strs.GoCamelCase(java.SimpleName(name[:i])) + name[i+1:]
+}
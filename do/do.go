@@ -3,46 +3,124 @@ package do
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/emit"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
 	"github.com/spf13/cobra"
 )
 
 // CmdDo represents the source command.
 var CmdDo = &cobra.Command{
-	Use:   "do",
+	Use:   "do [java_dir] [go_dir]",
 	Short: "Generate the ent schema code from xxxDO.java",
 	Long:  "Generate the ent schema code from xxxDO.java. Example: ./j2g.exe do ./test/do ./test/do ",
+	Args:  cobra.MaximumNArgs(2),
 	Run:   run,
 }
 
-var javaPath string
+var (
+	javaPaths []string
+	goPath    string
+	repoPath  string
+)
 
 func init() {
-	CmdDo.Flags().StringVarP(&javaPath, "java_path", "p", "./", "java source directory")
+	CmdDo.Flags().StringSliceVarP(&javaPaths, "java_path", "p", []string{"./"}, "java source directories or sources jars")
+	CmdDo.Flags().StringVarP(&goPath, "output", "o", "./", "ent schema directory")
+	CmdDo.Flags().StringVar(&repoPath, "repo_path", "", "Kratos data-layer repository directory, no repositories if empty")
 }
 
-func run(_ *cobra.Command, args []string) {
-	if len(args) == 0 {
-		fmt.Println("Please enter the java file or directory")
+// run resolves the paths from, in order of precedence, the arguments, the
+// flags given on the command line, java2go.yaml and the flag defaults.
+func run(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load(config.File)
+	if err != nil {
+		fmt.Println(err)
 		return
 	}
-	var (
-		err  error
-		java = strings.TrimSpace(args[0])
-		goo  = strings.TrimSpace(args[1])
-	)
-	if strings.HasSuffix(java, ".java") {
-		err = generate(java, goo, args)
-	} else {
-		err = walk(java, goo, args)
+	java := cfg.Do.Sources
+	if cmd.Flags().Changed("java_path") || len(java) == 0 {
+		java = javaPaths
+	}
+	goo := cfg.Do.Output
+	if cmd.Flags().Changed("output") || goo == "" {
+		goo = goPath
+	}
+	repo := cfg.Do.Repo
+	if cmd.Flags().Changed("repo_path") {
+		repo = repoPath
 	}
+	if len(args) > 0 {
+		java = []string{strings.TrimSpace(args[0])}
+	}
+	if len(args) > 1 {
+		goo = strings.TrimSpace(args[1])
+	}
+	srcs, err := gen.ReadSourceRoots(java, ".java", nil)
 	if err != nil {
 		fmt.Println(err)
+		return
 	}
+	out := new(gen.Output)
+	m, err := Parse(srcs, cfg, out)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	emit.Ent(m, cfg, out)
+	gen.PrintDiagnostics(out.Diagnostics)
+	if err = gen.WriteFiles(goo, out.Files, cfg.Overwrite); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if repo == "" {
+		return
+	}
+	repos := new(gen.Output)
+	emit.Data(m, cfg, repos)
+	gen.PrintDiagnostics(repos.Diagnostics)
+	if err = os.MkdirAll(repo, 0755); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err = gen.WriteFiles(repo, repos.Files, cfg.Overwrite); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Generate converts the java sources into ent schema files.
+func Generate(srcs []gen.Source, cfg *config.Config) (*gen.Output, error) {
+	out := new(gen.Output)
+	m, err := Parse(srcs, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	emit.Ent(m, cfg, out)
+	return out, nil
+}
+
+// Parse parses the DO classes into the intermediate model. Problems found
+// in the sources are recorded in out.
+func Parse(srcs []gen.Source, cfg *config.Config, out *gen.Output) (*ir.Model, error) {
+	m := new(ir.Model)
+	names := naming.NewNamer()
+	for _, src := range srcs {
+		e, err := generate(src, cfg, names, out)
+		if err != nil {
+			return nil, err
+		}
+		if e != nil {
+			m.Entities = append(m.Entities, e)
+		}
+	}
+	return m, nil
 }
 
 func look(name ...string) error {
@@ -54,37 +132,27 @@ func look(name ...string) error {
 	return nil
 }
 
-func walk(dir string, goo string, args []string) error {
-	if dir == "" {
-		dir = "."
-	}
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if ext := filepath.Ext(path); ext != ".java" {
-			return nil
-		}
-		return generate(path, goo, args)
-	})
-}
-
-// generate is used to execute the generate command for the specified proto file
-func generate(java string, goo string, args []string) error {
-	protoBytes, err := os.ReadFile(java)
-	if err != nil && len(protoBytes) < 0 {
+// generate parses the specified java file into an entity.
+func generate(src gen.Source, cfg *config.Config, names *naming.Namer, out *gen.Output) (*ir.Entity, error) {
+	protoBytes, err := io.ReadAll(src.R)
+	if err != nil {
+		return nil, err
 	}
 	g := &Generator{
 		r:        bytes.NewReader(protoBytes),
-		path:     java,
+		path:     src.Path,
 		dir:      "",
 		file:     "",
 		target:   "",
-		goo:      goo,
+		out:      out,
 		pkg:      "",
 		commands: nil,
 		lineNum:  0,
 		env:      nil,
+		cfg:      cfg,
+		names:    names,
 	}
-	g.run()
-	return nil
+	return g.run(), nil
 }
 
 func pathExists(path string) bool {
@@ -2,49 +2,44 @@ package do
 
 import (
 	"bufio"
-	"fmt"
-	"github.com/luobote55/java2go/gen"
-	"github.com/luobote55/java2go/internal/match"
-	"github.com/luobote55/java2go/internal/strs"
 	"io"
-	"os"
 	"path/filepath"
 	"strings"
-)
 
-const (
-	version = "1.0.0"
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/match"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
 )
 
-// A Generator represents the state of a single Go source file
-// being scanned for generator commands.
+// A Generator represents the state of a single DO class
+// being scanned for table fields.
 type Generator struct {
 	r        io.Reader
 	path     string // full rooted path name.
 	dir      string // full rooted directory of file.
 	file     string // base name of file.
 	target   string
-	goo      string
+	out      *gen.Output
 	pkg      string
 	commands map[string][]string
 	lineNum  int // current line number.
 	env      []string
+	cfg      *config.Config
+	names    *naming.Namer
 }
 
-type EntField struct {
-	Name    string
-	Comment string
-	Typ     string
-}
-
-// run runs the generators in the current file.
-func (g *Generator) run() (ok bool) {
+// run parses the DO class into an entity. It returns nil if the file does
+// not declare a class.
+func (g *Generator) run() (entity *ir.Entity) {
 	// Processing below here calls g.errorf on failure, which does panic(stop).
 	// If we encounter an error, we abort the package.
 	defer func() {
 		e := recover()
 		if e != nil {
-			ok = false
+			entity = nil
 			if e != stop {
 				panic(e)
 			}
@@ -52,205 +47,123 @@ func (g *Generator) run() (ok bool) {
 	}()
 	g.dir, g.file = filepath.Split(g.path)
 	g.dir = filepath.Clean(g.dir) // No final separator please.
-	g.target = strs.JSONSnakeCase(strings.Replace(strings.Replace(g.file, "DO", "", -1), "java", "go", -1))
-	if g.goo == "" {
-		g.goo = "./"
-	}
-
-	// 检查路径是否存在
-	if _, err := os.Stat(g.goo); os.IsNotExist(err) {
-		fmt.Println("目标目录并不存在：" + g.goo)
-		return false
-	}
 
-	// 组合路径和文件名
-	filepath := filepath.Join(g.goo, g.target)
-	// 检查文件是否存在
-	if _, err := os.Stat(filepath); err == nil || os.IsExist(err) {
-		fmt.Println("文件已经存在：" + filepath + "， 如要更新先删除")
-		return false
-	}
-	// Scan for lines that start "//go:generate".
-	// Can't use bufio.Scanner because it can't handle long lines,
-	// which are likely to appear when using generate.
+	// Can't use bufio.Scanner because it can't handle long lines.
 	input := bufio.NewReader(g.r)
 	var err error
 	// One line per loop.
-	file := gen.NewGeneratedFile()
-	g.header(file)
+	entity = &ir.Entity{Source: g.path}
+	class := false
 
-	var field *EntField = nil
+	var field *ir.Field = nil
+	logic := false                   // @TableLogic, the soft delete flag.
+	annotations := make([]string, 0) // annotations of the next declaration.
 	for {
 		g.lineNum++ // 1-indexed.
 		var buf []byte
 		buf, err = input.ReadSlice('\n')
 		if err != nil {
-			// Check for marker at EOF without final \n.
-			if err == io.EOF {
-				err = io.ErrUnexpectedEOF
-			}
 			break
 		}
+		if line := strings.TrimSpace(string(buf)); strings.HasPrefix(line, "@") {
+			annotations = append(annotations, line)
+		}
 
 		if strings.HasPrefix(string(buf), "@ApiModel") {
-			file.ApiModel = match.FindDoubleQuotes(string(buf))
+			entity.Comment = unquotes(match.FindDoubleQuotes(string(buf)))
 		} else if strings.HasPrefix(string(buf), "@TableName") {
-			file.StructName = strs.GoCamelCase(strings.Replace(match.FindDoubleQuote(string(buf)), "\"", "", -1))
+			entity.Table = unquote(match.FindDoubleQuote(string(buf)))
 		} else if strings.HasPrefix(string(buf), "public class ") {
-			g.structer(file)
-			//file.StructName = "asd"
-		} else if strings.Contains(string(buf), "@TableId") {
-			file.P("\t\tfield.Int64(\"id\").Comment(\"id\"),")
-			field = nil
+			class = true
+			entity.Annotations, annotations = annotations, make([]string, 0)
+			if entity.Table == "" {
+				class := match.FindFix(string(buf), `public class (.*?) `)
+				entity.Table = strs.SnakeCase(g.cfg.Naming.Entities.Apply(class))
+			}
+			name, err := g.names.Assign(entity.Table, strs.GoCamelCase(g.cfg.Naming.Tables.Apply(entity.Table)), strs.GoCamelCase(entity.Table))
+			if err != nil {
+				g.warnf("%v", err)
+			}
+			entity.Name = name
 		} else if strings.Contains(string(buf), "@ApiModelProperty") {
-			field = new(EntField)
-			field.Comment = match.FindDoubleQuote(string(buf))
-		} else if strings.Contains(string(buf), "@TableField") {
+			field = new(ir.Field)
+			field.Comment = unquote(match.FindDoubleQuote(string(buf)))
+		} else if strings.Contains(string(buf), "@TableId") {
 			if field == nil {
-				continue
+				field = new(ir.Field)
 			}
-			field.Name = match.FindDoubleQuote(string(buf))
-		} else if strings.Contains(string(buf), "private ") {
+			field.ID = true
+			if ids := match.FindDoubleQuotes(string(buf)); len(ids) > 0 {
+				field.Column = unquote(ids[0])
+			}
+		} else if strings.Contains(string(buf), "@TableLogic") {
+			logic, entity.SoftDelete = true, true
+		} else if strings.Contains(string(buf), "@TableField") {
 			if field == nil {
 				continue
 			}
-			if strings.Contains(string(buf), "Integer") {
-				field.Typ = "Int32"
-				g.field(file, field)
-				field = nil
-			} else if strings.Contains(string(buf), "String") {
-				field.Typ = "String"
-				g.field(file, field)
-				field = nil
-			} else if strings.Contains(string(buf), "Long") {
-				field.Typ = "Int64"
-				g.field(file, field)
-				field = nil
-			} else if strings.Contains(string(buf), "Float") {
-				field.Typ = "Float32"
-				g.field(file, field)
-				field = nil
-			} else if strings.Contains(string(buf), "Double") {
-				field.Typ = "Float"
-				g.field(file, field)
-				field = nil
-			} else if strings.Contains(string(buf), "private Date") {
-				if strings.Contains(string(buf), "createTime;") {
-					field = nil
-					continue
-				} else if strings.Contains(string(buf), "updateTime;") {
-					field = nil
-					continue
-				} else {
-					field.Typ = "Time"
-					g.field(file, field)
-					field = nil
-				}
-			} else {
-				fmt.Println("暂不支持的类型：" + string(buf))
-				field = nil
-				continue
+			field.Column = unquote(match.FindDoubleQuote(string(buf)))
+		} else if strings.Contains(string(buf), "private ") {
+			if field != nil && !logic {
+				field.Annotations = annotations
+				g.field(entity, field, string(buf))
 			}
+			field, logic, annotations = nil, false, make([]string, 0)
 		}
 	}
-	//if err != nil && err != io.EOF {
-	//	g.errorf("error reading %s: %s", ShortPath(g.path), err)
-	//}
-
-	field = new(EntField)
-	field.Comment = "创建时间"
-	field.Name = "\"created_at\""
-	field.Typ = "Time"
-	g.field(file, field)
-
-	field.Comment = "修改时间"
-	field.Name = "\"updated_at\""
-	field.Typ = "Time"
-	g.field(file, field)
-
-	field.Comment = "删除"
-	field.Name = "\"deleted_at\""
-	field.Typ = "Time"
-	g.field(file, field)
-	file.P("\t}")
-	file.P("}")
-	file.P("")
+	if !class {
+		return nil
+	}
+	return entity
+}
 
-	err = file.WriteFile(filepath)
+// field completes field from its declaration and adds it to the entity.
+func (g *Generator) field(entity *ir.Entity, field *ir.Field, line string) {
+	decl := strings.TrimSpace(match.FindFix(line, `private (.*?);`))
+	index := strings.LastIndex(decl, " ")
+	if index < 0 {
+		return
+	}
+	field.Name = decl[index+1:]
+	if field.Column == "" {
+		field.Column = strs.SnakeCase(field.Name)
+	}
+	// audit columns are maintained by the ent schema itself.
+	if g.cfg.IsAudit(field.Column) {
+		return
+	}
+	typ, err := g.cfg.JavaType(decl[:index])
 	if err != nil {
-		fmt.Println(err)
+		g.warnf("暂不支持的类型：%s", strings.TrimSpace(line))
+		return
+	}
+	field.Type = typ
+	if !field.ID && typ.Kind != ir.Time {
+		value := "0"
+		if typ.Kind == ir.String {
+			value = ""
+		} else if typ.Kind == ir.Bool {
+			value = "false"
+		}
+		field.Default = &value
+	}
+	if typ.Kind == ir.Time {
+		field.Nillable = true
 	}
-	return true
+	entity.Fields = append(entity.Fields, field)
 }
 
-func (g *Generator) header(file *gen.GeneratedFile) {
-	file.P("// Code generated by j2g. DO NOT EDIT.")
-	file.P("// versions:")
-	file.P("// - j2g v", version)
-	file.P("package schema                       ")
-	file.P("                                     ")
-	file.P("import (")
-	file.P("\t\"time\"")
-	file.P("")
-	file.P("\t\"entgo.io/ent\"")
-	file.P("\t\"entgo.io/ent/dialect\"")
-	file.P("\t\"entgo.io/ent/schema/field\"")
-	file.P(")")
-	file.P("")
+func (g *Generator) warnf(format string, args ...interface{}) {
+	g.out.Warnf(g.path, g.lineNum, format, args...)
 }
 
-func (g *Generator) structer(file *gen.GeneratedFile) {
-	if len(file.ApiModel) > 0 {
-		comment := file.ApiModel[0]
-		for index := 1; index < len(file.ApiModel); index++ {
-			comment += ", " + file.ApiModel[index]
-		}
-		file.P("// " + comment)
-	}
-	file.P("// " + file.StructName + " holds the schema definition for the " + file.StructName + " entity.")
-	file.P("type " + file.StructName + " struct {")
-	file.P("\tent.Schema")
-	file.P("}")
-	file.P("")
-	file.P("// Fields of the " + file.StructName + ".")
-	file.P("func (" + file.StructName + ") Fields() []ent.Field {")
-	file.P("\treturn []ent.Field{")
+func unquote(s string) string {
+	return strings.Trim(s, "\"")
 }
 
-func (g *Generator) field(file *gen.GeneratedFile, field *EntField) {
-	if field.Name == "" {
-		return
-	}
-	if field.Typ == "Int32" {
-		file.P("\t\tfield." + field.Typ + "(" + field.Name + ").Default(0).Comment(" + field.Comment + "),")
-	} else if field.Typ == "Int64" {
-		file.P("\t\tfield." + field.Typ + "(" + field.Name + ").Default(0).Comment(" + field.Comment + "),")
-	} else if field.Typ == "Float32" { // 32bit
-		file.P("\t\tfield." + field.Typ + "(" + field.Name + ").Default(0).Comment(" + field.Comment + "),")
-	} else if field.Typ == "Float" { // 64bit
-		file.P("\t\tfield." + field.Typ + "(" + field.Name + ").Default(0).Comment(" + field.Comment + "),")
-	} else if field.Typ == "String" {
-		file.P("\t\tfield." + field.Typ + "(" + field.Name + ").Default(\"\").Comment(" + field.Comment + "),")
-	} else if field.Typ == "Time" {
-		if field.Name == "created_at" {
-			file.P("\t\tfield." + field.Typ + "(" + field.Name + ").")
-			file.P("\t\t\tDefault(time.Now).SchemaType(map[string]string{")
-			file.P("\t\t\tdialect.MySQL:  \"datetime\",")
-			file.P("\t\t\tdialect.SQLite: \"datetime\",")
-			file.P("\t\t}),")
-		} else if field.Name == "updated_at" {
-			file.P("\t\tfield." + field.Typ + "(" + field.Name + ").")
-			file.P("\t\t\tDefault(time.Now).SchemaType(map[string]string{")
-			file.P("\t\t\tdialect.MySQL:  \"datetime\",")
-			file.P("\t\t\tdialect.SQLite: \"datetime\",")
-			file.P("\t\t}),")
-		} else {
-			file.P("\t\tfield." + field.Typ + "(" + field.Name + ").")
-			file.P("\t\t\tOptional().Nillable().")
-			file.P("\t\t\tSchemaType(map[string]string{")
-			file.P("\t\t\t\tdialect.MySQL:  \"datetime\",")
-			file.P("\t\t\t\tdialect.SQLite: \"datetime\",")
-			file.P("\t\t\t}),")
-		}
+func unquotes(ss []string) string {
+	for i := range ss {
+		ss[i] = unquote(ss[i])
 	}
+	return strings.Join(ss, ", ")
 }
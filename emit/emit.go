@@ -0,0 +1,242 @@
+package emit
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/gen/biz"
+	"github.com/luobote55/java2go/gen/conv"
+	"github.com/luobote55/java2go/gen/data"
+	"github.com/luobote55/java2go/gen/ent"
+	"github.com/luobote55/java2go/gen/openapi"
+	"github.com/luobote55/java2go/gen/proto"
+	"github.com/luobote55/java2go/gen/query"
+	"github.com/luobote55/java2go/gen/service"
+	"github.com/luobote55/java2go/ir"
+	"github.com/spf13/cobra"
+)
+
+// CmdEmit represents the emit command.
+var CmdEmit = &cobra.Command{
+	Use:   "emit",
+	Short: "Generate protobuf and ent schema code from a model dumped by inspect",
+	Long:  "Generate protobuf and ent schema code from a model dumped by inspect. Example: ./j2g.exe emit ./model.json ./api",
+	Run:   run,
+}
+
+var (
+	target string
+)
+
+func init() {
+	CmdEmit.Flags().StringVarP(&target, "target", "t", "all", "what to generate: proto, openapi, ent, service, biz, data, query, convert or all")
+}
+
+func run(_ *cobra.Command, args []string) {
+	if len(args) < 2 {
+		fmt.Println("Please enter the model file and the output directory")
+		return
+	}
+	var (
+		model = strings.TrimSpace(args[0])
+		goo   = strings.TrimSpace(args[1])
+	)
+	cfg, err := config.Load(config.File)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	f, err := os.Open(model)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer f.Close()
+	m, err := ir.Load(f)
+	if err != nil {
+		fmt.Println(model + ": " + err.Error())
+		return
+	}
+	out := new(gen.Output)
+	switch target {
+	case "proto":
+		Proto(m, cfg, out)
+	case "openapi":
+		OpenAPI(m, cfg, openapi.FileName, out)
+	case "ent":
+		Ent(m, cfg, out)
+	case "service":
+		Service(m, cfg, out)
+	case "biz":
+		Biz(m, cfg, out)
+	case "data":
+		Data(m, cfg, out)
+	case "query":
+		Queries(m, cfg, out)
+	case "convert":
+		Converters(m, cfg, out)
+	case "all":
+		Generate(m, cfg, out)
+	default:
+		fmt.Println("未知的target：" + target)
+		return
+	}
+	if err = gen.WriteFiles(goo, out.Files, cfg.Overwrite); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Generate adds the protobuf files of the services, the ent schemas of
+// the tables and the biz files of the usecases in m to out.
+func Generate(m *ir.Model, cfg *config.Config, out *gen.Output) {
+	Proto(m, cfg, out)
+	Ent(m, cfg, out)
+	Biz(m, cfg, out)
+}
+
+// Proto adds a protobuf file for every service in m to out.
+func Proto(m *ir.Model, cfg *config.Config, out *gen.Output) {
+	for _, svc := range m.Services {
+		out.Add(proto.Generate(svc, cfg))
+	}
+}
+
+// OpenAPI adds the OpenAPI document of the services in m, named name, to
+// out. The document is JSON if name ends in .json and YAML otherwise.
+func OpenAPI(m *ir.Model, cfg *config.Config, name string, out *gen.Output) {
+	file, err := openapi.Generate(m, cfg, name)
+	if err != nil {
+		out.Warnf(name, 0, "%v", err)
+		return
+	}
+	out.Add(file)
+}
+
+// Service adds a Kratos service stub for every service in m to out.
+func Service(m *ir.Model, cfg *config.Config, out *gen.Output) {
+	if len(m.Services) > 0 {
+		checkModule(cfg, proto.ImportPath(m.Services[0], cfg), out)
+	}
+	for _, svc := range m.Services {
+		out.Add(service.Generate(svc, cfg))
+	}
+}
+
+// Biz adds a biz file for every usecase in m to out, and a file declaring
+// the entities they use.
+func Biz(m *ir.Model, cfg *config.Config, out *gen.Output) {
+	for _, uc := range m.Usecases {
+		out.Add(biz.Generate(uc, cfg))
+	}
+	if entities := biz.Uses(m); len(entities) > 0 {
+		out.Add(biz.Types(entities))
+	}
+}
+
+// Data adds a data-layer repository for every entity of m backed by a
+// table to out.
+func Data(m *ir.Model, cfg *config.Config, out *gen.Output) {
+	checked := false
+	for _, e := range m.Entities {
+		if e.Table == "" {
+			continue
+		}
+		if !checked {
+			checkModule(cfg, cfg.EntPackage(), out)
+			checked = true
+		}
+		out.Add(data.Generate(e, cfg))
+	}
+}
+
+// Queries adds the query functions of every mapper in m to out, and a
+// file with the helpers and result structs they share.
+func Queries(m *ir.Model, cfg *config.Config, out *gen.Output) {
+	for _, mp := range m.Mappers {
+		out.Add(query.Generate(mp, m, cfg))
+	}
+	if len(m.Mappers) > 0 {
+		checkModule(cfg, cfg.EntPackage(), out)
+		out.Add(query.Helpers(m.Mappers))
+	}
+}
+
+// Converters adds the functions converting between the messages of m and
+// the entities backed by tables they stand for, and the implementations of
+// the MapStruct converters of m, to out, and warns about the fields
+// without counterpart.
+func Converters(m *ir.Model, cfg *config.Config, out *gen.Output) {
+	pairs := conv.Match(m, cfg)
+	if len(pairs) > 0 || len(m.Converters) > 0 {
+		checkModule(cfg, cfg.EntPackage(), out)
+	}
+	for _, p := range pairs {
+		if len(p.Unmatched) > 0 {
+			out.Warnf(p.Message.Source, 0, "%s 的字段在 %s 中没有对应：%s", p.Message.Name, p.Entity.Name, strings.Join(p.Unmatched, "、"))
+		}
+		if len(p.Missing) > 0 {
+			out.Warnf(p.Message.Source, 0, "%s 的字段在 %s 中没有对应：%s", p.Entity.Name, p.Message.Name, strings.Join(p.Missing, "、"))
+		}
+	}
+	if file := conv.Generate(pairs, cfg); file != nil {
+		out.Add(file)
+	}
+	for _, c := range m.Converters {
+		file, skips := conv.Converter(c, m, cfg)
+		for _, s := range skips {
+			at := s.Method.Java
+			if s.Target != "" {
+				at += "." + s.Target
+			}
+			if s.Expression != "" {
+				at += " = " + s.Expression
+			}
+			out.Warnf(c.Source, s.Method.Line, skipped[s.Reason], at)
+		}
+		if file != nil {
+			out.Add(file)
+		}
+	}
+}
+
+// checkModule warns, once per output, that the Go files being generated
+// import paths such as example outside of any Go module if go.module is
+// not set: the {module} of the import path templates is then dropped.
+func checkModule(cfg *config.Config, example string, out *gen.Output) {
+	if cfg.Go.Module != "" {
+		return
+	}
+	for _, d := range out.Diagnostics {
+		if strings.HasPrefix(d.Message, noModule) {
+			return
+		}
+	}
+	out.Warnf("", 0, noModule+"，生成的 Go 代码 import 的 %s 不是合法的 module 路径，请在 java2go.yaml 中设置 go.module", example)
+}
+
+const noModule = "没有设置 go.module"
+
+// skipped are the warnings about the targets the MapStruct converters
+// leave unset, by reason.
+var skipped = map[string]string{
+	conv.Unmapped:      "目标字段没有来源：%s",
+	conv.Unconvertible: "字段类型无法转换，已忽略：%s",
+	conv.Unresolved:    "找不到字段，已忽略：%s",
+	conv.Unsupported:   "暂不支持的映射，已忽略：%s",
+	conv.Expression:    "暂不支持翻译 Java 表达式，需要手动转换：%s",
+	conv.NoMessage:     "转换的类没有对应的 proto message，已忽略：%s",
+}
+
+// Ent adds an ent schema for every entity of m backed by a table to out.
+// VO and request classes have no table and are skipped.
+func Ent(m *ir.Model, cfg *config.Config, out *gen.Output) {
+	for _, e := range m.Entities {
+		if e.Table == "" {
+			continue
+		}
+		out.Add(ent.Generate(e, cfg))
+	}
+}
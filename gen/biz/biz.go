@@ -0,0 +1,252 @@
+// Package biz generates the Kratos biz layer from the intermediate model:
+// a repo interface and a usecase struct per usecase, and Go structs for the
+// entities their methods use.
+package biz
+
+import (
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+)
+
+const (
+	version = "1.0.0"
+)
+
+// Package is the Go package of the biz layer in a Kratos layout.
+const Package = "biz"
+
+// TypesFile is the name of the file holding the entities used by the
+// usecases.
+const TypesFile = "types.go"
+
+// Generate returns the biz file of a usecase. The repo interface declares
+// the methods of the Java service; the usecase delegates every method to
+// the repo, with a TODO naming the Java implementation to port.
+func Generate(uc *ir.Usecase, cfg *config.Config) *gen.GeneratedFile {
+	file := gen.NewGeneratedFile()
+	file.Name = FileName(uc)
+	repo, usecase := uc.Name+"Repo", uc.Name+"Usecase"
+	std := []string{"context"}
+	if usesTime(uc) {
+		std = append(std, "time")
+	}
+	header(file, uc.Class, std, []string{"github.com/go-kratos/kratos/v2/log"})
+	comment(file, repo, uc.Comment, "is the data access of "+usecase+".")
+	file.P("type " + repo + " interface {")
+	for _, m := range uc.Methods {
+		if m.Comment != "" {
+			file.P("\t// " + m.Name + " " + m.Comment)
+		}
+		file.P("\t" + m.Name + signature(m))
+	}
+	file.P("}")
+	file.P("")
+	comment(file, usecase, uc.Comment, "is the business logic of "+uc.Name+".")
+	file.P("type " + usecase + " struct {")
+	file.P("\trepo " + repo)
+	file.P("\tlog  *log.Helper")
+	file.P("}")
+	file.P("")
+	file.P("// New" + usecase + " returns a new " + usecase + ".")
+	file.P("func New" + usecase + "(repo " + repo + ", logger log.Logger) *" + usecase + " {")
+	file.P("\treturn &" + usecase + "{repo: repo, log: log.NewHelper(logger)}")
+	file.P("}")
+	for _, m := range uc.Methods {
+		file.P("")
+		if m.Comment != "" {
+			file.P("// " + m.Name + " " + m.Comment)
+		}
+		file.P("func (uc *" + usecase + ") " + m.Name + signature(m) + " {")
+		if uc.Impl != "" && m.Java != "" {
+			file.P("\t// TODO: port " + java.SimpleName(uc.Impl) + "." + m.Java)
+		} else {
+			file.P("\t// TODO: implement")
+		}
+		args := []string{"ctx"}
+		for _, p := range m.Params {
+			args = append(args, paramName(p.Name))
+		}
+		file.P("\treturn uc.repo." + m.Name + "(" + strings.Join(args, ", ") + ")")
+		file.P("}")
+	}
+	file.Format()
+	return file
+}
+
+// Types returns the file declaring a Go struct for each of the entities.
+func Types(entities []*ir.Entity) *gen.GeneratedFile {
+	file := gen.NewGeneratedFile()
+	file.Name = TypesFile
+	needTime := false
+	for _, e := range entities {
+		for _, f := range e.Fields {
+			needTime = needTime || f.Type.Kind == ir.Time
+		}
+	}
+	std := make([]string, 0)
+	if needTime {
+		std = append(std, "time")
+	}
+	header(file, "", std, nil)
+	for i, e := range entities {
+		if i > 0 {
+			file.P("")
+		}
+		comment(file, e.Name, e.Comment, "is a "+e.Name+".")
+		file.P("type " + e.Name + " struct {")
+		for _, f := range e.Fields {
+			line := "\t" + strs.GoCamelCase(f.Name) + " " + GoType(f.Type, f.Repeated) + " `json:" + strconv.Quote(f.Name+",omitempty") + "`"
+			if f.Comment != "" && f.Comment != f.Name {
+				line += " // " + f.Comment
+			}
+			file.P(line)
+		}
+		file.P("}")
+	}
+	file.Format()
+	return file
+}
+
+// FileName returns the name of the biz file of a usecase.
+func FileName(uc *ir.Usecase) string {
+	return strs.SnakeCase(uc.Name) + ".go"
+}
+
+// GoType returns the Go type of a field or parameter. Entities are passed
+// by pointer.
+func GoType(t ir.Type, repeated bool) string {
+	var typ string
+	switch t.Kind {
+	case ir.Int32, ir.Int64, ir.Float32, ir.Float64, ir.Bool, ir.String:
+		typ = string(t.Kind)
+	case ir.Bytes:
+		typ = "[]byte"
+	case ir.Time:
+		typ = "time.Time"
+	case ir.Message:
+		typ = "*" + t.Name
+	default:
+		typ = "string"
+	}
+	if repeated {
+		return "[]" + typ
+	}
+	return typ
+}
+
+// Uses returns the entities of m used by the usecases, directly or through
+// the fields of other entities, in model order.
+func Uses(m *ir.Model) []*ir.Entity {
+	used := make(map[string]bool)
+	var use func(t ir.Type)
+	use = func(t ir.Type) {
+		if t.Kind != ir.Message || used[t.Name] {
+			return
+		}
+		used[t.Name] = true
+		if e := m.Entity(t.Name); e != nil {
+			for _, dep := range e.Deps() {
+				use(ir.Ref(dep))
+			}
+		}
+	}
+	for _, uc := range m.Usecases {
+		for _, meth := range uc.Methods {
+			for _, p := range meth.Params {
+				use(p.Type)
+			}
+			if meth.Result != nil {
+				use(meth.Result.Type)
+			}
+		}
+	}
+	entities := make([]*ir.Entity, 0)
+	for _, e := range m.Entities {
+		if used[e.Name] {
+			entities = append(entities, e)
+		}
+	}
+	return entities
+}
+
+// header writes the file comment, the package clause and the imports,
+// the standard library first.
+func header(file *gen.GeneratedFile, class string, std, imports []string) {
+	if class != "" {
+		file.P("// Generated by j2g v", version, " from ", class, ".")
+	} else {
+		file.P("// Generated by j2g v", version, ".")
+	}
+	file.P("")
+	file.P("package " + Package)
+	file.P("")
+	if len(std)+len(imports) == 0 {
+		return
+	}
+	file.P("import (")
+	for _, s := range std {
+		file.P("\t" + strconv.Quote(s))
+	}
+	if len(std) > 0 && len(imports) > 0 {
+		file.P("")
+	}
+	for _, s := range imports {
+		file.P("\t" + strconv.Quote(s))
+	}
+	file.P(")")
+	file.P("")
+}
+
+// comment writes the doc comment of a declaration: the source comment if
+// any, or else the fallback sentence.
+func comment(file *gen.GeneratedFile, name, comment, fallback string) {
+	if comment != "" {
+		file.P("// " + name + " " + comment)
+	} else {
+		file.P("// " + name + " " + fallback)
+	}
+}
+
+// signature returns the parameters and results of a method.
+func signature(m *ir.Method) string {
+	params := []string{"ctx context.Context"}
+	for _, p := range m.Params {
+		params = append(params, paramName(p.Name)+" "+GoType(p.Type, p.Repeated))
+	}
+	results := "error"
+	if m.Result != nil {
+		results = "(" + GoType(m.Result.Type, m.Result.Repeated) + ", error)"
+	}
+	return "(" + strings.Join(params, ", ") + ") " + results
+}
+
+// paramName returns a Java parameter name usable in Go: keywords and the
+// names taken by the context and the receiver get a trailing underscore.
+func paramName(name string) string {
+	if token.IsKeyword(name) || name == "ctx" || name == "uc" {
+		return name + "_"
+	}
+	return name
+}
+
+// usesTime reports whether the methods of uc use time.Time.
+func usesTime(uc *ir.Usecase) bool {
+	for _, m := range uc.Methods {
+		for _, p := range m.Params {
+			if p.Type.Kind == ir.Time {
+				return true
+			}
+		}
+		if m.Result != nil && m.Result.Type.Kind == ir.Time {
+			return true
+		}
+	}
+	return false
+}
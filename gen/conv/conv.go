@@ -0,0 +1,377 @@
+// Package conv generates the functions converting between the protobuf
+// messages of VOs and requests and the ent entities of DOs, which Kratos
+// services otherwise copy field by field by hand.
+package conv
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/gen/data"
+	"github.com/luobote55/java2go/gen/ent"
+	"github.com/luobote55/java2go/gen/proto"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+)
+
+const (
+	version = "1.0.0"
+)
+
+// FileName is the name of the file holding the converters.
+const FileName = "convert.go"
+
+// A Pair is a message and the entity backed by a table it stands for, e.g.
+// DeviceVO or DeviceRequest and Device.
+type Pair struct {
+	Service *ir.Service // declares the message.
+	Message *ir.Entity
+	Entity  *ir.Entity
+	// Request is set if the message is a request, converted into ent
+	// create and update builders rather than from entities.
+	Request bool
+	Fields  []*Field
+	// Unmatched are the message fields without counterpart in the entity
+	// and Missing the columns of the entity without counterpart in the
+	// message, but for the audit fields every schema has. Fields matched
+	// by name whose types do not convert are in both.
+	Unmatched []string
+	Missing   []string
+}
+
+// A Field is a message field and the entity field it is copied from or to.
+type Field struct {
+	Message *ir.Field
+	Entity  *ir.Field
+}
+
+// requestSuffixes mark the messages holding the input of a create or an
+// update; the other suffixes of replySuffixes mark output.
+var (
+	requestSuffixes = []string{"Request", "Req", "Form", "Params", "Param", "Command", "Cmd"}
+	replySuffixes   = []string{"VO", "Vo", "DTO", "Dto", "BO", "Reply", "Response", "Resp"}
+)
+
+// base returns the name of the entity a message stands for, and whether
+// the message is a request.
+func base(name string) (string, bool) {
+	for _, s := range requestSuffixes {
+		if strings.HasSuffix(name, s) && len(name) > len(s) {
+			return strings.TrimSuffix(name, s), true
+		}
+	}
+	for _, s := range replySuffixes {
+		if strings.HasSuffix(name, s) && len(name) > len(s) {
+			return strings.TrimSuffix(name, s), false
+		}
+	}
+	return name, false
+}
+
+// Match pairs the messages of the services in m with the entities backed
+// by tables whose names they share once the suffixes of VOs and requests
+// are removed, and matches their fields by name or column. Messages used
+// by several services are paired once, for the first of them.
+func Match(m *ir.Model, cfg *config.Config) []*Pair {
+	tables := make(map[string]*ir.Entity)
+	for _, e := range m.Entities {
+		if e.Table == "" {
+			continue
+		}
+		tables[strings.ToLower(e.Name)] = e
+		if key := strings.ToLower(strs.GoCamelCase(e.Table)); tables[key] == nil {
+			tables[key] = e
+		}
+	}
+	pairs := make([]*Pair, 0)
+	seen := make(map[string]bool)
+	for _, svc := range m.Services {
+		for _, msg := range svc.Messages {
+			name, request := base(msg.Name)
+			e := tables[strings.ToLower(name)]
+			if e == nil || seen[msg.Name] {
+				continue
+			}
+			seen[msg.Name] = true
+			pairs = append(pairs, match(svc, msg, e, request, cfg))
+		}
+	}
+	return pairs
+}
+
+// key normalizes a field name or column for matching, e.g. deviceName
+// and device_name both to devicename.
+func key(s string) string {
+	return strings.ToLower(strings.Replace(s, "_", "", -1))
+}
+
+func match(svc *ir.Service, msg, e *ir.Entity, request bool, cfg *config.Config) *Pair {
+	p := &Pair{Service: svc, Message: msg, Entity: e, Request: request}
+	fields := make(map[string]*ir.Field)
+	for _, f := range ent.Fields(e, cfg) {
+		fields[key(f.Name)] = f
+		if k := key(ent.Column(f)); fields[k] == nil {
+			fields[k] = f
+		}
+	}
+	used := make(map[*ir.Field]bool)
+	for _, f := range msg.Fields {
+		ef := fields[key(f.Name)]
+		if ef == nil && f.Column != "" {
+			ef = fields[key(f.Column)]
+		}
+		if ef == nil || used[ef] || f.Repeated || !convertible(ef.Type, f.Type) {
+			p.Unmatched = append(p.Unmatched, f.Name)
+			continue
+		}
+		used[ef] = true
+		p.Fields = append(p.Fields, &Field{Message: f, Entity: ef})
+	}
+	for _, f := range e.Fields {
+		if !used[f] {
+			p.Missing = append(p.Missing, ent.Column(f))
+		}
+	}
+	return p
+}
+
+// numeric reports whether a kind is a number.
+func numeric(k ir.Kind) bool {
+	switch k {
+	case ir.Int32, ir.Int64, ir.Float32, ir.Float64:
+		return true
+	}
+	return false
+}
+
+// convertible reports whether the values of an entity field convert into
+// the values of a message field and back.
+func convertible(from, to ir.Type) bool {
+	if from.Kind == ir.Message || to.Kind == ir.Message {
+		return false
+	}
+	return from.Kind == to.Kind || numeric(from.Kind) && numeric(to.Kind)
+}
+
+// goType returns the Go type of a scalar in the ent entities and in the
+// protobuf messages, but for times.
+func goType(t ir.Type) string {
+	switch t.Kind {
+	case ir.Int32, ir.Int64, ir.Float32, ir.Float64, ir.Bool:
+		return string(t.Kind)
+	case ir.Bytes:
+		return "[]byte"
+	}
+	return "string"
+}
+
+// messageField returns the Go name protoc-gen-go gives a message field.
+func messageField(f *ir.Field) string {
+	return strs.GoCamelCase(strs.LetterCamelCase(f.Name))
+}
+
+// entityField returns the Go name ent gives an entity field.
+func entityField(f *ir.Field) string {
+	if f.ID {
+		return "ID"
+	}
+	return data.Pascal(ent.Column(f))
+}
+
+// Generate returns the file converting between the messages and entities
+// of pairs, nil if there are none: ToXxx functions converting an entity
+// into a reply message, and XxxToCreate and XxxToUpdate functions setting
+// the fields of a request message on ent builders. Ids are not set.
+func Generate(pairs []*Pair, cfg *config.Config) *gen.GeneratedFile {
+	if len(pairs) == 0 {
+		return nil
+	}
+	g := newGenerator()
+	g.ent = true
+	for _, p := range pairs {
+		alias := g.alias(p.Service, cfg)
+		if p.Request {
+			g.builder(p, alias, "Create", "Create")
+			g.builder(p, alias, "Update", "UpdateOne")
+		} else {
+			g.reply(p, alias)
+		}
+	}
+	return g.file(FileName, cfg)
+}
+
+// A generator holds the state of the file being generated.
+type generator struct {
+	body      *gen.GeneratedFile
+	imports   map[string]string // import path -> alias.
+	timestamp bool              // the body uses timestamppb.
+	ent       bool              // the body uses the ent package.
+}
+
+func newGenerator() *generator {
+	return &generator{body: gen.NewGeneratedFile(), imports: make(map[string]string)}
+}
+
+// file returns the file of the given name holding the body and the
+// imports it uses.
+func (g *generator) file(name string, cfg *config.Config) *gen.GeneratedFile {
+	file := gen.NewGeneratedFile()
+	file.Name = name
+	file.P("// Generated by j2g v", version, ".")
+	file.P("")
+	file.P("package " + data.Package)
+	file.P("")
+	file.P("import (")
+	if g.timestamp {
+		file.P("\t\"google.golang.org/protobuf/types/known/timestamppb\"")
+		file.P("")
+	}
+	paths := make([]string, 0, len(g.imports))
+	for p := range g.imports {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		file.P("\t" + g.imports[p] + " " + strconv.Quote(p))
+	}
+	if g.ent {
+		file.P("\t" + strconv.Quote(cfg.EntPackage()))
+	}
+	file.P(")")
+	file.P(string(g.body.Content()))
+	file.Format()
+	return file
+}
+
+// alias returns the import alias of the Go package of the messages of a
+// service, e.g. devicev1 for api/device/v1.
+func (g *generator) alias(svc *ir.Service, cfg *config.Config) string {
+	ip := proto.ImportPath(svc, cfg)
+	if a, ok := g.imports[ip]; ok {
+		return a
+	}
+	a := ident(proto.Segment(svc) + path.Base(ip))
+	for taken := true; taken; {
+		taken = false
+		for _, other := range g.imports {
+			if other == a {
+				a += "_"
+				taken = true
+			}
+		}
+	}
+	g.imports[ip] = a
+	return a
+}
+
+// ident returns s without the characters not allowed in Go identifiers.
+func ident(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' && b.Len() > 0 {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "pb"
+	}
+	return b.String()
+}
+
+// unmatched writes the comment lines listing the fields of a pair
+// without counterpart.
+func (g *generator) unmatched(p *Pair) {
+	if len(p.Unmatched) > 0 {
+		g.body.P("//")
+		g.body.P("// Fields of " + p.Message.Name + " without counterpart: " + strings.Join(p.Unmatched, ", ") + ".")
+	}
+	if len(p.Missing) > 0 {
+		if len(p.Unmatched) == 0 {
+			g.body.P("//")
+		}
+		g.body.P("// Columns of " + p.Entity.Name + " without counterpart: " + strings.Join(p.Missing, ", ") + ".")
+	}
+}
+
+// reply writes the functions converting entities into a reply message.
+func (g *generator) reply(p *Pair, alias string) {
+	msg := alias + "." + p.Message.Name
+	name := "To" + p.Message.Name
+	g.body.P("")
+	g.body.P("// " + name + " converts a " + p.Entity.Name + " into a " + p.Message.Name + ".")
+	g.unmatched(p)
+	g.body.P("func " + name + "(e *ent." + p.Entity.Name + ") *" + msg + " {")
+	g.body.P("\tif e == nil {")
+	g.body.P("\t\treturn nil")
+	g.body.P("\t}")
+	g.body.P("\tm := &" + msg + "{}")
+	for _, f := range p.Fields {
+		src := "e." + entityField(f.Entity)
+		dst := "m." + messageField(f.Message)
+		if f.Entity.Nillable && !f.Entity.ID {
+			g.body.P("\tif " + src + " != nil {")
+			g.body.P("\t\t" + dst + " = " + g.toMessage("*"+src, f))
+			g.body.P("\t}")
+			continue
+		}
+		g.body.P("\t" + dst + " = " + g.toMessage(src, f))
+	}
+	g.body.P("\treturn m")
+	g.body.P("}")
+	g.body.P("")
+	g.body.P("// " + name + "s converts " + p.Entity.Name + " entities into " + p.Message.Name + " messages.")
+	g.body.P("func " + name + "s(es []*ent." + p.Entity.Name + ") []*" + msg + " {")
+	g.body.P("\tres := make([]*" + msg + ", 0, len(es))")
+	g.body.P("\tfor _, e := range es {")
+	g.body.P("\t\tres = append(res, " + name + "(e))")
+	g.body.P("\t}")
+	g.body.P("\treturn res")
+	g.body.P("}")
+}
+
+// toMessage converts the value x of an entity field into the value of the
+// message field.
+func (g *generator) toMessage(x string, f *Field) string {
+	switch {
+	case f.Message.Type.Kind == ir.Time:
+		g.timestamp = true
+		return "timestamppb.New(" + x + ")"
+	case f.Message.Type.Kind != f.Entity.Type.Kind:
+		return goType(f.Message.Type) + "(" + x + ")"
+	}
+	return x
+}
+
+// builder writes the function setting the fields of a request message on
+// the create or update-one builder of the entity.
+func (g *generator) builder(p *Pair, alias, op, typ string) {
+	name := p.Message.Name + "To" + op
+	builder := "*ent." + p.Entity.Name + typ
+	g.body.P("")
+	g.body.P("// " + name + " sets the fields of a " + p.Message.Name + " on a " + p.Entity.Name + " " + strings.ToLower(op) + " builder.")
+	g.unmatched(p)
+	g.body.P("func " + name + "(r *" + alias + "." + p.Message.Name + ", b " + builder + ") " + builder + " {")
+	for _, f := range p.Fields {
+		if f.Entity.ID {
+			continue
+		}
+		src := "r." + messageField(f.Message)
+		set := "b.Set" + entityField(f.Entity)
+		switch {
+		case f.Message.Type.Kind == ir.Time:
+			g.body.P("\tif " + src + " != nil {")
+			g.body.P("\t\t" + set + "(" + src + ".AsTime())")
+			g.body.P("\t}")
+		case f.Message.Type.Kind != f.Entity.Type.Kind:
+			g.body.P("\t" + set + "(" + goType(f.Entity.Type) + "(" + src + "))")
+		default:
+			g.body.P("\t" + set + "(" + src + ")")
+		}
+	}
+	g.body.P("\treturn b")
+	g.body.P("}")
+}
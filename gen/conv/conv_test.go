@@ -0,0 +1,133 @@
+package conv
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/ir"
+)
+
+func TestGenerate(t *testing.T) {
+	cfg := config.Default()
+	cfg.Go.Module = "example.com/demo"
+	m := &ir.Model{
+		Services: []*ir.Service{{
+			Name: "Device",
+			Path: "/device/api",
+			Messages: []*ir.Entity{
+				{Name: "DeviceVO", Fields: []*ir.Field{
+					{Name: "id", Type: ir.Scalar(ir.Int64)},
+					{Name: "deviceName", Type: ir.Scalar(ir.String)},
+					{Name: "seenAt", Type: ir.Scalar(ir.Time)},
+					{Name: "tags", Type: ir.Scalar(ir.String), Repeated: true},
+				}},
+				{Name: "DeviceRequest", Fields: []*ir.Field{
+					{Name: "id", Type: ir.Scalar(ir.Int64)},
+					{Name: "deviceName", Type: ir.Scalar(ir.String)},
+					{Name: "status", Type: ir.Scalar(ir.Int64)},
+				}},
+				{Name: "GetDeviceReply", Fields: []*ir.Field{
+					{Name: "deviceVO", Type: ir.Ref("DeviceVO")},
+				}},
+			},
+		}},
+		Entities: []*ir.Entity{{
+			Name:  "Device",
+			Table: "device",
+			Fields: []*ir.Field{
+				{Name: "id", Type: ir.Scalar(ir.Int64), ID: true},
+				{Name: "name", Column: "device_name", Type: ir.Scalar(ir.String)},
+				{Name: "status", Type: ir.Scalar(ir.Int32)},
+				{Name: "seenAt", Column: "seen_at", Type: ir.Scalar(ir.Time), Nillable: true},
+			},
+		}},
+	}
+	pairs := Match(m, cfg)
+	if len(pairs) != 2 {
+		t.Fatalf("got %d pairs, want DeviceVO and DeviceRequest", len(pairs))
+	}
+	if got := strings.Join(pairs[0].Unmatched, ","); got != "tags" {
+		t.Errorf("unmatched fields of DeviceVO = %q, want tags", got)
+	}
+	if got := strings.Join(pairs[0].Missing, ","); got != "status" {
+		t.Errorf("missing columns of DeviceVO = %q, want status", got)
+	}
+	src := string(Generate(pairs, cfg).Content())
+	for _, want := range []string{
+		`devicev1 "example.com/demo/api/device/v1"`,
+		"func ToDeviceVO(e *ent.Device) *devicev1.DeviceVO {",
+		"m.Id = e.ID",
+		"m.DeviceName = e.DeviceName",
+		"m.SeenAt = timestamppb.New(*e.SeenAt)",
+		"func ToDeviceVOs(es []*ent.Device) []*devicev1.DeviceVO {",
+		"func DeviceRequestToCreate(r *devicev1.DeviceRequest, b *ent.DeviceCreate) *ent.DeviceCreate {",
+		"b.SetStatus(int32(r.Status))",
+		"func DeviceRequestToUpdate(r *devicev1.DeviceRequest, b *ent.DeviceUpdateOne) *ent.DeviceUpdateOne {",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated converters do not contain %q", want)
+		}
+	}
+	if strings.Contains(src, "SetID") {
+		t.Error("builders set the id")
+	}
+}
+
+func TestConverter(t *testing.T) {
+	cfg := config.Default()
+	cfg.Go.Module = "example.com/demo"
+	vo := &ir.Entity{Name: "DeviceVO", Fields: []*ir.Field{
+		{Name: "id", Type: ir.Scalar(ir.Int64)},
+		{Name: "deviceName", Type: ir.Scalar(ir.String)},
+		{Name: "owner", Type: ir.Ref("OwnerVO")},
+		{Name: "seenAt", Type: ir.Scalar(ir.Time)},
+	}}
+	owner := &ir.Entity{Name: "OwnerVO", Fields: []*ir.Field{{Name: "name", Type: ir.Scalar(ir.String)}}}
+	device := &ir.Entity{Name: "Device", Table: "device", Fields: []*ir.Field{
+		{Name: "id", Type: ir.Scalar(ir.Int64), ID: true},
+		{Name: "name", Column: "device_name", Type: ir.Scalar(ir.String)},
+		{Name: "ownerName", Column: "owner_name", Type: ir.Scalar(ir.String)},
+		{Name: "seenAt", Column: "seen_at", Type: ir.Scalar(ir.Time), Nillable: true},
+		{Name: "remark", Type: ir.Scalar(ir.String)},
+	}}
+	m := &ir.Model{
+		Services: []*ir.Service{{Name: "Device", Path: "/device", Messages: []*ir.Entity{vo, owner}}},
+		Entities: []*ir.Entity{vo, owner, device},
+	}
+	c := &ir.Converter{Name: "DeviceConvert", Class: "com.example.DeviceConvert", Methods: []*ir.Conversion{
+		{Name: "ToVO", Java: "toVO", Params: []*ir.Param{{Name: "d", Type: ir.Ref("Device")}}, Result: &ir.Param{Type: ir.Ref("DeviceVO")},
+			Mappings: []*ir.Mapping{{Target: "deviceName", Source: "name"}, {Target: "owner.name", Source: "ownerName"}}},
+		{Name: "ToDO", Java: "toDO", Params: []*ir.Param{{Name: "vo", Type: ir.Ref("DeviceVO")}}, Result: &ir.Param{Type: ir.Ref("Device")},
+			Mappings: []*ir.Mapping{{Target: "ownerName", Source: "owner.name"}, {Target: "remark", Expression: "\"\""}, {Target: "name", Ignore: true},
+				{Target: "label", Expression: "vo.getDeviceName()"}}},
+		{Name: "Update", Java: "update", Params: []*ir.Param{{Name: "vo", Type: ir.Ref("DeviceVO")}, {Name: "type", Type: ir.Ref("OwnerVO")}},
+			Result: &ir.Param{Name: "d", Type: ir.Ref("Device")}, Update: true, Explicit: true,
+			Mappings: []*ir.Mapping{{Target: "ownerName", Source: "type.name"}, {Target: "seenAt", Source: "seenAt"}}},
+	}}
+	file, skips := Converter(c, m, cfg)
+	src := string(file.Content())
+	for _, want := range []string{
+		"func (c DeviceConvert) ToVO(d *ent.Device) *devicev1.DeviceVO {",
+		"t.DeviceName = d.DeviceName",
+		"t.Owner = &devicev1.OwnerVO{}",
+		"t.Owner.Name = d.OwnerName",
+		"t.SeenAt = timestamppb.New(*d.SeenAt)",
+		"t.OwnerName = vo.GetOwner().GetName()",
+		"func (c DeviceConvert) Update(vo *devicev1.DeviceVO, type_ *devicev1.OwnerVO, t *ent.Device) {",
+		"if type_ != nil {",
+		"t.OwnerName = type_.Name",
+		"v := vo.SeenAt.AsTime()",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated converter does not contain %q", want)
+		}
+	}
+	got := make([]string, 0)
+	for _, s := range skips {
+		got = append(got, s.Method.Java+"."+s.Target+":"+s.Reason)
+	}
+	if want := "toDO.remark:expression,toDO.label:expression"; strings.Join(got, ",") != want {
+		t.Errorf("skips = %s, want %s", strings.Join(got, ","), want)
+	}
+}
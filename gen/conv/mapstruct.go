@@ -0,0 +1,601 @@
+package conv
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/gen/ent"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+)
+
+// Reasons a converter leaves a target field, or a whole method, out.
+const (
+	Unmapped      = "unmapped"      // no source field of the same name.
+	Unconvertible = "unconvertible" // the source does not convert into the target.
+	Unresolved    = "unresolved"    // the source or target path names no field.
+	Unsupported   = "unsupported"   // paths through ent entities.
+	Expression    = "expression"    // Java expressions, which are not translated.
+	NoMessage     = "message"       // a VO or request without protobuf message.
+)
+
+// A Skip is a target field a converter leaves unset, or a whole method
+// it leaves out if Target is empty.
+type Skip struct {
+	Method *ir.Conversion
+	Target string // property path of the target.
+	Reason string
+	// Expression is the Java expression of the target, for Expression.
+	Expression string
+}
+
+// ConverterFile returns the name of the file of a converter, e.g.
+// device_convert.go.
+func ConverterFile(c *ir.Converter) string {
+	return strs.SnakeCase(c.Name) + ".go"
+}
+
+// Converter returns the file implementing a MapStruct mapper over the
+// protobuf messages and ent entities of m: an empty struct named after the
+// mapper with a method per conversion, e.g.
+//
+//	func (c DeviceConvert) ToVO(d *ent.Device) *devicev1.DeviceVO
+//
+// and the fields and methods it leaves out. The file is nil if every
+// method is left out. Fields are set as explicitly
+// mapped, else from the source fields of the same name; lists are
+// converted by the method converting their elements.
+func Converter(c *ir.Converter, m *ir.Model, cfg *config.Config) (*gen.GeneratedFile, []*Skip) {
+	x := &mapper{generator: newGenerator(), c: c, m: m, cfg: cfg, services: make(map[string]*ir.Service)}
+	for _, svc := range m.Services {
+		for _, msg := range svc.Messages {
+			if x.services[msg.Name] == nil {
+				x.services[msg.Name] = svc
+			}
+		}
+	}
+	comment := c.Comment
+	if comment == "" {
+		comment = "converts between messages and entities like the MapStruct mapper " + simpleName(c.Class) + "."
+	}
+	x.body.P("")
+	x.body.P("// " + c.Name + " " + comment)
+	x.body.P("type " + c.Name + " struct{}")
+	written := false
+	for _, cv := range c.Methods {
+		written = x.method(cv) || written
+	}
+	if !written {
+		return nil, x.skips
+	}
+	return x.file(ConverterFile(c), cfg), x.skips
+}
+
+// simpleName returns the simple name of a Java class.
+func simpleName(class string) string {
+	return class[strings.LastIndex(class, ".")+1:]
+}
+
+// A mapper generates the methods of a converter.
+type mapper struct {
+	*generator
+	c        *ir.Converter
+	m        *ir.Model
+	cfg      *config.Config
+	services map[string]*ir.Service // message name -> declaring service.
+	skips    []*Skip
+}
+
+func (x *mapper) skip(cv *ir.Conversion, target, reason string) {
+	x.skips = append(x.skips, &Skip{Method: cv, Target: target, Reason: reason})
+}
+
+func (x *mapper) skipExpression(cv *ir.Conversion, target, expr string) {
+	x.skips = append(x.skips, &Skip{Method: cv, Target: target, Reason: Expression, Expression: expr})
+}
+
+// A side is an entity converted from or into: an ent entity if it is
+// backed by a table, else a protobuf message.
+type side struct {
+	e   *ir.Entity
+	svc *ir.Service // declares the message; nil for ent entities.
+}
+
+// side returns the side of the named entity, or nil if it is neither
+// backed by a table nor a message of a service.
+func (x *mapper) side(name string) *side {
+	e := x.m.Entity(name)
+	switch {
+	case e == nil:
+		return nil
+	case e.Table != "":
+		return &side{e: e}
+	case x.services[name] != nil:
+		return &side{e: e, svc: x.services[name]}
+	}
+	return nil
+}
+
+func (s *side) message() bool {
+	return s.svc != nil
+}
+
+// typ returns the Go type of a side, adding its import.
+func (x *mapper) typ(s *side) string {
+	if s.message() {
+		return "*" + x.alias(s.svc, x.cfg) + "." + s.e.Name
+	}
+	x.ent = true
+	return "*ent." + s.e.Name
+}
+
+func (x *mapper) fields(s *side) []*ir.Field {
+	if s.message() {
+		return s.e.Fields
+	}
+	return ent.Fields(s.e, x.cfg)
+}
+
+// field returns the field of a side with the given name or column.
+func (x *mapper) field(s *side, name string) *ir.Field {
+	for _, f := range x.fields(s) {
+		if key(f.Name) == key(name) || !s.message() && key(ent.Column(f)) == key(name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// goName returns the Go name of a field of a side.
+func (s *side) goName(f *ir.Field) string {
+	if s.message() {
+		return messageField(f)
+	}
+	return entityField(f)
+}
+
+// pointer reports whether a field of a side is a pointer, as the nillable
+// fields of ent entities are.
+func (s *side) pointer(f *ir.Field) bool {
+	return !s.message() && f.Nillable && !f.ID
+}
+
+// reserved are the names the generated methods use themselves.
+var reserved = map[string]bool{
+	"c": true, "t": true, "res": true, "item": true, "v": true, "ent": true, "timestamppb": true,
+	"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true,
+	"defer": true, "else": true, "fallthrough": true, "for": true, "func": true, "go": true,
+	"goto": true, "if": true, "import": true, "interface": true, "map": true, "package": true,
+	"range": true, "return": true, "select": true, "struct": true, "switch": true, "type": true, "var": true,
+}
+
+// A source is a param a conversion reads.
+type source struct {
+	name string // Go name.
+	side *side
+}
+
+// A stmt is a statement setting a target field, reading the source of
+// the given name or, if it is empty, none.
+type stmt struct {
+	source string
+	lines  []string
+}
+
+// find returns the conversion of the converter from an entity into
+// another, or nil. Lists are converted by list methods.
+func (x *mapper) find(from, to string, list bool) *ir.Conversion {
+	for _, cv := range x.c.Methods {
+		if len(cv.Params) == 1 && !cv.Update && cv.Result.Repeated == list &&
+			cv.Params[0].Type.Name == from && cv.Result.Type.Name == to {
+			return cv
+		}
+	}
+	return nil
+}
+
+// method writes the method of a conversion, and reports whether it did.
+func (x *mapper) method(cv *ir.Conversion) bool {
+	srcs := make([]*source, 0, len(cv.Params))
+	for _, p := range cv.Params {
+		s := x.side(p.Type.Name)
+		if s == nil {
+			x.skip(cv, "", NoMessage)
+			return false
+		}
+		name := p.Name
+		if reserved[name] {
+			name += "_"
+		}
+		srcs = append(srcs, &source{name: name, side: s})
+	}
+	ds := x.side(cv.Result.Type.Name)
+	if ds == nil {
+		x.skip(cv, "", NoMessage)
+		return false
+	}
+	var elem *ir.Conversion
+	if cv.Result.Repeated {
+		if elem = x.find(cv.Params[0].Type.Name, cv.Result.Type.Name, false); elem == nil {
+			x.skip(cv, "", Unconvertible)
+			return false
+		}
+	}
+
+	params := make([]string, 0, len(srcs))
+	nils := make([]string, 0, len(srcs))
+	for _, src := range srcs {
+		typ := x.typ(src.side)
+		if cv.Result.Repeated {
+			typ = "[]" + typ
+		}
+		params = append(params, src.name+" "+typ)
+		nils = append(nils, src.name+" == nil")
+	}
+	typ := x.typ(ds)
+	x.body.P("")
+	switch {
+	case cv.Comment != "":
+		x.body.P("// " + cv.Name + " " + cv.Comment)
+	case cv.Update:
+		x.body.P("// " + cv.Name + " sets the fields of " + describe(cv.Result) + " from " + describe(cv.Params...) + ".")
+	default:
+		x.body.P("// " + cv.Name + " converts " + describe(cv.Params...) + " into " + describe(cv.Result) + ".")
+	}
+	switch {
+	case cv.Result.Repeated:
+		x.body.P("func (c " + x.c.Name + ") " + cv.Name + "(" + params[0] + ") []" + typ + " {")
+		x.body.P("\tif " + srcs[0].name + " == nil {")
+		x.body.P("\t\treturn nil")
+		x.body.P("\t}")
+		x.body.P("\tres := make([]" + typ + ", 0, len(" + srcs[0].name + "))")
+		x.body.P("\tfor _, item := range " + srcs[0].name + " {")
+		x.body.P("\t\tres = append(res, c." + elem.Name + "(item))")
+		x.body.P("\t}")
+		x.body.P("\treturn res")
+		x.body.P("}")
+		return true
+	case cv.Update:
+		x.body.P("func (c " + x.c.Name + ") " + cv.Name + "(" + strings.Join(params, ", ") + ", t " + typ + ") {")
+		x.body.P("\tif t == nil || " + strings.Join(nils, " && ") + " {")
+		x.body.P("\t\treturn")
+		x.body.P("\t}")
+	default:
+		x.body.P("func (c " + x.c.Name + ") " + cv.Name + "(" + strings.Join(params, ", ") + ") " + typ + " {")
+		x.body.P("\tif " + strings.Join(nils, " && ") + " {")
+		x.body.P("\t\treturn nil")
+		x.body.P("\t}")
+		x.body.P("\tt := &" + typ[1:] + "{}")
+	}
+	stmts := x.assign(cv, srcs, "t", "", ds, cv.Mappings, !cv.Explicit)
+	if len(srcs) == 1 {
+		for _, st := range stmts {
+			x.lines(st.lines, "\t")
+		}
+	} else {
+		// sources may be nil: the fields read from each are set together.
+		for _, st := range stmts {
+			if st.source == "" {
+				x.lines(st.lines, "\t")
+			}
+		}
+		for _, src := range srcs {
+			first := true
+			for _, st := range stmts {
+				if st.source != src.name {
+					continue
+				}
+				if first {
+					x.body.P("\tif " + src.name + " != nil {")
+					first = false
+				}
+				x.lines(st.lines, "\t\t")
+			}
+			if !first {
+				x.body.P("\t}")
+			}
+		}
+	}
+	if !cv.Update {
+		x.body.P("\treturn t")
+	}
+	x.body.P("}")
+	return true
+}
+
+// describe returns the description of params, e.g. "a Device and a
+// DeviceRequest" or "Device lists".
+func describe(params ...*ir.Param) string {
+	list := make([]string, 0, len(params))
+	for _, p := range params {
+		if p.Repeated {
+			list = append(list, p.Type.Name+" lists")
+		} else {
+			list = append(list, "a "+p.Type.Name)
+		}
+	}
+	return strings.Join(list, " and ")
+}
+
+func (x *mapper) lines(lines []string, indent string) {
+	for _, l := range lines {
+		x.body.P(indent + l)
+	}
+}
+
+// A target is a mapping of a field, with the rest of its target path.
+type target struct {
+	m    *ir.Mapping
+	rest string
+}
+
+// assign returns the statements setting the fields of the target dst of
+// side ds from the sources: explicitly mapped fields as mapped, the others
+// from the source fields of the same name if implicit is set. Paths are
+// reported below prefix.
+func (x *mapper) assign(cv *ir.Conversion, srcs []*source, dst, prefix string, ds *side, mappings []*ir.Mapping, implicit bool) []*stmt {
+	byField := make(map[string][]*target)
+	order := make([]string, 0)
+	for _, m := range mappings {
+		first, rest := m.Target, ""
+		if i := strings.Index(first, "."); i >= 0 {
+			first, rest = first[:i], first[i+1:]
+		}
+		if f := x.field(ds, first); f != nil {
+			first = f.Name
+		}
+		if byField[first] == nil {
+			order = append(order, first)
+		}
+		byField[first] = append(byField[first], &target{m: m, rest: rest})
+	}
+	stmts := make([]*stmt, 0)
+	for _, f := range x.fields(ds) {
+		path := prefix + f.Name
+		to := dst + "." + ds.goName(f)
+		targets := byField[f.Name]
+		delete(byField, f.Name)
+		var direct *ir.Mapping
+		nested := make([]*ir.Mapping, 0)
+		for _, t := range targets {
+			if t.rest == "" {
+				direct = t.m
+			} else {
+				nested = append(nested, &ir.Mapping{Target: t.rest, Source: t.m.Source, Constant: t.m.Constant, Expression: t.m.Expression, Ignore: t.m.Ignore})
+			}
+		}
+		switch {
+		case direct != nil && direct.Ignore:
+		case direct != nil && direct.Expression != "":
+			x.skipExpression(cv, path, direct.Expression)
+		case direct != nil && direct.Constant != nil:
+			lines, reason := constant(*direct.Constant, to, f, ds)
+			if reason != "" {
+				x.skip(cv, path, reason)
+				continue
+			}
+			stmts = append(stmts, &stmt{lines: lines})
+		case direct != nil && direct.Source != "":
+			src, expr, sf, ss, reason := x.resolve(srcs, direct.Source)
+			if reason == "" {
+				var lines []string
+				if lines, reason = x.convert(to, f, ds, expr, sf, ss); reason == "" {
+					stmts = append(stmts, &stmt{source: src, lines: lines})
+					continue
+				}
+			}
+			x.skip(cv, path, reason)
+		case len(nested) > 0:
+			fs := x.side(f.Type.Name)
+			if !ds.message() || f.Type.Kind != ir.Message || f.Repeated || fs == nil {
+				x.skip(cv, path, Unsupported)
+				continue
+			}
+			stmts = append(stmts, &stmt{lines: []string{
+				"if " + to + " == nil {",
+				"\t" + to + " = &" + x.typ(fs)[1:] + "{}",
+				"}",
+			}})
+			stmts = append(stmts, x.assign(cv, srcs, to, path+".", fs, nested, false)...)
+		case direct != nil || implicit:
+			if st := x.implicit(srcs, to, f, ds); st != nil {
+				stmts = append(stmts, st)
+			} else if direct != nil || ds.message() || x.entityField(ds, f) {
+				x.skip(cv, path, Unmapped)
+			}
+		}
+	}
+	for _, name := range order {
+		for _, t := range byField[name] {
+			if t.m.Expression != "" {
+				x.skipExpression(cv, prefix+t.m.Target, t.m.Expression)
+			}
+		}
+		if t := byField[name]; t != nil && t[0].m.Expression == "" {
+			x.skip(cv, prefix+t[0].m.Target, Unresolved)
+		}
+	}
+	return stmts
+}
+
+// entityField reports whether a field of an ent entity is one of its own
+// fields rather than an audit field.
+func (x *mapper) entityField(s *side, f *ir.Field) bool {
+	for _, ef := range s.e.Fields {
+		if ef == f {
+			return true
+		}
+	}
+	return false
+}
+
+// implicit returns the statement setting a target field from the first
+// source field of the same name that converts into it, or nil.
+func (x *mapper) implicit(srcs []*source, to string, f *ir.Field, ds *side) *stmt {
+	for _, src := range srcs {
+		sf := x.field(src.side, f.Name)
+		if sf == nil && !ds.message() {
+			sf = x.field(src.side, ent.Column(f))
+		}
+		if sf == nil {
+			continue
+		}
+		if lines, reason := x.convert(to, f, ds, src.name+"."+src.side.goName(sf), sf, src.side); reason == "" {
+			return &stmt{source: src.name, lines: lines}
+		}
+	}
+	return nil
+}
+
+// resolve returns the source a source path reads, the Go expression of
+// its value, the field and the side of the field. Paths start with the
+// name of a param if there are several.
+func (x *mapper) resolve(srcs []*source, path string) (string, string, *ir.Field, *side, string) {
+	segs := strings.Split(path, ".")
+	var src *source
+	for _, s := range srcs {
+		if len(segs) > 1 && strings.TrimSuffix(s.name, "_") == segs[0] {
+			src, segs = s, segs[1:]
+			break
+		}
+	}
+	for _, s := range srcs {
+		if src == nil && x.field(s.side, segs[0]) != nil {
+			src = s
+		}
+	}
+	if src == nil {
+		return "", "", nil, nil, Unresolved
+	}
+	s, expr := src.side, src.name
+	var f *ir.Field
+	for i, seg := range segs {
+		if f = x.field(s, seg); f == nil {
+			return "", "", nil, nil, Unresolved
+		}
+		if len(segs) == 1 {
+			return src.name, expr + "." + s.goName(f), f, s, ""
+		}
+		if !s.message() {
+			return "", "", nil, nil, Unsupported
+		}
+		expr += ".Get" + s.goName(f) + "()"
+		if i < len(segs)-1 {
+			if f.Type.Kind != ir.Message || f.Repeated {
+				return "", "", nil, nil, Unresolved
+			}
+			if s = x.side(f.Type.Name); s == nil {
+				return "", "", nil, nil, NoMessage
+			}
+		}
+	}
+	return src.name, expr, f, s, ""
+}
+
+// convert returns the statements setting the field df of side ds, to,
+// from the value of the field sf of side ss, from, or the reason it does
+// not convert.
+func (x *mapper) convert(to string, df *ir.Field, ds *side, from string, sf *ir.Field, ss *side) ([]string, string) {
+	if df.Type.Kind == ir.Message || sf.Type.Kind == ir.Message {
+		switch {
+		case df.Type.Kind != sf.Type.Kind || df.Repeated != sf.Repeated:
+			return nil, Unconvertible
+		case df.Type.Name == sf.Type.Name && ds.message() && ss.message():
+			return []string{to + " = " + from}, ""
+		}
+		if cv := x.find(sf.Type.Name, df.Type.Name, df.Repeated); cv != nil {
+			return []string{to + " = c." + cv.Name + "(" + from + ")"}, ""
+		}
+		if cv := x.find(sf.Type.Name, df.Type.Name, false); cv != nil && df.Repeated {
+			return []string{
+				"for _, item := range " + from + " {",
+				"\t" + to + " = append(" + to + ", c." + cv.Name + "(item))",
+				"}",
+			}, ""
+		}
+		return nil, Unconvertible
+	}
+	if df.Repeated || sf.Repeated {
+		if df.Repeated && sf.Repeated && df.Type.Kind == sf.Type.Kind && ds.message() == ss.message() {
+			return []string{to + " = " + from}, ""
+		}
+		return nil, Unconvertible
+	}
+	if !convertible(sf.Type, df.Type) {
+		return nil, Unconvertible
+	}
+	if ss.pointer(sf) && ds.pointer(df) && sf.Type.Kind == df.Type.Kind {
+		return []string{to + " = " + from}, ""
+	}
+	cond, v := "", from
+	if ss.pointer(sf) {
+		cond, v = from+" != nil", "*"+from
+	}
+	switch src, dst := ss.message() && sf.Type.Kind == ir.Time, ds.message() && df.Type.Kind == ir.Time; {
+	case src && !dst:
+		cond, v = from+" != nil", from+".AsTime()"
+	case dst && !src:
+		x.timestamp = true
+		v = "timestamppb.New(" + v + ")"
+	case sf.Type.Kind != df.Type.Kind:
+		v = goType(df.Type) + "(" + v + ")"
+	}
+	return set(to, v, cond, ds.pointer(df)), ""
+}
+
+// set returns the statements setting to to v if cond holds, taking the
+// address of v for pointers.
+func set(to, v, cond string, pointer bool) []string {
+	lines := []string{to + " = " + v}
+	if pointer {
+		lines = []string{"v := " + v, to + " = &v"}
+	}
+	switch {
+	case cond != "":
+		return append(append([]string{"if " + cond + " {"}, indent(lines)...), "}")
+	case pointer:
+		return append(append([]string{"{"}, indent(lines)...), "}")
+	}
+	return lines
+}
+
+func indent(lines []string) []string {
+	res := make([]string, 0, len(lines))
+	for _, l := range lines {
+		res = append(res, "\t"+l)
+	}
+	return res
+}
+
+// constant returns the statements setting the field df of side ds, to, to
+// a constant, or the reason it cannot be.
+func constant(value, to string, df *ir.Field, ds *side) ([]string, string) {
+	if df.Repeated {
+		return nil, Unsupported
+	}
+	v := value
+	switch df.Type.Kind {
+	case ir.String:
+		v = strconv.Quote(value)
+	case ir.Bool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return nil, Unconvertible
+		}
+	case ir.Int32, ir.Int64:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, Unconvertible
+		}
+	case ir.Float32, ir.Float64:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, Unconvertible
+		}
+	default:
+		return nil, Unsupported
+	}
+	if ds.pointer(df) {
+		v = goType(df.Type) + "(" + v + ")"
+	}
+	return set(to, v, "", ds.pointer(df)), ""
+}
@@ -0,0 +1,270 @@
+// Package data generates Kratos data-layer repositories from the
+// intermediate model. A repository implements the CRUD operations a
+// MyBatis-Plus BaseMapper gives a DO, on top of the ent client generated
+// from the schemas of gen/ent.
+package data
+
+import (
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/gen/ent"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+)
+
+const (
+	version = "1.0.0"
+)
+
+// Package is the Go package of the repositories in a Kratos layout. The
+// repositories expect its Data struct to hold the ent client in a db field.
+const Package = "data"
+
+// Generate returns the repository of an entity backed by a table: create,
+// update by id, delete by id, get by id and a paginated list shaped like
+// the DataGrid page messages of ctl. Delete only sets the deleted-at audit
+// field of soft-deleted entities, whose deleted rows get and list skip.
+func Generate(e *ir.Entity, cfg *config.Config) *gen.GeneratedFile {
+	file := gen.NewGeneratedFile()
+	file.Name = FileName(e)
+	r := &repo{
+		e:       e,
+		cfg:     cfg,
+		name:    e.Name + "Repo",
+		pkg:     strings.ToLower(e.Name),
+		id:      "int",
+		soft:    e.SoftDelete && cfg.Audit.DeletedAt != "",
+		updated: cfg.Audit.UpdatedAt,
+	}
+	for _, f := range e.Fields {
+		if f.ID || ent.Column(f) == "id" {
+			r.id = goType(f)
+			continue
+		}
+		r.fields = append(r.fields, f)
+	}
+	r.header(file)
+	r.page(file)
+	r.constructor(file)
+	r.create(file)
+	r.update(file)
+	r.delete(file)
+	r.get(file)
+	r.list(file)
+	file.Format()
+	return file
+}
+
+// FileName returns the name of the repository file of an entity.
+func FileName(e *ir.Entity) string {
+	return strs.SnakeCase(e.Name) + ".go"
+}
+
+// A repo holds what the parts of a repository file share.
+type repo struct {
+	e       *ir.Entity
+	cfg     *config.Config
+	name    string // e.g. DeviceRepo.
+	pkg     string // ent package of the entity, e.g. device.
+	id      string // Go type of the id.
+	soft    bool
+	updated string      // update time audit field, if any.
+	fields  []*ir.Field // fields other than the id.
+}
+
+func (r *repo) header(file *gen.GeneratedFile) {
+	file.P("// Generated by j2g v", version, " from table ", r.e.Table, ".")
+	file.P("")
+	file.P("package " + Package)
+	file.P("")
+	file.P("import (")
+	file.P("\t\"context\"")
+	if r.soft || r.updated != "" {
+		file.P("\t\"time\"")
+	}
+	file.P("")
+	file.P("\t\"github.com/go-kratos/kratos/v2/log\"")
+	file.P("\t" + strconv.Quote(r.cfg.EntPackage()))
+	file.P("\t" + strconv.Quote(path.Join(r.cfg.EntPackage(), r.pkg)))
+	file.P(")")
+	file.P("")
+}
+
+// page writes the page type returned by List, see ir.Page.
+func (r *repo) page(file *gen.GeneratedFile) {
+	page := ir.Page(ir.Ref(r.e.Name))
+	file.P("// " + page.Name + " is a page of " + r.e.Name + ", shaped like the DataGrid replies.")
+	file.P("type " + page.Name + " struct {")
+	for _, f := range page.Fields {
+		typ := "int32"
+		if f.Type.Kind == ir.Message {
+			typ = "[]*ent." + f.Type.Name
+		}
+		file.P("\t" + strs.GoCamelCase(f.Name) + " " + typ)
+	}
+	file.P("}")
+	file.P("")
+}
+
+func (r *repo) constructor(file *gen.GeneratedFile) {
+	file.P("// " + r.name + " stores " + r.e.Name + " in table " + r.e.Table + ".")
+	file.P("type " + r.name + " struct {")
+	file.P("\tdata *Data")
+	file.P("\tlog  *log.Helper")
+	file.P("}")
+	file.P("")
+	file.P("// New" + r.name + " returns a new " + r.name + ".")
+	file.P("func New" + r.name + "(data *Data, logger log.Logger) *" + r.name + " {")
+	file.P("\treturn &" + r.name + "{data: data, log: log.NewHelper(logger)}")
+	file.P("}")
+	file.P("")
+}
+
+func (r *repo) create(file *gen.GeneratedFile) {
+	file.P("// Create inserts a " + r.e.Name + ".")
+	file.P("func (r *" + r.name + ") Create(ctx context.Context, e *ent." + r.e.Name + ") (*ent." + r.e.Name + ", error) {")
+	file.P("\treturn r.data.db." + r.e.Name + ".Create().")
+	r.setters(file)
+	file.P("\t\tSave(ctx)")
+	file.P("}")
+	file.P("")
+}
+
+func (r *repo) update(file *gen.GeneratedFile) {
+	file.P("// Update updates the " + r.e.Name + " with the id of e.")
+	file.P("func (r *" + r.name + ") Update(ctx context.Context, e *ent." + r.e.Name + ") (*ent." + r.e.Name + ", error) {")
+	file.P("\treturn r.data.db." + r.e.Name + ".UpdateOneID(e.ID).")
+	if r.soft {
+		file.P("\t\tWhere(" + r.pkg + "." + Pascal(r.cfg.Audit.DeletedAt) + "IsNil()).")
+	}
+	r.setters(file)
+	if r.updated != "" {
+		file.P("\t\tSet" + Pascal(r.updated) + "(time.Now()).")
+	}
+	file.P("\t\tSave(ctx)")
+	file.P("}")
+	file.P("")
+}
+
+// setters writes the builder calls setting the fields of e.
+func (r *repo) setters(file *gen.GeneratedFile) {
+	for _, f := range r.fields {
+		name := Pascal(ent.Column(f))
+		if f.Nillable {
+			file.P("\t\tSetNillable" + name + "(e." + name + ").")
+		} else {
+			file.P("\t\tSet" + name + "(e." + name + ").")
+		}
+	}
+}
+
+func (r *repo) delete(file *gen.GeneratedFile) {
+	if r.soft {
+		deleted := Pascal(r.cfg.Audit.DeletedAt)
+		file.P("// Delete marks the " + r.e.Name + " as deleted.")
+		file.P("func (r *" + r.name + ") Delete(ctx context.Context, id " + r.id + ") error {")
+		file.P("\treturn r.data.db." + r.e.Name + ".UpdateOneID(id).")
+		file.P("\t\tWhere(" + r.pkg + "." + deleted + "IsNil()).")
+		file.P("\t\tSet" + deleted + "(time.Now()).")
+		file.P("\t\tExec(ctx)")
+		file.P("}")
+		file.P("")
+		return
+	}
+	file.P("// Delete deletes the " + r.e.Name + ".")
+	file.P("func (r *" + r.name + ") Delete(ctx context.Context, id " + r.id + ") error {")
+	file.P("\treturn r.data.db." + r.e.Name + ".DeleteOneID(id).Exec(ctx)")
+	file.P("}")
+	file.P("")
+}
+
+func (r *repo) get(file *gen.GeneratedFile) {
+	file.P("// Get returns the " + r.e.Name + " with the id.")
+	file.P("func (r *" + r.name + ") Get(ctx context.Context, id " + r.id + ") (*ent." + r.e.Name + ", error) {")
+	if r.soft {
+		file.P("\treturn r.data.db." + r.e.Name + ".Query().")
+		file.P("\t\tWhere(" + r.pkg + ".ID(id), " + r.pkg + "." + Pascal(r.cfg.Audit.DeletedAt) + "IsNil()).")
+		file.P("\t\tOnly(ctx)")
+	} else {
+		file.P("\treturn r.data.db." + r.e.Name + ".Get(ctx, id)")
+	}
+	file.P("}")
+	file.P("")
+}
+
+func (r *repo) list(file *gen.GeneratedFile) {
+	page := ir.Page(ir.Ref(r.e.Name)).Name
+	file.P("// List returns the page-th page, counted from 1, of size " + r.e.Name + ", ordered by id.")
+	file.P("func (r *" + r.name + ") List(ctx context.Context, page, size int) (*" + page + ", error) {")
+	file.P("\tif page < 1 {")
+	file.P("\t\tpage = 1")
+	file.P("\t}")
+	file.P("\tif size < 1 {")
+	file.P("\t\tsize = 10")
+	file.P("\t}")
+	file.P("\tquery := r.data.db." + r.e.Name + ".Query()")
+	if r.soft {
+		file.P("\tquery = query.Where(" + r.pkg + "." + Pascal(r.cfg.Audit.DeletedAt) + "IsNil())")
+	}
+	file.P("\ttotal, err := query.Clone().Count(ctx)")
+	file.P("\tif err != nil {")
+	file.P("\t\treturn nil, err")
+	file.P("\t}")
+	file.P("\toffset := (page - 1) * size")
+	file.P("\trows, err := query.Order(ent.Asc(" + r.pkg + ".FieldID)).Offset(offset).Limit(size).All(ctx)")
+	file.P("\tif err != nil {")
+	file.P("\t\treturn nil, err")
+	file.P("\t}")
+	file.P("\tpages := (total + size - 1) / size")
+	file.P("\tres := &" + page + "{" + strs.GoCamelCase(r.e.Name) + ": rows, Pages: int32(pages), Offset: int32(offset), Total: int32(total)}")
+	file.P("\tif page > 1 {")
+	file.P("\t\tres.PrePage = int32(page - 1)")
+	file.P("\t}")
+	file.P("\tif page < pages {")
+	file.P("\t\tres.NextPage = int32(page + 1)")
+	file.P("\t}")
+	file.P("\treturn res, nil")
+	file.P("}")
+}
+
+// goType returns the Go type ent generates for a field.
+func goType(f *ir.Field) string {
+	switch f.Type.Kind {
+	case ir.Int32, ir.Int64, ir.Float32, ir.Float64, ir.Bool, ir.String:
+		return string(f.Type.Kind)
+	case ir.Bytes:
+		return "[]byte"
+	case ir.Time:
+		return "time.Time"
+	}
+	return "string"
+}
+
+// acronyms are the words ent writes in upper case in Go names.
+var acronyms = map[string]bool{
+	"ACL": true, "API": true, "ASCII": true, "AWS": true, "CPU": true, "CSS": true, "DNS": true,
+	"EOF": true, "GB": true, "GUID": true, "HCL": true, "HTML": true, "HTTP": true, "HTTPS": true,
+	"ID": true, "IP": true, "JSON": true, "KB": true, "LHS": true, "MAC": true, "MB": true,
+	"QPS": true, "RAM": true, "RHS": true, "RPC": true, "SLA": true, "SMTP": true, "SQL": true,
+	"SSH": true, "SSO": true, "TCP": true, "TLS": true, "TTL": true, "UDP": true, "UI": true,
+	"UID": true, "URI": true, "URL": true, "UTF8": true, "UUID": true, "VM": true, "XML": true,
+	"XMPP": true, "XSRF": true, "XSS": true,
+}
+
+// Pascal returns the Go name ent generates for a field name, e.g. "UserID"
+// for user_id.
+func Pascal(s string) string {
+	words := strings.Split(s, "_")
+	for i, w := range words {
+		if upper := strings.ToUpper(w); acronyms[upper] {
+			words[i] = upper
+		} else if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, "")
+}
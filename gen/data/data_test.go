@@ -0,0 +1,45 @@
+package data
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/ir"
+)
+
+func TestGenerate(t *testing.T) {
+	cfg := config.Default()
+	cfg.Go.Module = "example.com/demo"
+	e := &ir.Entity{
+		Name:  "Device",
+		Table: "device",
+		Fields: []*ir.Field{
+			{Name: "id", Type: ir.Scalar(ir.Int64), ID: true},
+			{Name: "userId", Column: "user_id", Type: ir.Scalar(ir.Int64)},
+			{Name: "seenAt", Column: "seen_at", Type: ir.Scalar(ir.Time), Nillable: true},
+		},
+	}
+	hard := string(Generate(e, cfg).Content())
+	e.SoftDelete = true
+	soft := string(Generate(e, cfg).Content())
+	for _, tc := range []struct {
+		src, want string
+	}{
+		{hard, `"example.com/demo/internal/data/ent/device"`},
+		{hard, "SetUserID(e.UserID)."},
+		{hard, "SetNillableSeenAt(e.SeenAt)."},
+		{hard, "func (r *DeviceRepo) Delete(ctx context.Context, id int64) error {"},
+		{hard, "DeleteOneID(id).Exec(ctx)"},
+		{hard, "func (r *DeviceRepo) List(ctx context.Context, page, size int) (*PageDevice, error) {"},
+		{soft, "SetDeletedAt(time.Now())."},
+		{soft, "Where(device.ID(id), device.DeletedAtIsNil())."},
+	} {
+		if !strings.Contains(tc.src, tc.want) {
+			t.Errorf("generated repository does not contain %q", tc.want)
+		}
+	}
+	if strings.Contains(soft, "DeleteOneID") {
+		t.Error("soft-deleted entity is deleted for real")
+	}
+}
@@ -0,0 +1,233 @@
+// Package ent generates ent schema files from the intermediate model.
+package ent
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+)
+
+const (
+	version = "1.0.0"
+)
+
+// auditFields returns the fields appended to every schema. The front-ends
+// drop the corresponding source columns (see config.Audit).
+func auditFields(cfg *config.Config) []*ir.Field {
+	return []*ir.Field{
+		{Name: cfg.Audit.CreatedAt, Comment: "创建时间", Type: ir.Scalar(ir.Time)},
+		{Name: cfg.Audit.UpdatedAt, Comment: "修改时间", Type: ir.Scalar(ir.Time)},
+		{Name: cfg.Audit.DeletedAt, Comment: "删除", Type: ir.Scalar(ir.Time), Nillable: true},
+	}
+}
+
+// Generate returns the ent schema file of an entity.
+func Generate(e *ir.Entity, cfg *config.Config) *gen.GeneratedFile {
+	file := gen.NewGeneratedFile()
+	file.Name = FileName(e)
+	header(file, e, cfg)
+	structer(file, e)
+	for _, f := range Fields(e, cfg) {
+		field(file, f)
+	}
+	file.P("\t}")
+	file.P("}")
+	file.P("")
+	index(file, e)
+	annotations(file, e)
+	return file
+}
+
+// Fields returns the fields of the schema of an entity: its own fields
+// followed by the audit fields that are configured.
+func Fields(e *ir.Entity, cfg *config.Config) []*ir.Field {
+	fields := append([]*ir.Field{}, e.Fields...)
+	for _, f := range auditFields(cfg) {
+		if f.Name != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// FileName returns the name of the schema file of an entity.
+func FileName(e *ir.Entity) string {
+	return strs.SnakeCase(e.Name) + ".go"
+}
+
+// TypeName returns the name of the ent field builder for a type, e.g.
+// "Int64" for field.Int64.
+func TypeName(t ir.Type) string {
+	switch t.Kind {
+	case ir.Int32:
+		return "Int32"
+	case ir.Int64:
+		return "Int64"
+	case ir.Float32:
+		return "Float32"
+	case ir.Float64:
+		return "Float"
+	case ir.Bool:
+		return "Bool"
+	case ir.Bytes:
+		return "Bytes"
+	case ir.Time:
+		return "Time"
+	}
+	return "String"
+}
+
+// Column returns the column name of a field.
+func Column(f *ir.Field) string {
+	if f.Column != "" {
+		return f.Column
+	}
+	return strs.SnakeCase(f.Name)
+}
+
+func header(file *gen.GeneratedFile, e *ir.Entity, cfg *config.Config) {
+	file.P("// Code generated by j2g. DO NOT EDIT.")
+	file.P("// versions:")
+	file.P("// - j2g v", version)
+	file.P("package " + cfg.Go.SchemaPackage)
+	file.P("")
+	file.P("import (")
+	file.P("\t\"time\"")
+	file.P("")
+	file.P("\t\"entgo.io/ent\"")
+	file.P("\t\"entgo.io/ent/dialect\"")
+	if renamed(e) {
+		file.P("\t\"entgo.io/ent/dialect/entsql\"")
+		file.P("\t\"entgo.io/ent/schema\"")
+	}
+	file.P("\t\"entgo.io/ent/schema/field\"")
+	if len(e.Indexes) > 0 {
+		file.P("\t\"entgo.io/ent/schema/index\"")
+	}
+	file.P(")")
+	file.P("")
+}
+
+func structer(file *gen.GeneratedFile, e *ir.Entity) {
+	if e.Comment != "" {
+		file.P("// " + e.Comment)
+	}
+	file.P("// " + e.Name + " holds the schema definition for the " + e.Name + " entity.")
+	file.P("type " + e.Name + " struct {")
+	file.P("\tent.Schema")
+	file.P("}")
+	file.P("")
+	file.P("// Fields of the " + e.Name + ".")
+	file.P("func (" + e.Name + ") Fields() []ent.Field {")
+	file.P("\treturn []ent.Field{")
+}
+
+func field(file *gen.GeneratedFile, f *ir.Field) {
+	name := strconv.Quote(Column(f))
+	comment := ".Comment(" + strconv.Quote(f.Comment) + ")"
+	typ := TypeName(f.Type)
+	if f.ID {
+		file.P("\t\tfield." + typ + "(" + name + ")" + comment + ",")
+		return
+	}
+	if f.Type.Kind == ir.Time {
+		file.P("\t\tfield." + typ + "(" + name + ").")
+		if f.Nillable {
+			file.P("\t\t\tOptional().Nillable().")
+		} else {
+			file.P("\t\t\tDefault(time.Now).")
+		}
+		file.P("\t\t\tSchemaType(map[string]string{")
+		file.P("\t\t\t\tdialect.MySQL:  \"datetime\",")
+		file.P("\t\t\t\tdialect.SQLite: \"datetime\",")
+		file.P("\t\t\t})" + comment + ",")
+		return
+	}
+	buf := "\t\tfield." + typ + "(" + name + ")"
+	if f.Nillable {
+		buf += ".Optional().Nillable()"
+	}
+	if f.Default != nil {
+		if value, ok := defaultValue(f.Type, *f.Default); ok {
+			buf += ".Default(" + value + ")"
+		}
+	}
+	if f.MaxLen > 0 {
+		buf += ".MaxLen(" + strconv.Itoa(f.MaxLen) + ")"
+	}
+	file.P(buf + comment + ",")
+}
+
+// defaultValue returns the Go literal of a default value.
+func defaultValue(t ir.Type, value string) (string, bool) {
+	switch t.Kind {
+	case ir.Int32, ir.Int64:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return "", false
+		}
+		return value, true
+	case ir.Float32, ir.Float64:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", false
+		}
+		return value, true
+	case ir.Bool:
+		switch strings.ToLower(value) {
+		case "1", "true", "b'1'":
+			return "true", true
+		case "0", "false", "b'0'":
+			return "false", true
+		}
+		return "", false
+	case ir.String:
+		return strconv.Quote(value), true
+	}
+	return "", false
+}
+
+func index(file *gen.GeneratedFile, e *ir.Entity) {
+	if len(e.Indexes) == 0 {
+		return
+	}
+	file.P("// Indexes of the " + e.Name + ".")
+	file.P("func (" + e.Name + ") Indexes() []ent.Index {")
+	file.P("\treturn []ent.Index{")
+	for _, idx := range e.Indexes {
+		cols := make([]string, 0, len(idx.Columns))
+		for _, c := range idx.Columns {
+			cols = append(cols, strconv.Quote(c))
+		}
+		buf := "\t\tindex.Fields(" + strings.Join(cols, ", ") + ")"
+		if idx.Unique {
+			buf += ".Unique()"
+		}
+		buf += ".StorageKey(" + strconv.Quote(idx.Name) + "),"
+		file.P(buf)
+	}
+	file.P("\t}")
+	file.P("}")
+	file.P("")
+}
+
+// renamed reports whether the schema name of e no longer matches its table,
+// e.g. after stripping the table prefix t_.
+func renamed(e *ir.Entity) bool {
+	return e.Table != "" && strs.SnakeCase(e.Name) != e.Table
+}
+
+func annotations(file *gen.GeneratedFile, e *ir.Entity) {
+	if !renamed(e) {
+		return
+	}
+	file.P("// Annotations of the " + e.Name + ".")
+	file.P("func (" + e.Name + ") Annotations() []schema.Annotation {")
+	file.P("\treturn []schema.Annotation{")
+	file.P("\t\tentsql.Annotation{Table: " + strconv.Quote(e.Table) + "},")
+	file.P("\t}")
+	file.P("}")
+	file.P("")
+}
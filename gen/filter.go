@@ -0,0 +1,66 @@
+package gen
+
+import (
+	"path"
+	"strings"
+)
+
+// A Filter selects source files by glob patterns, which are matched against
+// the slash-separated path of a file relative to its source root. "**"
+// matches any number of directories, and a pattern without "/" matches the
+// base name of the file, e.g. "*VO.java" or "**/dto/*.java".
+type Filter struct {
+	Include []string // if not empty, only matching files are read.
+	Exclude []string // matching files are skipped.
+}
+
+// Match reports whether the file at rel passes the filter. A nil filter
+// passes every file.
+func (f *Filter) Match(rel string) bool {
+	if f == nil {
+		return true
+	}
+	for _, pattern := range f.Exclude {
+		if matchGlob(pattern, rel) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, pattern := range f.Include {
+		if matchGlob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
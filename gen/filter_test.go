@@ -0,0 +1,30 @@
+package gen
+
+import "testing"
+
+func TestFilter(t *testing.T) {
+	f := &Filter{
+		Include: []string{"**/vo/*.java", "*Request.java"},
+		Exclude: []string{"**/internal/**"},
+	}
+	tests := []struct {
+		rel  string
+		want bool
+	}{
+		{"vo/DeviceVO.java", true},
+		{"com/example/vo/DeviceVO.java", true},
+		{"com/example/dto/DeviceDTO.java", false},
+		{"com/example/request/DeviceRequest.java", true},
+		{"com/example/internal/vo/DeviceVO.java", false},
+		{"com/example/vo/sub/DeviceVO.java", false},
+	}
+	for _, tc := range tests {
+		if got := f.Match(tc.rel); got != tc.want {
+			t.Errorf("Match(%q) = %v, want %v", tc.rel, got, tc.want)
+		}
+	}
+	var all *Filter
+	if !all.Match("any/File.java") {
+		t.Error("nil filter rejected a file")
+	}
+}
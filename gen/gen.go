@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/luobote55/java2go/internal/strs"
-	"github.com/pkg/errors"
+	"go/format"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 	"io/ioutil"
@@ -13,6 +13,10 @@ import (
 	"strings"
 )
 
+// Version is the version of java2go. It is part of the cache key, so that
+// a new version regenerates every output.
+const Version = "1.0.0"
+
 // A GoIdent is a Go identifier, consisting of a name and import path.
 // The name is a single identifier and may not be a dot-qualified selector.
 type GoIdent struct {
@@ -95,20 +99,9 @@ func (loc Location) appendPath(num protoreflect.FieldNumber, idx int) Location {
 	return loc
 }
 
-type GeneratedIndex struct {
-	StorageKey string
-	Fields     []string
-	UNIQUE     bool
-}
-
 type GeneratedFile struct {
-	ApiModel         []string
-	ServiceName      string
-	TableName        string
-	StructName       string
-	Url              string
-	Urls             []string
-	Index            []GeneratedIndex
+	Name             string   // output path, relative to the output root.
+	Sources          []string // paths of the sources it is generated from, its own first; empty if any.
 	goImportPath     GoImportPath
 	buf              bytes.Buffer
 	packageNames     map[GoImportPath]GoPackageName
@@ -119,12 +112,7 @@ type GeneratedFile struct {
 
 func NewGeneratedFile() *GeneratedFile {
 	return &GeneratedFile{
-		ApiModel:         nil,
-		TableName:        "",
-		StructName:       "",
-		Url:              "",
-		Urls:             nil,
-		Index:            []GeneratedIndex{},
+		Name:             "",
 		goImportPath:     "",
 		buf:              bytes.Buffer{},
 		packageNames:     nil,
@@ -174,24 +162,21 @@ func (g *GeneratedFile) WriteFile(filepath string) error {
 	return ioutil.WriteFile(filepath, g.buf.Bytes(), 0644)
 }
 
-func (g *GeneratedFile) Replace(src, des string) error {
-	g.buf = *bytes.NewBufferString(strings.Replace(g.buf.String(), src, des, -1))
-	return nil
+// Content returns the generated output.
+func (g *GeneratedFile) Content() []byte {
+	return g.buf.Bytes()
 }
 
-func (g *GeneratedFile) SetUrl(url string) error {
-	g.Url = strings.Replace(url, "\"", "", -1)
-	urls := strings.Split(g.Url, "/")
-	if urls[0] == "" {
-		g.Urls = urls[1:]
-	} else {
-		g.Urls = urls
+// Format gofmts the output of a Go file. Output that does not parse is left
+// as is, so that the problem shows in the written file.
+func (g *GeneratedFile) Format() {
+	if b, err := format.Source(g.buf.Bytes()); err == nil {
+		g.buf = *bytes.NewBuffer(b)
 	}
-	if len(g.Urls) == 0 {
-		fmt.Println("url为空:" + g.ApiModel[0])
-		return errors.New("url为空:" + g.ApiModel[0])
-	}
-	g.StructName = strs.GoCamelCase(strings.Replace(strings.Replace(g.Urls[len(g.Urls)-1], "-", "", -1), "\"", "", -1))
+}
+
+func (g *GeneratedFile) Replace(src, des string) error {
+	g.buf = *bytes.NewBufferString(strings.Replace(g.buf.String(), src, des, -1))
 	return nil
 }
 
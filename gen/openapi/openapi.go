@@ -0,0 +1,372 @@
+// Package openapi generates an OpenAPI 3 document from the services of the
+// intermediate model.
+//
+// The document describes the HTTP API of the protobuf files generated for
+// the same services, as served by Kratos: POST, PUT and PATCH endpoints take
+// their request message as JSON body, the other endpoints take its fields
+// as path and query parameters, and every endpoint replies with its reply message.
+// Property names are the protobuf JSON names.
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Version is the OpenAPI version of the generated documents.
+	Version = "3.0.3"
+	// FileName is the default name of the generated document.
+	FileName = "openapi.yaml"
+)
+
+// A Document is an OpenAPI document, reduced to the parts generated here.
+type Document struct {
+	OpenAPI    string              `json:"openapi" yaml:"openapi"`
+	Info       Info                `json:"info" yaml:"info"`
+	Tags       []*Tag              `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths" yaml:"paths"`
+	Components Components          `json:"components" yaml:"components"`
+}
+
+// Info is the metadata of the API.
+type Info struct {
+	Title   string `json:"title" yaml:"title"`
+	Version string `json:"version" yaml:"version"`
+}
+
+// A Tag groups the operations of a service.
+type Tag struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// A PathItem holds the operations of a path by lower-case HTTP method.
+type PathItem map[string]*Operation
+
+// An Operation is an endpoint.
+type Operation struct {
+	Tags        []string             `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Summary     string               `json:"summary,omitempty" yaml:"summary,omitempty"`
+	OperationID string               `json:"operationId" yaml:"operationId"`
+	Parameters  []*Parameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses" yaml:"responses"`
+}
+
+// A Parameter is a path or query parameter of an operation.
+type Parameter struct {
+	Name        string  `json:"name" yaml:"name"`
+	In          string  `json:"in" yaml:"in"`
+	Description string  `json:"description,omitempty" yaml:"description,omitempty"`
+	Required    bool    `json:"required,omitempty" yaml:"required,omitempty"`
+	Schema      *Schema `json:"schema" yaml:"schema"`
+}
+
+// A RequestBody is the JSON body of an operation.
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty" yaml:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content" yaml:"content"`
+}
+
+// A Response is a reply of an operation.
+type Response struct {
+	Description string                `json:"description" yaml:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+// A MediaType holds the schema of a body.
+type MediaType struct {
+	Schema *Schema `json:"schema" yaml:"schema"`
+}
+
+// Components holds the schemas of the messages.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+}
+
+// A Schema is a type: a reference to a message schema, an object, an
+// array or a scalar.
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty" yaml:"type,omitempty"`
+	Format      string             `json:"format,omitempty" yaml:"format,omitempty"`
+	Description string             `json:"description,omitempty" yaml:"description,omitempty"`
+	Items       *Schema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty" yaml:"properties,omitempty"`
+}
+
+// Generate returns the OpenAPI document of the services of m. The document
+// is written as JSON if name ends in .json and as YAML otherwise.
+func Generate(m *ir.Model, cfg *config.Config, name string) (*gen.GeneratedFile, error) {
+	doc := Build(m, cfg)
+	var (
+		b   []byte
+		err error
+	)
+	if strings.EqualFold(path.Ext(name), ".json") {
+		b, err = json.MarshalIndent(doc, "", "  ")
+		b = append(b, '\n')
+	} else {
+		buf := new(bytes.Buffer)
+		enc := yaml.NewEncoder(buf)
+		enc.SetIndent(2)
+		if err = enc.Encode(doc); err == nil {
+			err = enc.Close()
+		}
+		b = buf.Bytes()
+	}
+	if err != nil {
+		return nil, err
+	}
+	file := gen.NewGeneratedFile()
+	file.Name = name
+	file.P(strings.TrimSuffix(string(b), "\n"))
+	return file, nil
+}
+
+// Build returns the OpenAPI document of the services of m. Endpoints
+// without a path are not part of the HTTP API and are left out.
+func Build(m *ir.Model, cfg *config.Config) *Document {
+	title := cfg.Go.Module
+	if title == "" {
+		title = "API"
+	}
+	doc := &Document{
+		OpenAPI:    Version,
+		Info:       Info{Title: title, Version: "1.0.0"},
+		Paths:      make(map[string]PathItem),
+		Components: Components{Schemas: make(map[string]*Schema)},
+	}
+	s := newSchemas(m.Services)
+	for _, msg := range s.list {
+		doc.Components.Schemas[msg.key] = s.object(msg.svc, msg.entity)
+	}
+	for _, svc := range m.Services {
+		doc.Tags = append(doc.Tags, &Tag{Name: svc.Name, Description: svc.Comment})
+		for _, ep := range svc.Endpoints {
+			if ep.Path == "" {
+				continue
+			}
+			p := svc.Path + ep.Path
+			if doc.Paths[p] == nil {
+				doc.Paths[p] = make(PathItem)
+			}
+			doc.Paths[p][ep.Method] = s.operation(svc, ep, p)
+		}
+	}
+	return doc
+}
+
+// A message is an entity defined by a service, and the key of its schema.
+type message struct {
+	key    string
+	svc    *ir.Service
+	entity *ir.Entity
+}
+
+// schemas names the schemas of the messages of the services. Messages are
+// defined per proto package, so two services may each define a message of
+// the same name; the same fields share a schema, otherwise the schema of
+// the later one is qualified with its service name.
+type schemas struct {
+	list   []*message
+	byName map[string]*message
+	local  map[*ir.Service]map[string]*message
+}
+
+func newSchemas(services []*ir.Service) *schemas {
+	s := &schemas{
+		byName: make(map[string]*message),
+		local:  make(map[*ir.Service]map[string]*message),
+	}
+	for _, svc := range services {
+		s.local[svc] = make(map[string]*message)
+		for _, e := range svc.Messages {
+			msg := s.byName[e.Name]
+			if msg == nil || !reflect.DeepEqual(msg.entity.Fields, e.Fields) {
+				key := e.Name
+				if msg != nil {
+					key = svc.Name + "." + e.Name
+				}
+				msg = &message{key: key, svc: svc, entity: e}
+				s.list = append(s.list, msg)
+				if s.byName[e.Name] == nil {
+					s.byName[e.Name] = msg
+				}
+			}
+			s.local[svc][e.Name] = msg
+		}
+	}
+	return s
+}
+
+// find returns the message a service refers to by name, or nil.
+func (s *schemas) find(svc *ir.Service, name string) *message {
+	if msg, ok := s.local[svc][name]; ok {
+		return msg
+	}
+	return s.byName[name]
+}
+
+// ref returns the schema referring to the named message.
+func (s *schemas) ref(svc *ir.Service, name string) *Schema {
+	if msg := s.find(svc, name); msg != nil {
+		name = msg.key
+	}
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// object returns the schema of a message.
+func (s *schemas) object(svc *ir.Service, e *ir.Entity) *Schema {
+	obj := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	if e.Comment != e.Name {
+		obj.Description = e.Comment
+	}
+	for _, f := range e.Fields {
+		prop := s.field(svc, f)
+		if prop.Ref == "" {
+			prop.Description = description(f)
+		}
+		obj.Properties[JSONName(f.Name)] = prop
+	}
+	return obj
+}
+
+// field returns the schema of a field.
+func (s *schemas) field(svc *ir.Service, f *ir.Field) *Schema {
+	var typ *Schema
+	if f.Type.Kind == ir.Message {
+		typ = s.ref(svc, f.Type.Name)
+	} else {
+		typ = Scalar(f.Type)
+	}
+	if f.Repeated {
+		return &Schema{Type: "array", Items: typ}
+	}
+	return typ
+}
+
+// Scalar returns the schema of a scalar type. 64-bit integers are numbers
+// here although protobuf JSON writes them as strings, which clients accept
+// either way.
+func Scalar(t ir.Type) *Schema {
+	switch t.Kind {
+	case ir.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case ir.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case ir.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case ir.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case ir.Bool:
+		return &Schema{Type: "boolean"}
+	case ir.Bytes:
+		return &Schema{Type: "string", Format: "byte"}
+	case ir.Time:
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	return &Schema{Type: "string"}
+}
+
+// JSONName returns the protobuf JSON name of a field, e.g. "deviceId".
+func JSONName(field string) string {
+	return strs.JSONCamelCase(strs.LetterCamelCase(field))
+}
+
+var pathParamRe = regexp.MustCompile(`\{([^}=]+)`)
+
+// operation returns the operation of an endpoint served at p.
+func (s *schemas) operation(svc *ir.Service, ep *ir.Endpoint, p string) *Operation {
+	op := &Operation{
+		Tags:        []string{svc.Name},
+		Summary:     ep.Comment,
+		OperationID: svc.Name + "_" + ep.Name,
+		Responses:   make(map[string]*Response),
+	}
+	req := s.find(svc, ep.Request)
+	fields := make(map[string]*ir.Field)
+	if req != nil {
+		for _, f := range req.entity.Fields {
+			fields[JSONName(f.Name)] = f
+		}
+	}
+	inPath := make(map[string]bool)
+	for _, match := range pathParamRe.FindAllStringSubmatch(p, -1) {
+		name := match[1]
+		param := &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}}
+		if f := fields[JSONName(name)]; f != nil && f.Type.Kind != ir.Message && !f.Repeated {
+			param.Schema = Scalar(f.Type)
+			param.Description = description(f)
+		}
+		inPath[JSONName(name)] = true
+		op.Parameters = append(op.Parameters, param)
+	}
+	if ep.Body() {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: s.ref(svc, ep.Request)}},
+		}
+	} else if req != nil {
+		s.query(op, req, "", inPath, map[*ir.Entity]bool{})
+	}
+	op.Responses["200"] = &Response{
+		Description: "OK",
+		Content:     map[string]*MediaType{"application/json": {Schema: s.ref(svc, ep.Reply)}},
+	}
+	return op
+}
+
+// query adds the fields of a request message not bound to the path as
+// query parameters. Fields of nested messages are named by their dotted
+// path, e.g. "page.size"; repeated messages cannot be sent in a query and
+// are left out.
+func (s *schemas) query(op *Operation, msg *message, prefix string, inPath map[string]bool, seen map[*ir.Entity]bool) {
+	if seen[msg.entity] {
+		return
+	}
+	seen[msg.entity] = true
+	defer delete(seen, msg.entity)
+	for _, f := range msg.entity.Fields {
+		name := prefix + JSONName(f.Name)
+		if inPath[name] {
+			continue
+		}
+		if f.Type.Kind == ir.Message {
+			if nested := s.find(msg.svc, f.Type.Name); nested != nil && !f.Repeated {
+				s.query(op, nested, name+".", inPath, seen)
+			}
+			continue
+		}
+		schema := Scalar(f.Type)
+		if f.Repeated {
+			schema = &Schema{Type: "array", Items: schema}
+		}
+		op.Parameters = append(op.Parameters, &Parameter{
+			Name:        name,
+			In:          "query",
+			Description: description(f),
+			Schema:      schema,
+		})
+	}
+}
+
+// description returns the comment of a field unless it merely repeats the
+// field name.
+func description(f *ir.Field) string {
+	if f.Comment == f.Name {
+		return ""
+	}
+	return f.Comment
+}
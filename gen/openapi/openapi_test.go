@@ -0,0 +1,110 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/ir"
+)
+
+func TestBuild(t *testing.T) {
+	vo := &ir.Entity{
+		Name:    "DeviceVO",
+		Comment: "设备",
+		Fields: []*ir.Field{
+			{Name: "id", Type: ir.Scalar(ir.Int64)},
+			{Name: "device_name", Comment: "设备名称", Type: ir.Scalar(ir.String)},
+			{Name: "createTime", Type: ir.Scalar(ir.Time)},
+		},
+	}
+	page := ir.Page(ir.Ref(vo.Name))
+	svc := &ir.Service{
+		Name: "Device",
+		Path: "/device",
+		Endpoints: []*ir.Endpoint{
+			{Name: "GetDevice", Comment: "查询设备", Method: "get", Path: "/{id}", Request: "GetDeviceRequest", Reply: "GetDeviceReply"},
+			{Name: "ListDevice", Method: "get", Path: "/list", Request: "ListDeviceRequest", Reply: "ListDeviceReply"},
+			{Name: "SaveDevice", Method: "post", Path: "/save", Request: vo.Name, Reply: "SaveDeviceReply"},
+			{Name: "Internal", Request: vo.Name, Reply: "SaveDeviceReply"},
+		},
+		Messages: []*ir.Entity{
+			vo,
+			page,
+			{Name: "GetDeviceRequest", Comment: "GetDeviceRequest", Fields: []*ir.Field{{Name: "id", Type: ir.Scalar(ir.Int64)}}},
+			{Name: "GetDeviceReply", Comment: "GetDeviceReply", Fields: []*ir.Field{{Name: vo.Name, Type: ir.Ref(vo.Name)}}},
+			{Name: "ListDeviceRequest", Comment: "ListDeviceRequest", Fields: []*ir.Field{
+				{Name: "page", Type: ir.Scalar(ir.Int32)},
+				{Name: "filter", Type: ir.Ref(vo.Name)},
+				{Name: "ids", Type: ir.Scalar(ir.Int64), Repeated: true},
+			}},
+			{Name: "ListDeviceReply", Comment: "ListDeviceReply", Fields: []*ir.Field{{Name: page.Name, Type: ir.Ref(page.Name)}}},
+			{Name: "SaveDeviceReply", Comment: "SaveDeviceReply", Fields: []*ir.Field{{Name: "data", Type: ir.Scalar(ir.String)}}},
+		},
+	}
+	doc := Build(&ir.Model{Services: []*ir.Service{svc}}, config.Default())
+
+	get := doc.Paths["/device/{id}"]["get"]
+	if get == nil || get.Summary != "查询设备" || get.OperationID != "Device_GetDevice" {
+		t.Fatalf("get operation = %+v", get)
+	}
+	if len(get.Parameters) != 1 || get.Parameters[0].In != "path" || get.Parameters[0].Schema.Format != "int64" {
+		t.Errorf("get parameters = %+v", get.Parameters)
+	}
+	var query []string
+	for _, p := range doc.Paths["/device/list"]["get"].Parameters {
+		query = append(query, p.In+":"+p.Name)
+	}
+	if got, want := strings.Join(query, ","), "query:page,query:filter.id,query:filter.deviceName,query:filter.createTime,query:ids"; got != want {
+		t.Errorf("list parameters = %s, want %s", got, want)
+	}
+	save := doc.Paths["/device/save"]["post"]
+	if save.RequestBody == nil || save.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/DeviceVO" {
+		t.Errorf("save request body = %+v", save.RequestBody)
+	}
+	if len(doc.Paths) != 3 {
+		t.Errorf("got %d paths, want 3", len(doc.Paths))
+	}
+
+	schema := doc.Components.Schemas["DeviceVO"]
+	if schema.Description != "设备" || schema.Properties["deviceName"].Description != "设备名称" {
+		t.Errorf("DeviceVO = %+v", schema)
+	}
+	if schema.Properties["createTime"].Format != "date-time" {
+		t.Errorf("createTime = %+v", schema.Properties["createTime"])
+	}
+	pageSchema := doc.Components.Schemas["PageDeviceVO"]
+	if pageSchema == nil || pageSchema.Properties["deviceVO"].Items.Ref != "#/components/schemas/DeviceVO" || pageSchema.Properties["total"].Type != "integer" {
+		t.Errorf("PageDeviceVO = %+v", pageSchema)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	m := &ir.Model{Services: []*ir.Service{{
+		Name:      "Ping",
+		Endpoints: []*ir.Endpoint{{Name: "Ping", Method: "get", Path: "/ping", Request: "PingRequest", Reply: "PingReply"}},
+		Messages:  []*ir.Entity{{Name: "PingRequest"}, {Name: "PingReply"}},
+	}}}
+	cfg := config.Default()
+	yml, err := Generate(m, cfg, "openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"openapi: 3.0.3\n", "  /ping:\n", "$ref: '#/components/schemas/PingReply'"} {
+		if !strings.Contains(string(yml.Content()), want) {
+			t.Errorf("YAML document does not contain %q", want)
+		}
+	}
+	js, err := Generate(m, cfg, "api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc Document
+	if err = json.Unmarshal(js.Content(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Paths["/ping"]["get"].OperationID != "Ping_Ping" {
+		t.Errorf("JSON document = %s", js.Content())
+	}
+}
@@ -0,0 +1,217 @@
+package gen
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+)
+
+// A Source is a named input of a converter, e.g. a .java or .sql file.
+type Source struct {
+	Path string // name used for diagnostics and output file names.
+	R    io.Reader
+}
+
+// A Diagnostic is a problem found while converting a source.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+	}
+	if d.File != "" {
+		return d.File + ": " + d.Message
+	}
+	return d.Message
+}
+
+// An Output collects the files and diagnostics of a converter run.
+type Output struct {
+	Files       []*GeneratedFile
+	Diagnostics []Diagnostic
+}
+
+// Add appends a generated file to the output.
+func (o *Output) Add(file *GeneratedFile) {
+	o.Files = append(o.Files, file)
+}
+
+// Warnf records a diagnostic for the given source position.
+func (o *Output) Warnf(file string, line int, format string, args ...interface{}) {
+	o.Diagnostics = append(o.Diagnostics, Diagnostic{
+		File:    file,
+		Line:    line,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// ReadSources reads the file at path, or every file below path whose
+// extension is ext, into memory. A .jar or .zip path is read as a source
+// archive.
+func ReadSources(path string, ext string) ([]Source, error) {
+	return readSources(path, ext, nil)
+}
+
+// ReadSourceRoots reads the sources below every root in roots that pass
+// filter, see ReadSources; a nil filter passes every file. Files reached
+// through more than one root are read once.
+func ReadSourceRoots(roots []string, ext string, filter *Filter) ([]Source, error) {
+	srcs := make([]Source, 0)
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		s, err := readSources(root, ext, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, src := range s {
+			if seen[filepath.Clean(src.Path)] {
+				continue
+			}
+			seen[filepath.Clean(src.Path)] = true
+			srcs = append(srcs, src)
+		}
+	}
+	return srcs, nil
+}
+
+func readSources(root string, ext string, filter *Filter) ([]Source, error) {
+	if root == "" {
+		root = "."
+	}
+	if isArchive(root) {
+		return readArchive(root, ext, filter)
+	}
+	if strings.HasSuffix(root, ext) {
+		if !filter.Match(filepath.Base(root)) {
+			return nil, nil
+		}
+		src, err := readSource(root)
+		if err != nil {
+			return nil, err
+		}
+		return []Source{src}, nil
+	}
+	srcs := make([]Source, 0)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ext {
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if !filter.Match(filepath.ToSlash(rel)) {
+			return nil
+		}
+		src, err := readSource(path)
+		if err != nil {
+			return err
+		}
+		srcs = append(srcs, src)
+		return nil
+	})
+	return srcs, err
+}
+
+// isArchive reports whether root is a source archive, such as a Maven
+// sources jar.
+func isArchive(root string) bool {
+	switch strings.ToLower(filepath.Ext(root)) {
+	case ".jar", ".zip":
+		return true
+	}
+	return false
+}
+
+// readArchive reads the entries of a source archive whose extension is ext.
+// The sources are named "<archive>!/<entry>", as in jar URLs.
+func readArchive(root string, ext string, filter *Filter) ([]Source, error) {
+	r, err := zip.OpenReader(root)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	files := append([]*zip.File{}, r.File...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	srcs := make([]Source, 0)
+	for _, f := range files {
+		if f.FileInfo().IsDir() || path.Ext(f.Name) != ext || !filter.Match(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		srcs = append(srcs, Source{Path: root + "!/" + f.Name, R: bytes.NewReader(b)})
+	}
+	return srcs, nil
+}
+
+func readSource(path string) (Source, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Source{}, err
+	}
+	return Source{Path: path, R: bytes.NewReader(b)}, nil
+}
+
+// WriteFiles writes the generated files below dir. Existing files are
+// handled according to the overwrite policy (see package config).
+func WriteFiles(dir string, files []*GeneratedFile, overwrite string) error {
+	if dir == "" {
+		dir = "./"
+	}
+	// 检查路径是否存在
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return fmt.Errorf("目标目录并不存在：%s", dir)
+	}
+	for _, file := range files {
+		// 组合路径和文件名
+		path := filepath.Join(dir, file.Name)
+		// 检查文件是否存在
+		if _, err := os.Stat(path); err == nil || os.IsExist(err) {
+			if overwrite == config.OverwriteFail {
+				return fmt.Errorf("文件已经存在：%s", path)
+			}
+			if overwrite != config.OverwriteAlways {
+				fmt.Println("文件已经存在：" + path + "， 如要更新先删除")
+				continue
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		fmt.Println("写入文件：" + path)
+		if err := file.WriteFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PrintDiagnostics prints the diagnostics of a converter run.
+func PrintDiagnostics(diags []Diagnostic) {
+	for _, d := range diags {
+		fmt.Println(d.String())
+	}
+}
@@ -0,0 +1,40 @@
+package gen
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadArchive(t *testing.T) {
+	jar := filepath.Join(t.TempDir(), "api-1.0.0-sources.jar")
+	f, err := os.Create(jar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for _, name := range []string{"META-INF/MANIFEST.MF", "com/example/vo/DeviceVO.java", "com/example/internal/Hidden.java"} {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(fw, "public class X {\n}\n")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	srcs, err := ReadSourceRoots([]string{jar}, ".java", &Filter{Exclude: []string{"**/internal/**"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(srcs) != 1 || srcs[0].Path != jar+"!/com/example/vo/DeviceVO.java" {
+		t.Fatalf("got sources %+v, want the DeviceVO entry", srcs)
+	}
+	if b, _ := io.ReadAll(srcs[0].R); string(b) != "public class X {\n}\n" {
+		t.Errorf("entry content = %q", b)
+	}
+}
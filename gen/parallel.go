@@ -0,0 +1,48 @@
+package gen
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Workers is the number of sources parsed at the same time, set by
+// --workers. Values below 1 parse one source at a time.
+var Workers = runtime.GOMAXPROCS(0)
+
+// Parallel calls f for every index in [0, n) on at most Workers goroutines
+// and waits for the calls to return. f must only write state of its own
+// index, e.g. the i-th element of a result slice, so that the result does
+// not depend on scheduling. The error returned is that of the smallest
+// index that failed.
+func Parallel(n int, f func(i int) error) error {
+	workers := Workers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+	errs := make([]error, n)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
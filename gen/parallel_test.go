@@ -0,0 +1,32 @@
+package gen
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParallel(t *testing.T) {
+	defer func(n int) { Workers = n }(Workers)
+	for _, Workers = range []int{0, 1, 3, 100} {
+		squares := make([]int, 50)
+		err := Parallel(len(squares), func(i int) error {
+			squares[i] = i * i
+			if i%10 == 7 {
+				return errors.New(fmt.Sprint(i))
+			}
+			return nil
+		})
+		if err == nil || err.Error() != "7" {
+			t.Errorf("workers %d: err = %v, want 7", Workers, err)
+		}
+		for i, sq := range squares {
+			if sq != i*i {
+				t.Fatalf("workers %d: squares[%d] = %d", Workers, i, sq)
+			}
+		}
+	}
+	if err := Parallel(0, func(int) error { return errors.New("called") }); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,174 @@
+// Package proto generates Kratos protobuf files from the intermediate model.
+package proto
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+)
+
+const (
+	version = "1.0.0"
+)
+
+// Generate returns the protobuf file of a service.
+func Generate(svc *ir.Service, cfg *config.Config) *gen.GeneratedFile {
+	file := gen.NewGeneratedFile()
+	file.Name = FileName(svc)
+	file.Sources = svc.Sources()
+	header(file, svc, cfg)
+	for _, ep := range svc.Endpoints {
+		rpc(file, svc, ep)
+	}
+	file.P("}")
+	file.P("")
+	for _, msg := range svc.Messages {
+		message(file, msg)
+	}
+	file.Timestamp()
+	return file
+}
+
+// FileName returns the name of the protobuf file of a service.
+func FileName(svc *ir.Service) string {
+	return strs.SnakeCase(svc.Name) + ".proto"
+}
+
+// Dir returns the directory of the protobuf file of a service in a Kratos
+// layout, the import path of its go_package option relative to the Go
+// module, e.g. "api/device/v1".
+func Dir(svc *ir.Service, cfg *config.Config) string {
+	goPackage := ImportPath(svc, cfg)
+	if cfg.Go.Module != "" {
+		goPackage = strings.TrimPrefix(strings.TrimPrefix(goPackage, cfg.Go.Module), "/")
+	}
+	return goPackage
+}
+
+// ImportPath returns the Go import path of the code generated from the
+// protobuf file of a service, its go_package option without the package
+// name, e.g. "example.com/demo/api/device/v1".
+func ImportPath(svc *ir.Service, cfg *config.Config) string {
+	goPackage := cfg.ProtoOption(cfg.Proto.GoPackage, Segment(svc), svc.Source)
+	if i := strings.Index(goPackage, ";"); i >= 0 {
+		goPackage = goPackage[:i]
+	}
+	return goPackage
+}
+
+// Package returns the proto package of a service, its package option,
+// e.g. "api.device.v1".
+func Package(svc *ir.Service, cfg *config.Config) string {
+	return cfg.ProtoOption(cfg.Proto.Package, Segment(svc), svc.Source)
+}
+
+// Segment returns the first segment of the service path, which names the
+// proto package, e.g. "device" for "/device/api/monitor".
+func Segment(svc *ir.Service) string {
+	for _, s := range strings.Split(svc.Path, "/") {
+		if s != "" {
+			return s
+		}
+	}
+	return strings.ToLower(svc.Name)
+}
+
+// TypeName returns the protobuf name of a type.
+func TypeName(t ir.Type) string {
+	switch t.Kind {
+	case ir.Int32:
+		return "int32"
+	case ir.Int64:
+		return "int64"
+	case ir.Float32:
+		return "float"
+	case ir.Float64:
+		return "double"
+	case ir.Bool:
+		return "bool"
+	case ir.Bytes:
+		return "bytes"
+	case ir.Time:
+		return "google.protobuf.Timestamp"
+	case ir.Message:
+		return t.Name
+	}
+	return "string"
+}
+
+func header(file *gen.GeneratedFile, svc *ir.Service, cfg *config.Config) {
+	seg := Segment(svc)
+	file.P("// Code generated by j2g. DO NOT EDIT.")
+	file.P("// versions:")
+	file.P("// - j2g v", version)
+	file.P("syntax = \"proto3\";")
+	file.P("")
+	file.P("package " + Package(svc, cfg) + ";")
+	file.P("")
+	file.P("import \"google/api/annotations.proto\";")
+	file.P("//import \"google/protobuf/timestamp.proto\";")
+	file.P("")
+	file.P("option go_package = \"" + cfg.ProtoOption(cfg.Proto.GoPackage, seg, svc.Source) + "\";")
+	file.P("option java_multiple_files = true;")
+	file.P("option java_package = \"" + cfg.ProtoOption(cfg.Proto.JavaPackage, seg, svc.Source) + "\";")
+	file.P("")
+	file.P("service " + svc.Name + " {")
+}
+
+func rpc(file *gen.GeneratedFile, svc *ir.Service, ep *ir.Endpoint) {
+	file.P("  // " + ep.Comment)
+	if ep.Path == "" {
+		file.P("  rpc " + ep.Name + "(" + ep.Request + ") returns (" + ep.Reply + ");")
+		return
+	}
+	file.P("  rpc " + ep.Name + "(" + ep.Request + ") returns (" + ep.Reply + "){")
+	file.P("    option (google.api.http) = {")
+	file.P("      " + ep.Method + ": \"" + svc.Path + ep.Path + "\"")
+	if ep.Body() {
+		file.P("      body: \"*\"")
+	}
+	file.P("    };")
+	file.P("  };")
+}
+
+func message(file *gen.GeneratedFile, msg *ir.Entity) {
+	if msg.Comment != "" {
+		file.P("// " + msg.Comment)
+	}
+	file.P("message " + msg.Name + " {")
+	for i, f := range msg.Fields {
+		field(file, f, i+1)
+	}
+	file.P("}")
+	file.P("")
+}
+
+func field(file *gen.GeneratedFile, f *ir.Field, num int) {
+	commentIndex := 50
+	repeated := ""
+	if f.Repeated {
+		repeated = "repeated "
+	}
+	buf := fmt.Sprintf("  %s%s %s = %d;",
+		repeated,
+		TypeName(f.Type),
+		strs.LetterCamelCase(f.Name),
+		num)
+	if f.Comment == "" {
+		file.P(buf)
+		return
+	}
+	printBufLen := len(buf)
+	if printBufLen < commentIndex {
+		printBufLen = commentIndex
+	}
+	// 定义初始值
+	printBuf := bytes.Repeat([]byte{' '}, printBufLen)
+	copy(printBuf, buf)
+	file.P(string(printBuf) + "// " + f.Comment)
+}
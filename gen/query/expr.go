@@ -0,0 +1,391 @@
+package query
+
+import (
+	"strconv"
+
+	"github.com/luobote55/java2go/internal/ognl"
+	"github.com/luobote55/java2go/ir"
+	"github.com/pkg/errors"
+)
+
+// A value is a Go expression standing for an OGNL property.
+type value struct {
+	expr     string
+	typ      ir.Type // zero if unknown, i.e. interface{}.
+	repeated bool
+	pointer  bool
+}
+
+// known reports whether v has a Go type other than interface{}.
+func (v value) known() bool {
+	return v.typ.Kind != "" && v.typ.Kind != ir.Message
+}
+
+// A translator turns OGNL expressions into Go expressions.
+type translator struct {
+	lookup func(path string) (value, bool)
+	// nonNil are the pointers known to be non-nil, i.e. tested by the
+	// left operand of an enclosing &&.
+	nonNil map[string]bool
+	fmt    bool // the translation uses package fmt.
+}
+
+func newTranslator(lookup func(path string) (value, bool)) *translator {
+	return &translator{lookup: lookup, nonNil: make(map[string]bool)}
+}
+
+// cond translates a test into a Go condition.
+func (t *translator) cond(s string) (string, error) {
+	e, err := ognl.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	return t.bool(e)
+}
+
+// bind translates the value of a <bind>.
+func (t *translator) bind(s string) (string, value, error) {
+	e, err := ognl.Parse(s)
+	if err != nil {
+		return "", value{}, err
+	}
+	v, err := t.value(e)
+	return v.expr, v, err
+}
+
+func unsupported(e ognl.Expr) error {
+	return errors.Errorf("暂不支持的表达式：%T", e)
+}
+
+// bool translates e in a boolean context. Properties that are not bool
+// are true if they are set: non-nil, non-empty or non-zero.
+func (t *translator) bool(e ognl.Expr) (string, error) {
+	switch x := e.(type) {
+	case *ognl.Binary:
+		switch x.Op {
+		case "&&":
+			l, err := t.bool(x.X)
+			if err != nil {
+				return "", err
+			}
+			// The right operand runs only if the left one holds.
+			added := t.known(x.X)
+			r, err := t.bool(x.Y)
+			for _, s := range added {
+				delete(t.nonNil, s)
+			}
+			if err != nil {
+				return "", err
+			}
+			return paren(x.X, "||", l) + " && " + paren(x.Y, "||", r), nil
+		case "||":
+			l, err := t.bool(x.X)
+			if err != nil {
+				return "", err
+			}
+			r, err := t.bool(x.Y)
+			if err != nil {
+				return "", err
+			}
+			return l + " || " + r, nil
+		case "==", "!=", "<", "<=", ">", ">=":
+			return t.compare(x)
+		}
+	case *ognl.Unary:
+		if x.Op == "!" {
+			s, err := t.bool(x.X)
+			if err != nil {
+				return "", err
+			}
+			switch y := x.X.(type) {
+			case *ognl.Binary:
+				s = "(" + s + ")"
+			case *ognl.Call:
+				// isEmpty and equals translate into comparisons.
+				if y.Method == "isEmpty" || y.Method == "equals" {
+					s = "(" + s + ")"
+				}
+			}
+			return "!" + s, nil
+		}
+	case *ognl.Lit:
+		if x.Kind == ognl.Bool {
+			return x.Value, nil
+		}
+	case *ognl.Call:
+		if x.Method == "isEmpty" || x.Method == "equals" || x.Method == "contains" {
+			v, err := t.value(x)
+			return v.expr, err
+		}
+	}
+	v, err := t.value(e)
+	if err != nil {
+		return "", err
+	}
+	if v.pointer && !t.nonNil[v.expr] {
+		inner := v
+		inner.pointer = false
+		inner.expr = "*" + v.expr
+		return "(" + v.expr + " != nil && " + truth(inner) + ")", nil
+	}
+	if v.pointer {
+		v.expr = "*" + v.expr
+	}
+	return truth(v), nil
+}
+
+// truth returns the Go condition telling whether a non-pointer v is set.
+func truth(v value) string {
+	switch {
+	case v.repeated:
+		return "len(" + v.expr + ") > 0"
+	case !v.known():
+		return v.expr + " != nil"
+	}
+	switch v.typ.Kind {
+	case ir.Bool:
+		return v.expr
+	case ir.String:
+		return v.expr + ` != ""`
+	case ir.Bytes:
+		return "len(" + v.expr + ") > 0"
+	case ir.Time:
+		return "!" + v.expr + ".IsZero()"
+	}
+	return v.expr + " != 0"
+}
+
+// known returns the pointers that e, when true, proves non-nil, and adds
+// them to t.nonNil.
+func (t *translator) known(e ognl.Expr) []string {
+	added := make([]string, 0)
+	var walk func(e ognl.Expr)
+	walk = func(e ognl.Expr) {
+		x, ok := e.(*ognl.Binary)
+		if !ok {
+			return
+		}
+		if x.Op == "&&" {
+			walk(x.X)
+			walk(x.Y)
+			return
+		}
+		if x.Op != "!=" {
+			return
+		}
+		id, ok := x.X.(*ognl.Ident)
+		lit, isLit := x.Y.(*ognl.Lit)
+		if !ok || !isLit || lit.Kind != ognl.Null {
+			return
+		}
+		if v, ok := t.lookup(id.Path); ok && v.pointer && !t.nonNil[v.expr] {
+			t.nonNil[v.expr] = true
+			added = append(added, v.expr)
+		}
+	}
+	walk(e)
+	return added
+}
+
+// paren parenthesizes the translation s of e if e is a binary op.
+func paren(e ognl.Expr, op, s string) string {
+	if x, ok := e.(*ognl.Binary); ok && x.Op == op {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// compare translates a comparison. A comparison with null tests the
+// pointer, slice or interface; other comparisons of a pointer are false
+// for nil, except != which is true, as in OGNL.
+func (t *translator) compare(x *ognl.Binary) (string, error) {
+	l, r := x.X, x.Y
+	op := x.Op
+	if _, ok := l.(*ognl.Lit); ok {
+		l, r = r, l
+		op = flip(op)
+	}
+	if lit, ok := r.(*ognl.Lit); ok && lit.Kind == ognl.Null {
+		v, err := t.value(l)
+		if err != nil {
+			return "", err
+		}
+		if !v.pointer && !v.repeated && v.known() {
+			// A Go value is never nil.
+			return strconv.FormatBool(op == "!="), nil
+		}
+		if op != "==" && op != "!=" {
+			return "", errors.Errorf("暂不支持和null比较大小：%s", op)
+		}
+		if v.pointer && t.nonNil[v.expr] {
+			return strconv.FormatBool(op == "!="), nil
+		}
+		return v.expr + " " + op + " nil", nil
+	}
+	v, err := t.value(l)
+	if err != nil {
+		return "", err
+	}
+	var rs string
+	if lit, ok := r.(*ognl.Lit); ok {
+		rs, err = literal(lit, v.typ)
+	} else {
+		var w value
+		w, err = t.value(r)
+		if w.pointer {
+			w.expr = "*" + w.expr
+		}
+		rs = w.expr
+	}
+	if err != nil {
+		return "", err
+	}
+	if !v.pointer {
+		return v.expr + " " + op + " " + rs, nil
+	}
+	cmp := "*" + v.expr + " " + op + " " + rs
+	if t.nonNil[v.expr] {
+		return cmp, nil
+	}
+	if op == "!=" {
+		return "(" + v.expr + " == nil || " + cmp + ")", nil
+	}
+	return "(" + v.expr + " != nil && " + cmp + ")", nil
+}
+
+// flip returns the operator comparing the operands the other way round.
+func flip(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+// literal returns the Go literal of lit for a comparison with a value of
+// type typ: MyBatis compares '1' and 1 alike.
+func literal(lit *ognl.Lit, typ ir.Type) (string, error) {
+	switch lit.Kind {
+	case ognl.Null:
+		return "nil", nil
+	case ognl.Bool:
+		return lit.Value, nil
+	}
+	switch typ.Kind {
+	case ir.String:
+		return strconv.Quote(lit.Value), nil
+	case ir.Int32, ir.Int64, ir.Float32, ir.Float64:
+		if _, err := strconv.ParseFloat(lit.Value, 64); err != nil {
+			return "", errors.Errorf("无法比较：%s", lit.Value)
+		}
+		return lit.Value, nil
+	}
+	if lit.Kind == ognl.String {
+		return strconv.Quote(lit.Value), nil
+	}
+	return lit.Value, nil
+}
+
+// value translates e in a value context. The result may be a pointer.
+func (t *translator) value(e ognl.Expr) (value, error) {
+	switch x := e.(type) {
+	case *ognl.Ident:
+		v, ok := t.lookup(x.Path)
+		if !ok {
+			return value{}, errors.Errorf("暂不支持的表达式：%s", x.Path)
+		}
+		return v, nil
+	case *ognl.Lit:
+		s, err := literal(x, ir.Type{})
+		typ := ir.Type{}
+		switch x.Kind {
+		case ognl.String:
+			typ = ir.Scalar(ir.String)
+		case ognl.Bool:
+			typ = ir.Scalar(ir.Bool)
+		case ognl.Number:
+			typ = ir.Scalar(ir.Int64)
+		}
+		return value{expr: s, typ: typ}, err
+	case *ognl.Unary:
+		v, err := t.deref(x.X)
+		if err != nil {
+			return value{}, err
+		}
+		if x.Op == "!" {
+			v.typ = ir.Scalar(ir.Bool)
+		}
+		v.expr = x.Op + v.expr
+		return v, nil
+	case *ognl.Binary:
+		switch x.Op {
+		case "+", "-", "*", "/", "%":
+			l, err := t.deref(x.X)
+			if err != nil {
+				return value{}, err
+			}
+			r, err := t.deref(x.Y)
+			if err != nil {
+				return value{}, err
+			}
+			typ := l.typ
+			if r.typ.Kind == ir.String {
+				typ = r.typ
+			}
+			if x.Op == "+" && typ.Kind == ir.String && (l.typ.Kind != ir.String || r.typ.Kind != ir.String) {
+				// Java converts the other operand of a string concatenation.
+				t.fmt = true
+				return value{expr: "fmt.Sprint(" + l.expr + ", " + r.expr + ")", typ: typ}, nil
+			}
+			return value{expr: l.expr + " " + x.Op + " " + r.expr, typ: typ}, nil
+		}
+		s, err := t.bool(x)
+		return value{expr: "(" + s + ")", typ: ir.Scalar(ir.Bool)}, err
+	case *ognl.Call:
+		v, err := t.deref(x.X)
+		if err != nil {
+			return value{}, err
+		}
+		switch {
+		case (x.Method == "size" || x.Method == "length") && len(x.Args) == 0:
+			return value{expr: "len(" + v.expr + ")", typ: ir.Scalar(ir.Int64)}, nil
+		case x.Method == "isEmpty" && len(x.Args) == 0:
+			return value{expr: "len(" + v.expr + ") == 0", typ: ir.Scalar(ir.Bool)}, nil
+		case x.Method == "trim" && len(x.Args) == 0:
+			return value{expr: "strings.TrimSpace(" + v.expr + ")", typ: ir.Scalar(ir.String)}, nil
+		case x.Method == "toString" && len(x.Args) == 0:
+			t.fmt = true
+			return value{expr: "fmt.Sprint(" + v.expr + ")", typ: ir.Scalar(ir.String)}, nil
+		case x.Method == "equals" && len(x.Args) == 1:
+			var arg string
+			if lit, ok := x.Args[0].(*ognl.Lit); ok {
+				arg, err = literal(lit, v.typ)
+			} else {
+				var w value
+				w, err = t.deref(x.Args[0])
+				arg = w.expr
+			}
+			return value{expr: v.expr + " == " + arg, typ: ir.Scalar(ir.Bool)}, err
+		case x.Method == "contains" && len(x.Args) == 1 && v.typ.Kind == ir.String && !v.repeated:
+			w, err := t.deref(x.Args[0])
+			return value{expr: "strings.Contains(" + v.expr + ", " + w.expr + ")", typ: ir.Scalar(ir.Bool)}, err
+		}
+		return value{}, errors.Errorf("暂不支持的方法：%s", x.Method)
+	}
+	return value{}, unsupported(e)
+}
+
+// deref translates e in a value context and dereferences pointers.
+func (t *translator) deref(e ognl.Expr) (value, error) {
+	v, err := t.value(e)
+	if v.pointer {
+		v.expr, v.pointer = "*"+v.expr, false
+	}
+	return v, err
+}
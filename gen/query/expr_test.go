@@ -0,0 +1,49 @@
+package query
+
+import (
+	"testing"
+
+	"github.com/luobote55/java2go/ir"
+)
+
+func TestCond(t *testing.T) {
+	params := map[string]value{
+		"name":   {expr: "p.Name", typ: ir.Scalar(ir.String), pointer: true},
+		"status": {expr: "p.Status", typ: ir.Scalar(ir.Int32)},
+		"ids":    {expr: "p.Ids", typ: ir.Scalar(ir.Int64), repeated: true},
+		"order":  {expr: "p.Order"},
+	}
+	lookup := func(path string) (value, bool) {
+		v, ok := params[path]
+		return v, ok
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"name != null and name != ''", `p.Name != nil && *p.Name != ""`},
+		{"name != null", "p.Name != nil"},
+		{"name == 'a'", `(p.Name != nil && *p.Name == "a")`},
+		{"status == '1' or status == 2", "p.Status == 1 || p.Status == 2"},
+		{"status != null", "true"},
+		{"ids != null and ids.size() > 0", "p.Ids != nil && len(p.Ids) > 0"},
+		{"!ids.isEmpty()", "!(len(p.Ids) == 0)"},
+		{"order", "p.Order != nil"},
+		{"name", `(p.Name != nil && *p.Name != "")`},
+		{"!name", `!(p.Name != nil && *p.Name != "")`},
+		{"!(status == 1 and name == null)", "!(p.Status == 1 && p.Name == nil)"},
+		{"!(name == 'a' or status == 1)", `!((p.Name != nil && *p.Name == "a") || p.Status == 1)`},
+	}
+	for _, tt := range tests {
+		got, err := newTranslator(lookup).cond(tt.in)
+		if err != nil {
+			t.Errorf("cond(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("cond(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if _, err := newTranslator(lookup).cond("missing != null"); err == nil {
+		t.Error("cond of an unknown property succeeded")
+	}
+}
@@ -0,0 +1,705 @@
+// Package query generates Go functions running the statements of MyBatis
+// mappers with database/sql. Dynamic SQL is built at run time with the
+// same rules as MyBatis; rows are scanned by column name into the ent
+// entities of DOs or into structs of their own.
+package query
+
+import (
+	"go/token"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/gen/data"
+	"github.com/luobote55/java2go/gen/ent"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+)
+
+const (
+	version = "1.0.0"
+)
+
+// HelpersFile is the name of the file holding the helpers and the result
+// structs shared by the mappers.
+const HelpersFile = "mybatis.go"
+
+// Generate returns the file of a mapper: a struct holding the database,
+// with a method per statement. Entities are looked up in m.
+func Generate(mp *ir.Mapper, m *ir.Model, cfg *config.Config) *gen.GeneratedFile {
+	g := &generator{mp: mp, m: m, cfg: cfg, body: gen.NewGeneratedFile()}
+	g.base = strings.TrimSuffix(strings.TrimSuffix(mp.Name, "Mapper"), "Dao")
+	g.body.P("// " + mp.Name + " runs the statements of " + className(mp) + ".")
+	g.body.P("type " + mp.Name + " struct {")
+	g.body.P("\tdb *sql.DB")
+	g.body.P("}")
+	g.body.P("")
+	g.body.P("// New" + mp.Name + " returns a new " + mp.Name + ".")
+	g.body.P("func New" + mp.Name + "(db *sql.DB) *" + mp.Name + " {")
+	g.body.P("\treturn &" + mp.Name + "{db: db}")
+	g.body.P("}")
+	scans := make([]string, 0)
+	scanned := make(map[string]bool)
+	for _, st := range mp.Statements {
+		g.statement(st)
+		if st.Result != nil && st.Result.Type.Kind == ir.Message && !scanned[st.Result.Type.Name] {
+			scanned[st.Result.Type.Name] = true
+			scans = append(scans, st.Result.Type.Name)
+		}
+	}
+	for _, name := range scans {
+		if r := mp.Result(name); r != nil {
+			g.scan(r)
+		}
+	}
+
+	file := gen.NewGeneratedFile()
+	file.Name = FileName(mp)
+	file.P("// Generated by j2g v", version, " from ", className(mp), ".")
+	file.P("")
+	file.P("package " + data.Package)
+	file.P("")
+	file.P("import (")
+	file.P("\t\"context\"")
+	file.P("\t\"database/sql\"")
+	if g.errors {
+		file.P("\t\"errors\"")
+	}
+	if g.fmt {
+		file.P("\t\"fmt\"")
+	}
+	file.P("\t\"strings\"")
+	if g.time {
+		file.P("\t\"time\"")
+	}
+	if g.ent {
+		file.P("")
+		file.P("\t" + strconv.Quote(cfg.EntPackage()))
+	}
+	file.P(")")
+	file.P("")
+	file.P(string(g.body.Content()))
+	file.Format()
+	return file
+}
+
+// FileName returns the name of the file of a mapper.
+func FileName(mp *ir.Mapper) string {
+	return strs.SnakeCase(mp.Name) + ".go"
+}
+
+// Helpers returns the file declaring the helpers of the generated mappers
+// and the result structs of mappers, each once.
+func Helpers(mappers []*ir.Mapper) *gen.GeneratedFile {
+	results := make([]*ir.Result, 0)
+	seen := make(map[string]bool)
+	needTime := false
+	for _, mp := range mappers {
+		for _, r := range mp.Results {
+			if r.Entity != "" || seen[r.Name] {
+				continue
+			}
+			seen[r.Name] = true
+			results = append(results, r)
+			for _, c := range r.Columns {
+				needTime = needTime || c.Type.Kind == ir.Time
+			}
+		}
+	}
+	file := gen.NewGeneratedFile()
+	file.Name = HelpersFile
+	file.P("// Generated by j2g v", version, ".")
+	file.P("")
+	file.P("package " + data.Package)
+	file.P("")
+	file.P("import (")
+	file.P("\t\"strings\"")
+	if needTime {
+		file.P("\t\"time\"")
+	}
+	file.P(")")
+	file.P("")
+	file.P("// sqlTrim applies a MyBatis <trim> to the SQL s: the overrides are")
+	file.P("// removed, then the prefix and suffix are added unless s is empty.")
+	file.P("func sqlTrim(s, prefix, suffix string, prefixOverrides, suffixOverrides []string) string {")
+	file.P("\ts = strings.TrimSpace(s)")
+	file.P("\tif s == \"\" {")
+	file.P("\t\treturn \"\"")
+	file.P("\t}")
+	file.P("\tfor _, o := range prefixOverrides {")
+	file.P("\t\tif strings.HasPrefix(strings.ToUpper(s), strings.ToUpper(o)) {")
+	file.P("\t\t\ts = strings.TrimSpace(s[len(o):])")
+	file.P("\t\t\tbreak")
+	file.P("\t\t}")
+	file.P("\t}")
+	file.P("\tfor _, o := range suffixOverrides {")
+	file.P("\t\tif strings.HasSuffix(strings.ToUpper(s), strings.ToUpper(o)) {")
+	file.P("\t\t\ts = strings.TrimSpace(s[:len(s)-len(o)])")
+	file.P("\t\t\tbreak")
+	file.P("\t\t}")
+	file.P("\t}")
+	file.P("\treturn \" \" + strings.TrimSpace(prefix+\" \"+s+\" \"+suffix)")
+	file.P("}")
+	for _, r := range results {
+		file.P("")
+		file.P("// " + r.Name + " is a result row; fields are nil for NULL columns.")
+		file.P("type " + r.Name + " struct {")
+		for _, c := range r.Columns {
+			file.P("\t" + fieldName(c.Field) + " *" + goType(c.Type))
+		}
+		file.P("}")
+	}
+	file.Format()
+	return file
+}
+
+// className returns the name of the mapper interface, or of the mapper
+// file if it has no namespace.
+func className(mp *ir.Mapper) string {
+	if mp.Namespace != "" {
+		return mp.Namespace
+	}
+	return mp.Source
+}
+
+// A generator holds the state of a mapper file being generated.
+type generator struct {
+	mp     *ir.Mapper
+	m      *ir.Model
+	cfg    *config.Config
+	base   string // mapper name without the Mapper suffix.
+	body   *gen.GeneratedFile
+	fmt    bool // the body uses package fmt.
+	time   bool // the body uses package time.
+	ent    bool // the body uses the ent package.
+	errors bool // the body uses package errors.
+
+	// State of the statement being generated.
+	vars    map[string]value // foreach items and indexes, bound names.
+	args    map[string]value // parameters.
+	trims   int              // number of trim builders.
+	loops   int              // number of foreach loops.
+	missing []string         // parameters that cannot be translated.
+}
+
+// reserved are the names used by the generated methods.
+var reserved = map[string]bool{
+	"ctx": true, "m": true, "p": true, "q": true, "args": true, "rows": true,
+	"res": true, "err": true, "v": true, "e": true, "sql": true, "strings": true,
+	"fmt": true, "time": true, "ent": true, "context": true,
+}
+
+// local returns a Go variable name for an OGNL name.
+func local(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "v" + s
+	}
+	if reserved[s] || token.IsKeyword(s) {
+		s += "_"
+	}
+	return s
+}
+
+// fieldName returns the Go field of a property path, named like ent names
+// its fields, e.g. UserID for user.id.
+func fieldName(path string) string {
+	path = strings.TrimLeft(path, "_")
+	return data.Pascal(strs.SnakeCase(strings.Replace(path, ".", "_", -1)))
+}
+
+// unexport returns the lower-case form of a Go name, e.g. userID for
+// UserID and id for ID.
+func unexport(s string) string {
+	r := []rune(s)
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+	if n > 1 && n < len(r) {
+		n-- // keep the start of the next word, e.g. URLPath -> urlPath.
+	}
+	if n == 0 {
+		n = 1
+	}
+	return strings.ToLower(string(r[:n])) + string(r[n:])
+}
+
+// goType returns the Go type of a scalar, interface{} for unknown types.
+func goType(t ir.Type) string {
+	switch t.Kind {
+	case ir.Int32, ir.Int64, ir.Float32, ir.Float64, ir.Bool, ir.String:
+		return string(t.Kind)
+	case ir.Bytes:
+		return "[]byte"
+	case ir.Time:
+		return "time.Time"
+	}
+	return "interface{}"
+}
+
+// paramType returns the Go type of a parameter: the ent entity for a
+// DO.
+func (g *generator) paramType(p *ir.Param) string {
+	typ := goType(p.Type)
+	if p.Type.Kind == ir.Message {
+		g.ent = true
+		typ = "*ent." + p.Type.Name
+	}
+	if p.Repeated {
+		return "[]" + typ
+	}
+	if p.Nillable && !strings.HasPrefix(typ, "*") && typ != "interface{}" {
+		return "*" + typ
+	}
+	return typ
+}
+
+// pointer reports whether the Go type of a parameter is a pointer to a
+// scalar, which is dereferenced where its value is used.
+func pointer(p *ir.Param) bool {
+	return p.Nillable && !p.Repeated && p.Type.Kind != ir.Message && goType(p.Type) != "interface{}"
+}
+
+// resultType returns the Go type of a row of a select.
+func (g *generator) resultType(st *ir.Statement) string {
+	if st.Result.Type.Kind != ir.Message {
+		g.time = g.time || st.Result.Type.Kind == ir.Time
+		return goType(st.Result.Type)
+	}
+	if r := g.mp.Result(st.Result.Type.Name); r != nil && r.Entity != "" {
+		g.ent = true
+		return "*ent." + r.Entity
+	}
+	return "*" + st.Result.Type.Name
+}
+
+// statement writes the method running a statement.
+func (g *generator) statement(st *ir.Statement) {
+	g.vars = make(map[string]value)
+	g.args = make(map[string]value)
+	g.trims, g.loops = 0, 0
+	params := ""
+	switch {
+	case len(st.Params) == 0:
+	case len(st.Params) == 1 || st.Positional:
+		for _, p := range st.Params {
+			name := local(unexport(fieldName(p.Name)))
+			g.args[p.Name] = value{expr: name, typ: p.Type, repeated: p.Repeated, pointer: pointer(p)}
+			params += ", " + name + " " + g.paramType(p)
+		}
+	default:
+		typ := g.base + st.Name + "Params"
+		g.body.P("")
+		g.body.P("// " + typ + " are the parameters of " + g.mp.Name + "." + st.Name + ".")
+		g.body.P("type " + typ + " struct {")
+		for _, p := range st.Params {
+			g.body.P("\t" + fieldName(p.Name) + " " + g.paramType(p))
+			g.args[p.Name] = value{expr: "p." + fieldName(p.Name), typ: p.Type, repeated: p.Repeated, pointer: pointer(p)}
+		}
+		g.body.P("}")
+		params = ", p *" + typ
+	}
+	for _, p := range st.Params {
+		g.time = g.time || p.Type.Kind == ir.Time
+	}
+	results := "(int64, error)"
+	zero := "0"
+	if st.Kind == ir.Select {
+		typ := g.resultType(st)
+		results = "([]" + typ + ", error)"
+		zero = "nil"
+		if st.One {
+			results = "(" + typ + ", error)"
+			zero = zeroValue(typ)
+		}
+	}
+	g.body.P("")
+	if st.Comment != "" {
+		g.body.P("// " + st.Name + " " + st.Comment)
+	} else {
+		g.body.P("// " + st.Name + " runs " + st.Kind + " " + st.ID + ".")
+	}
+	g.body.P("func (m *" + g.mp.Name + ") " + st.Name + "(ctx context.Context" + params + ") " + results + " {")
+	// The body is written aside, to be dropped if a parameter cannot be
+	// translated.
+	decl, usesFmt, usesTime := g.body, g.fmt, g.time
+	g.body, g.missing = gen.NewGeneratedFile(), nil
+	g.body.P("\tvar q strings.Builder")
+	g.body.P("\targs := make([]interface{}, 0)")
+	g.nodes(st.SQL, "q", "\t")
+	query := "strings.TrimSpace(q.String())"
+	switch {
+	case st.Kind != ir.Select:
+		g.body.P("\tres, err := m.db.ExecContext(ctx, " + query + ", args...)")
+		g.body.P("\tif err != nil {")
+		g.body.P("\t\treturn 0, err")
+		g.body.P("\t}")
+		if st.Kind == ir.Insert && st.GeneratedKey {
+			g.body.P("\treturn res.LastInsertId()")
+		} else {
+			g.body.P("\treturn res.RowsAffected()")
+		}
+	case st.Result.Type.Kind != ir.Message && st.One:
+		g.body.P("\tvar v " + goType(st.Result.Type))
+		g.body.P("\terr := m.db.QueryRowContext(ctx, " + query + ", args...).Scan(&v)")
+		g.body.P("\treturn v, err")
+	default:
+		g.body.P("\trows, err := m.db.QueryContext(ctx, " + query + ", args...)")
+		g.body.P("\tif err != nil {")
+		g.body.P("\t\treturn " + zero + ", err")
+		g.body.P("\t}")
+		g.body.P("\tdefer rows.Close()")
+		if st.Result.Type.Kind != ir.Message {
+			typ := goType(st.Result.Type)
+			g.body.P("\tres := make([]" + typ + ", 0)")
+			g.body.P("\tfor rows.Next() {")
+			g.body.P("\t\tvar v " + typ)
+			g.body.P("\t\tif err := rows.Scan(&v); err != nil {")
+			g.body.P("\t\t\treturn nil, err")
+			g.body.P("\t\t}")
+			g.body.P("\t\tres = append(res, v)")
+			g.body.P("\t}")
+			g.body.P("\treturn res, rows.Err()")
+		} else if st.One {
+			g.body.P("\tres, err := m.scan" + st.Result.Type.Name + "(rows)")
+			g.body.P("\tif err != nil {")
+			g.body.P("\t\treturn nil, err")
+			g.body.P("\t}")
+			g.body.P("\tif len(res) == 0 {")
+			g.body.P("\t\treturn nil, sql.ErrNoRows")
+			g.body.P("\t}")
+			g.body.P("\treturn res[0], nil")
+		} else {
+			g.body.P("\treturn m.scan" + st.Result.Type.Name + "(rows)")
+		}
+	}
+	body := g.body
+	g.body = decl
+	if len(g.missing) == 0 {
+		g.body.P(strings.TrimSuffix(string(body.Content()), "\n"))
+	} else {
+		// Running the statement without the parameter would run another
+		// statement, e.g. insert NULLs.
+		g.fmt, g.time, g.errors = usesFmt, usesTime, true
+		for _, s := range g.missing {
+			g.body.P("\t// TODO: translate " + s)
+		}
+		g.body.P("\treturn " + zero + ", errors.New(" + strconv.Quote(st.ID+": "+g.missing[0]+" not translated") + ")")
+	}
+	g.body.P("}")
+}
+
+// zeroValue returns the zero value of a Go type.
+func zeroValue(typ string) string {
+	switch typ {
+	case "string":
+		return `""`
+	case "bool":
+		return "false"
+	case "int32", "int64", "float32", "float64":
+		return "0"
+	case "time.Time":
+		return "time.Time{}"
+	}
+	return "nil"
+}
+
+// lookup resolves an OGNL property path in the statement being generated.
+// The properties of a DO are the fields of its ent entity.
+func (g *generator) lookup(path string) (value, bool) {
+	if v, ok := g.vars[path]; ok {
+		return v, true
+	}
+	if v, ok := g.args[path]; ok {
+		return v, true
+	}
+	i := strings.Index(path, ".")
+	if i < 0 {
+		return value{}, false
+	}
+	v, ok := g.lookup(path[:i])
+	if !ok || v.typ.Kind != ir.Message || v.repeated {
+		return value{}, false
+	}
+	e := g.m.Entity(v.typ.Name)
+	if e == nil {
+		return value{}, false
+	}
+	f := e.Property(path[i+1:])
+	if f == nil || f.Type.Kind == ir.Message || f.Repeated {
+		return value{}, false
+	}
+	return value{expr: v.expr + "." + data.Pascal(ent.Column(f)), typ: f.Type, pointer: f.Nillable}, true
+}
+
+// nodes writes the code appending SQL nodes to the builder b.
+func (g *generator) nodes(nodes []*ir.SQL, b, indent string) {
+	for _, n := range nodes {
+		switch n.Kind {
+		case ir.Text:
+			g.text(n.Text, b, indent)
+		case ir.If:
+			g.body.P(indent + "if " + g.cond(n.Test, indent) + " {")
+			g.nodes(n.Nodes, b, indent+"\t")
+			g.body.P(indent + "}")
+		case ir.Choose:
+			g.choose(n, b, indent)
+		case ir.Trim:
+			g.trims++
+			t := "t" + strconv.Itoa(g.trims)
+			g.body.P(indent + "var " + t + " strings.Builder")
+			g.nodes(n.Nodes, t, indent)
+			g.body.P(indent + b + ".WriteString(sqlTrim(" + t + ".String(), " + strconv.Quote(n.Prefix) + ", " + strconv.Quote(n.Suffix) + ", " + stringsLit(n.PrefixOverrides) + ", " + stringsLit(n.SuffixOverrides) + "))")
+		case ir.Foreach:
+			g.foreach(n, b, indent)
+		case ir.Bind:
+			t := newTranslator(g.lookup)
+			expr, v, err := t.bind(n.Value)
+			g.fmt = g.fmt || t.fmt
+			name := local(n.Name)
+			if err != nil {
+				g.body.P(indent + "// TODO: translate <bind name=" + strconv.Quote(n.Name) + " value=" + strconv.Quote(n.Value) + ">: " + err.Error())
+				g.body.P(indent + "var " + name + " interface{}")
+				v = value{}
+			} else {
+				g.body.P(indent + name + " := " + expr)
+			}
+			g.vars[n.Name] = value{expr: name, typ: v.typ, repeated: v.repeated}
+		}
+	}
+}
+
+// cond translates the test of an <if> or <when>. A test that cannot be
+// translated is false, with a TODO.
+func (g *generator) cond(test, indent string) string {
+	t := newTranslator(g.lookup)
+	s, err := t.cond(test)
+	g.fmt = g.fmt || t.fmt
+	if err != nil {
+		g.body.P(indent + "// TODO: translate test " + strconv.Quote(test) + ": " + err.Error())
+		return "false"
+	}
+	return s
+}
+
+// choose writes a <choose> as an if-else chain.
+func (g *generator) choose(n *ir.SQL, b, indent string) {
+	first := true
+	for _, c := range n.Nodes {
+		switch c.Kind {
+		case ir.When:
+			cond := g.cond(c.Test, indent)
+			if first {
+				g.body.P(indent + "if " + cond + " {")
+			} else {
+				g.body.P(indent + "} else if " + cond + " {")
+			}
+			first = false
+		case ir.Otherwise:
+			if first {
+				g.body.P(indent + "{")
+			} else {
+				g.body.P(indent + "} else {")
+			}
+			first = false
+		default:
+			continue
+		}
+		g.nodes(c.Nodes, b, indent+"\t")
+	}
+	if !first {
+		g.body.P(indent + "}")
+	}
+}
+
+// foreach writes a <foreach> over a slice.
+func (g *generator) foreach(n *ir.SQL, b, indent string) {
+	coll, ok := g.lookup(n.Collection)
+	if !ok || !coll.repeated {
+		g.body.P(indent + "// TODO: translate <foreach collection=" + strconv.Quote(n.Collection) + ">")
+		return
+	}
+	g.loops++
+	item, index := "_", "_"
+	saved := make(map[string]value)
+	for k, v := range g.vars {
+		saved[k] = v
+	}
+	if n.Item != "" && mentions(n.Nodes, n.Item) {
+		item = local(n.Item)
+		g.vars[n.Item] = value{expr: item, typ: coll.typ}
+	}
+	if n.Index != "" && mentions(n.Nodes, n.Index) {
+		index = local(n.Index)
+		g.vars[n.Index] = value{expr: index, typ: ir.Scalar(ir.Int64)}
+	}
+	if n.Separator != "" && index == "_" {
+		index = "i" + strconv.Itoa(g.loops)
+	}
+	g.body.P(indent + "if len(" + coll.expr + ") > 0 {")
+	inner := indent + "\t"
+	if n.Open != "" {
+		g.body.P(inner + b + ".WriteString(" + strconv.Quote(" "+n.Open) + ")")
+	}
+	switch {
+	case item == "_" && index == "_":
+		g.body.P(inner + "for range " + coll.expr + " {")
+	case item == "_":
+		g.body.P(inner + "for " + index + " := range " + coll.expr + " {")
+	default:
+		g.body.P(inner + "for " + index + ", " + item + " := range " + coll.expr + " {")
+	}
+	if n.Separator != "" {
+		g.body.P(inner + "\tif " + index + " > 0 {")
+		g.body.P(inner + "\t\t" + b + ".WriteString(" + strconv.Quote(n.Separator) + ")")
+		g.body.P(inner + "\t}")
+	}
+	g.nodes(n.Nodes, b, inner+"\t")
+	g.body.P(inner + "}")
+	if n.Close != "" {
+		g.body.P(inner + b + ".WriteString(" + strconv.Quote(n.Close) + ")")
+	}
+	g.body.P(indent + "}")
+	g.vars = saved
+}
+
+// mentions reports whether the SQL nodes read an OGNL name or one of its
+// properties.
+func mentions(nodes []*ir.SQL, name string) bool {
+	re := regexp.MustCompile(`(^|[^\w.])` + regexp.QuoteMeta(name) + `($|[^\w])`)
+	for _, n := range nodes {
+		if re.MatchString(n.Text) || re.MatchString(n.Test) || re.MatchString(n.Value) || re.MatchString(n.Collection) || mentions(n.Nodes, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// text writes the code appending SQL text: #{} parameters become
+// placeholders and ${} substitutions are spliced into the SQL.
+func (g *generator) text(text, b, indent string) {
+	sqlText := " "
+	args := make([]string, 0)
+	flush := func() {
+		if sqlText != "" {
+			g.body.P(indent + b + ".WriteString(" + strconv.Quote(sqlText) + ")")
+		}
+		if len(args) > 0 {
+			g.body.P(indent + "args = append(args, " + strings.Join(args, ", ") + ")")
+		}
+		sqlText, args = "", args[:0]
+	}
+	last := 0
+	for _, m := range paramRe.FindAllStringSubmatchIndex(text, -1) {
+		sqlText += text[last:m[0]]
+		last = m[1]
+		name := strings.TrimSpace(strings.Split(text[m[4]:m[5]], ",")[0])
+		v, ok := g.lookup(name)
+		if text[m[2]:m[3]] == "#" {
+			sqlText += "?"
+			if ok {
+				args = append(args, v.expr)
+			} else {
+				g.missing = append(g.missing, text[m[0]:m[1]])
+			}
+			continue
+		}
+		flush()
+		if !ok {
+			g.missing = append(g.missing, text[m[0]:m[1]])
+			continue
+		}
+		if v.pointer {
+			v.expr = "*" + v.expr
+		}
+		if v.typ.Kind == ir.String && !v.repeated {
+			g.body.P(indent + b + ".WriteString(" + v.expr + ")")
+		} else {
+			g.fmt = true
+			g.body.P(indent + b + ".WriteString(fmt.Sprint(" + v.expr + "))")
+		}
+	}
+	sqlText += text[last:]
+	flush()
+}
+
+// paramRe matches the #{} parameters and ${} substitutions of SQL text.
+var paramRe = regexp.MustCompile(`([#$])\{([^}]*)\}`)
+
+// stringsLit returns a Go []string literal, nil if ss is empty.
+func stringsLit(ss []string) string {
+	if len(ss) == 0 {
+		return "nil"
+	}
+	quoted := make([]string, 0, len(ss))
+	for _, s := range ss {
+		quoted = append(quoted, strconv.Quote(s))
+	}
+	return "[]string{" + strings.Join(quoted, ", ") + "}"
+}
+
+// scan writes the method scanning rows into results of r by column name.
+// Columns the result does not map are skipped.
+func (g *generator) scan(r *ir.Result) {
+	typ := r.Name
+	var e *ir.Entity
+	if r.Entity != "" {
+		g.ent = true
+		typ = "ent." + r.Entity
+		e = g.m.Entity(r.Entity)
+	}
+	fields := make(map[string]string) // lower-case column -> Go field.
+	for _, c := range r.Columns {
+		if e == nil {
+			fields[strings.ToLower(c.Column)] = fieldName(c.Field)
+			continue
+		}
+		if f := e.Property(c.Field); f != nil {
+			fields[strings.ToLower(c.Column)] = data.Pascal(ent.Column(f))
+		}
+	}
+	columns := make([]string, 0, len(fields))
+	for c := range fields {
+		columns = append(columns, c)
+	}
+	sort.Strings(columns)
+	g.body.P("")
+	g.body.P("// scan" + r.Name + " scans rows into " + typ + " by column name.")
+	g.body.P("func (m *" + g.mp.Name + ") scan" + r.Name + "(rows *sql.Rows) ([]*" + typ + ", error) {")
+	g.body.P("\tcolumns, err := rows.Columns()")
+	g.body.P("\tif err != nil {")
+	g.body.P("\t\treturn nil, err")
+	g.body.P("\t}")
+	g.body.P("\tres := make([]*" + typ + ", 0)")
+	g.body.P("\tfor rows.Next() {")
+	g.body.P("\t\tv := new(" + typ + ")")
+	g.body.P("\t\tdest := make([]interface{}, len(columns))")
+	g.body.P("\t\tfor i, c := range columns {")
+	g.body.P("\t\t\tswitch strings.ToLower(c) {")
+	for _, c := range columns {
+		g.body.P("\t\t\tcase " + strconv.Quote(c) + ":")
+		g.body.P("\t\t\t\tdest[i] = &v." + fields[c])
+	}
+	g.body.P("\t\t\tdefault:")
+	g.body.P("\t\t\t\tdest[i] = new(interface{})")
+	g.body.P("\t\t\t}")
+	g.body.P("\t\t}")
+	g.body.P("\t\tif err := rows.Scan(dest...); err != nil {")
+	g.body.P("\t\t\treturn nil, err")
+	g.body.P("\t\t}")
+	g.body.P("\t\tres = append(res, v)")
+	g.body.P("\t}")
+	g.body.P("\treturn res, rows.Err()")
+	g.body.P("}")
+}
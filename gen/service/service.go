@@ -0,0 +1,78 @@
+// Package service generates Kratos service implementation stubs from the
+// intermediate model. The stubs implement the server interfaces generated
+// from the protobuf files of gen/proto and are meant to be filled in by hand.
+package service
+
+import (
+	"strconv"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/gen/proto"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+)
+
+const (
+	version = "1.0.0"
+)
+
+// Package is the Go package of the stubs in a Kratos layout.
+const Package = "service"
+
+// Generate returns the service stub of a service: a struct embedding the
+// unimplemented server with one method per RPC, whose TODO names the Java
+// service call the controller method delegated to.
+func Generate(svc *ir.Service, cfg *config.Config) *gen.GeneratedFile {
+	file := gen.NewGeneratedFile()
+	file.Name = FileName(svc)
+	file.Sources = svc.Sources()
+	name := svc.Name + "Service"
+	file.P("// Generated by j2g v", version, " from ", svc.Class, ".")
+	file.P("")
+	file.P("package " + Package)
+	file.P("")
+	file.P("import (")
+	file.P("\t\"context\"")
+	file.P("")
+	file.P("\tpb " + strconv.Quote(proto.ImportPath(svc, cfg)))
+	file.P(")")
+	file.P("")
+	if svc.Comment != "" {
+		file.P("// " + name + " " + svc.Comment)
+	} else {
+		file.P("// " + name + " implements the " + svc.Name + " service.")
+	}
+	file.P("type " + name + " struct {")
+	file.P("\tpb.Unimplemented" + svc.Name + "Server")
+	file.P("}")
+	file.P("")
+	file.P("// New" + name + " returns a new " + name + ".")
+	file.P("func New" + name + "() *" + name + " {")
+	file.P("\treturn &" + name + "{}")
+	file.P("}")
+	for _, ep := range svc.Endpoints {
+		method(file, name, ep)
+	}
+	return file
+}
+
+// FileName returns the name of the stub file of a service.
+func FileName(svc *ir.Service) string {
+	return strs.SnakeCase(svc.Name) + ".go"
+}
+
+func method(file *gen.GeneratedFile, name string, ep *ir.Endpoint) {
+	file.P("")
+	if ep.Comment != "" {
+		file.P("// " + ep.Name + " " + ep.Comment)
+	}
+	file.P("func (s *" + name + ") " + ep.Name + "(ctx context.Context, req *pb." + ep.Request + ") (*pb." + ep.Reply + ", error) {")
+	if ep.Call != "" {
+		file.P("\t// TODO: " + ep.Call)
+	} else {
+		file.P("\t// TODO: implement")
+	}
+	file.P("\treturn &pb." + ep.Reply + "{}, nil")
+	file.P("}")
+}
@@ -3,14 +3,14 @@ module github.com/luobote55/java2go
 go 1.21.1
 
 require (
-	entgo.io/ent v0.13.1
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/cobra v1.8.0
 	google.golang.org/protobuf v1.33.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
-	github.com/google/uuid v1.3.0 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 )
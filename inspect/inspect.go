@@ -0,0 +1,153 @@
+package inspect
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/ctl"
+	"github.com/luobote55/java2go/do"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/service"
+	"github.com/luobote55/java2go/sql"
+	"github.com/spf13/cobra"
+)
+
+// CmdInspect represents the inspect command.
+var CmdInspect = &cobra.Command{
+	Use:   "inspect",
+	Short: "Print the model parsed from the java and sql sources as JSON",
+	Long: "Print the model parsed from the java and sql sources as JSON. The output can be edited and fed to the emit command. " +
+		"Example: ./j2g.exe inspect -c ./test/ctl/controller -v ./test/ctl/vo -r ./test/ctl/request -d ./test/do --sql_path ./test/sql",
+	Run: run,
+}
+
+var (
+	controllerPath string
+	voPath         string
+	requestPath    string
+	doPath         string
+	sqlPath        string
+	servicePath    string
+	outputPath     string
+)
+
+func init() {
+	CmdInspect.Flags().StringVarP(&controllerPath, "controller_path", "c", "", "java controller source directory")
+	CmdInspect.Flags().StringVarP(&voPath, "vo_path", "v", "", "java vo source directory")
+	CmdInspect.Flags().StringVarP(&requestPath, "request_path", "r", "", "java request source directory")
+	CmdInspect.Flags().StringVarP(&doPath, "do_path", "d", "", "java do source directory")
+	CmdInspect.Flags().StringVar(&sqlPath, "sql_path", "", "sql source directory")
+	CmdInspect.Flags().StringVar(&servicePath, "service_path", "", "java service source directory; the vo and request classes are its models")
+	CmdInspect.Flags().StringVarP(&outputPath, "output", "o", "", "write the model to this file instead of stdout")
+}
+
+func run(_ *cobra.Command, args []string) {
+	cfg, err := config.Load(config.File)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	out := new(gen.Output)
+	m, err := parse(cfg, out)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, d := range out.Diagnostics {
+		fmt.Fprintln(os.Stderr, d.String())
+	}
+	var w io.Writer = os.Stdout
+	if outputPath != "" {
+		f, err := os.Create(outputPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		defer f.Close()
+		w = f
+	}
+	if err = m.Write(w); err != nil {
+		fmt.Println(err)
+	}
+}
+
+func parse(cfg *config.Config, out *gen.Output) (*ir.Model, error) {
+	m := new(ir.Model)
+	if controllerPath != "" || voPath != "" || requestPath != "" {
+		controllers, err := readSources(controllerPath, ".java")
+		if err != nil {
+			return nil, err
+		}
+		vos, err := readSources(voPath, ".java")
+		if err != nil {
+			return nil, err
+		}
+		requests, err := readSources(requestPath, ".java")
+		if err != nil {
+			return nil, err
+		}
+		ctlModel, err := ctl.Parse(controllers, append(vos, requests...), cfg, out)
+		if err != nil {
+			return nil, err
+		}
+		m.Merge(ctlModel)
+	}
+	if doPath != "" {
+		srcs, err := readSources(doPath, ".java")
+		if err != nil {
+			return nil, err
+		}
+		doModel, err := do.Parse(srcs, cfg, out)
+		if err != nil {
+			return nil, err
+		}
+		m.Merge(doModel)
+	}
+	if sqlPath != "" {
+		srcs, err := readSources(sqlPath, ".sql")
+		if err != nil {
+			return nil, err
+		}
+		sqlModel, err := sql.Parse(srcs, cfg, out)
+		if err != nil {
+			return nil, err
+		}
+		m.Merge(sqlModel)
+	}
+	if servicePath != "" {
+		srcs, err := readSources(servicePath, ".java")
+		if err != nil {
+			return nil, err
+		}
+		models := make([]gen.Source, 0)
+		for _, path := range []string{voPath, requestPath} {
+			srcs, err := readSources(path, ".java")
+			if err != nil {
+				return nil, err
+			}
+			models = append(models, srcs...)
+		}
+		serviceModel, err := service.Parse(srcs, models, cfg, out)
+		if err != nil {
+			return nil, err
+		}
+		m.Usecases = append(m.Usecases, serviceModel.Usecases...)
+		for _, e := range serviceModel.Entities {
+			if m.Entity(e.Name) == nil {
+				m.Entities = append(m.Entities, e)
+			}
+		}
+	}
+	return m, nil
+}
+
+// readSources reads the sources below path; an empty path yields none.
+func readSources(path string, ext string) ([]gen.Source, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return gen.ReadSources(path, ext)
+}
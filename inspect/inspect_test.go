@@ -0,0 +1,70 @@
+package inspect
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luobote55/java2go/emit"
+	"github.com/luobote55/java2go/ir"
+)
+
+// TestRoundTrip dumps the model of the test sources, edits it as a user
+// would and generates the code from the edited model.
+func TestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	model := filepath.Join(dir, "model.json")
+	CmdInspect.SetArgs([]string{"-c", "../test/ctl/controller", "-v", "../test/ctl/vo", "-r", "../test/ctl/request", "-d", "../test/do", "-o", model})
+	if err := CmdInspect.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ir.Load(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Services) != 1 || m.Entity("DeviceMonitorVO") == nil || len(m.Entities) < 2 {
+		t.Fatalf("inspect model: %d services, %d entities", len(m.Services), len(m.Entities))
+	}
+	// the messages of a service are copies of the entities in the JSON.
+	svc := m.Services[0]
+	svc.Name = "Monitor"
+	for _, msg := range svc.Messages {
+		if msg.Name == "DeviceMonitorVO" {
+			msg.Fields = append(msg.Fields, &ir.Field{Name: "remark", Comment: "备注", Type: ir.Scalar(ir.String)})
+		}
+	}
+	f, err = os.Create(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Write(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "api")
+	if err = os.Mkdir(out, 0755); err != nil {
+		t.Fatal(err)
+	}
+	emit.CmdEmit.SetArgs([]string{"-t", "proto", model, out})
+	if err = emit.CmdEmit.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filepath.Join(out, "monitor.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"service Monitor {", "rpc SetMonitorConfig(", "string remark = 5;", "// 备注"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("emitted proto does not contain %q:\n%s", want, b)
+		}
+	}
+}
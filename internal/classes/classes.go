@@ -0,0 +1,87 @@
+// Package classes binds the class names used by mappers, the MyBatis and
+// the MapStruct ones alike, to the entities parsed from the model classes.
+package classes
+
+import (
+	"path"
+	"strings"
+
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
+)
+
+// Classes indexes the entities of a model by class.
+type Classes struct {
+	byClass  map[string]*ir.Entity
+	bySimple map[string][]*ir.Entity
+	byLower  map[string][]*ir.Entity // by lower-case simple name.
+	byName   map[string]*ir.Entity   // by entity name.
+	entities naming.Rule
+}
+
+// New indexes the entities of m. VOs are indexed by their class and DOs,
+// named after their tables, by the class of their file. entities is the
+// rule naming the entities of DO classes, by which the DOs replaced by
+// tables defined in SQL are still found.
+func New(m *ir.Model, entities naming.Rule) *Classes {
+	c := &Classes{
+		byClass:  make(map[string]*ir.Entity),
+		bySimple: make(map[string][]*ir.Entity),
+		byLower:  make(map[string][]*ir.Entity),
+		byName:   make(map[string]*ir.Entity),
+		entities: entities,
+	}
+	for _, e := range m.Entities {
+		c.byName[e.Name] = e
+		class := e.Class
+		if class == "" {
+			if e.Table == "" || !strings.HasSuffix(e.Source, ".java") {
+				continue // messages synthesized for endpoints and tables.
+			}
+			class = strings.TrimSuffix(path.Base(e.Source), ".java")
+		}
+		c.byClass[class] = e
+		simple := java.SimpleName(class)
+		c.bySimple[simple] = append(c.bySimple[simple], e)
+		c.byLower[strings.ToLower(simple)] = append(c.byLower[strings.ToLower(simple)], e)
+	}
+	return c
+}
+
+// Find returns the entity of a class used in a file of the given scope,
+// nil for mapper XML files. The qualified names the scope gives are tried
+// first, then a simple name only one entity has, as written or, as MyBatis
+// type aliases are, in any case, then the table named after the class by
+// the entity rule. Find returns nil if there is none or the simple name is
+// ambiguous.
+func (c *Classes) Find(scope *java.Scope, class string) *ir.Entity {
+	names := []string{class}
+	if scope != nil {
+		names = scope.Candidates(class)
+	}
+	for _, name := range names {
+		if e, ok := c.byClass[name]; ok {
+			return e
+		}
+	}
+	simple := java.SimpleName(class)
+	if candidates := c.bySimple[simple]; len(candidates) > 0 {
+		if len(candidates) == 1 {
+			return candidates[0]
+		}
+		return nil
+	}
+	if candidates := c.byLower[strings.ToLower(simple)]; len(candidates) == 1 {
+		return candidates[0]
+	}
+	if e := c.byName[c.entities.Apply(simple)]; e != nil && e.Table != "" {
+		return e
+	}
+	return nil
+}
+
+// Entity returns the entity with the given name, or nil.
+func (c *Classes) Entity(name string) *ir.Entity {
+	return c.byName[name]
+}
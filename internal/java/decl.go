@@ -0,0 +1,243 @@
+package java
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A Decl is a declaration of a Java file without its comments: a package
+// or import, the declaration of a type or a member of the type.
+type Decl struct {
+	Text    string
+	Comment string // first line of the Javadoc before the declaration.
+	Line    int
+	Depth   int  // 0 for the declarations of the file, 1 for members.
+	Body    bool // ends with a block rather than a semicolon.
+}
+
+// Declarations splits a Java file into its declarations. Blocks below the
+// members, such as the bodies of default methods, are skipped. Braces
+// within parentheses are the array initializers of annotations.
+func Declarations(src string) []*Decl {
+	decls := make([]*Decl, 0)
+	var b strings.Builder
+	comment := ""
+	line, start := 1, 0
+	depth, parens := 0, 0
+	write := func(s string) {
+		if depth > 1 {
+			return
+		}
+		if strings.TrimSpace(b.String()) == "" && strings.TrimSpace(s) != "" {
+			start = line
+		}
+		b.WriteString(s)
+	}
+	flush := func(body bool) {
+		if text := strings.TrimSpace(b.String()); text != "" {
+			decls = append(decls, &Decl{Text: text, Comment: comment, Line: start, Depth: depth, Body: body})
+		}
+		b.Reset()
+		comment = ""
+	}
+	for i := 0; i < len(src); i++ {
+		rest := src[i:]
+		switch {
+		case strings.HasPrefix(rest, "//"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			i += end - 1
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest[2:], "*/")
+			if end < 0 {
+				end = len(rest) - 4
+			}
+			text := rest[:end+4]
+			if strings.HasPrefix(text, "/**") && strings.TrimSpace(b.String()) == "" {
+				comment = javadoc(text)
+			}
+			line += strings.Count(text, "\n")
+			i += len(text) - 1
+		case rest[0] == '"' || rest[0] == '\'':
+			lit := Literal(rest)
+			write(lit)
+			line += strings.Count(lit, "\n")
+			i += len(lit) - 1
+		default:
+			c := rest[0]
+			switch {
+			case c == '\n':
+				line++
+			case c == '(':
+				parens++
+			case c == ')':
+				parens--
+			case c == '{' && parens == 0:
+				if depth <= 1 {
+					flush(true)
+				}
+				depth++
+				continue
+			case c == '}' && parens == 0:
+				depth--
+				b.Reset()
+				continue
+			case c == ';' && parens == 0 && depth <= 1:
+				flush(false)
+				continue
+			}
+			write(string(c))
+		}
+	}
+	return decls
+}
+
+// javadoc returns the first line of the text of a Javadoc comment.
+func javadoc(text string) string {
+	text = strings.TrimSuffix(strings.TrimPrefix(text, "/**"), "*/")
+	for _, s := range strings.Split(text, "\n") {
+		s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "*"))
+		if s != "" && !strings.HasPrefix(s, "@") {
+			return s
+		}
+	}
+	return ""
+}
+
+// Literal returns the string, text block or character literal s starts
+// with, or the rest of s if it is not terminated.
+func Literal(s string) string {
+	if strings.HasPrefix(s, `"""`) {
+		if end := strings.Index(s[3:], `"""`); end >= 0 {
+			return s[:end+6]
+		}
+		return s
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case s[0]:
+			return s[:i+1]
+		}
+	}
+	return s
+}
+
+// Unquote returns the value of a string literal or text block.
+func Unquote(lit string) string {
+	if strings.HasPrefix(lit, `"""`) {
+		text := strings.TrimSuffix(strings.TrimPrefix(lit, `"""`), `"""`)
+		if i := strings.IndexByte(text, '\n'); i >= 0 {
+			text = text[i+1:] // the opening delimiter ends its line.
+		}
+		return text
+	}
+	if s, err := strconv.Unquote(strings.Replace(lit, `\'`, "'", -1)); err == nil {
+		return s
+	}
+	return strings.Trim(lit, `"`)
+}
+
+// SplitTop splits s at the separators outside of literals and brackets.
+func SplitTop(s string, sep byte) []string {
+	parts := make([]string, 0)
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\'':
+			i += len(Literal(s[i:])) - 1
+		case c == '(' || c == '{' || c == '<':
+			depth++
+		case c == ')' || c == '}' || c == '>':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if strings.TrimSpace(s[start:]) != "" {
+		parts = append(parts, s[start:])
+	}
+	return parts
+}
+
+// An Annotation is a Java annotation with its arguments as written.
+type Annotation struct {
+	Name string // simple name.
+	Args string
+}
+
+// Annotations removes the leading annotations of a declaration, like
+// StripAnnotations, but keeps their arguments and skips parentheses
+// within string literals such as SQL.
+func Annotations(s string) ([]Annotation, string) {
+	list := make([]Annotation, 0)
+	s = strings.TrimSpace(s)
+	for strings.HasPrefix(s, "@") && !strings.HasPrefix(s, "@interface") {
+		end := strings.IndexAny(s, " \t\n(")
+		if end < 0 {
+			end = len(s)
+		}
+		a := Annotation{Name: SimpleName(s[1:end])}
+		s = strings.TrimSpace(s[end:])
+		if strings.HasPrefix(s, "(") {
+			depth := 0
+			for i := 0; i < len(s); i++ {
+				switch s[i] {
+				case '"', '\'':
+					i += len(Literal(s[i:])) - 1
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+				if depth == 0 {
+					a.Args = s[1:i]
+					s = strings.TrimSpace(s[i+1:])
+					break
+				}
+			}
+		}
+		list = append(list, a)
+	}
+	return list, s
+}
+
+// Attrs returns the elements of annotation arguments by name; a single
+// value without a name is "value".
+func Attrs(args string) map[string]string {
+	m := make(map[string]string)
+	for _, part := range SplitTop(args, ',') {
+		part = strings.TrimSpace(part)
+		name := "value"
+		if i := strings.Index(part, "="); i > 0 && !strings.ContainsAny(part[:i], `"'{`) {
+			name, part = strings.TrimSpace(part[:i]), strings.TrimSpace(part[i+1:])
+		}
+		m[name] = part
+	}
+	return m
+}
+
+// Element returns the element class of a collection or array type, and
+// whether it was one. Optional types are their element.
+func Element(decl string) (class string, repeated bool) {
+	decl = strings.TrimSpace(decl)
+	if strings.HasSuffix(decl, "[]") && decl != "byte[]" {
+		return strings.TrimSpace(strings.TrimSuffix(decl, "[]")), true
+	}
+	i := strings.Index(decl, "<")
+	if i < 0 || !strings.HasSuffix(decl, ">") {
+		return decl, false
+	}
+	elem := decl[i+1 : len(decl)-1]
+	switch SimpleName(decl[:i]) {
+	case "List", "ArrayList", "LinkedList", "Set", "HashSet", "Collection", "Iterable":
+		return strings.TrimSpace(elem), true
+	case "Optional":
+		return strings.TrimSpace(elem), false
+	}
+	return decl[:i], false
+}
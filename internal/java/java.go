@@ -0,0 +1,124 @@
+// Package java holds the helpers shared by the front-ends that read Java
+// sources: package and import scopes, method signatures, and the
+// declarations and annotations of files read as a whole.
+package java
+
+import "strings"
+
+// A Scope holds the package and import declarations of a Java file, which
+// bind the class names used in the file.
+type Scope struct {
+	Package   string
+	Imports   map[string]string // simple name -> qualified name.
+	Wildcards []string          // packages imported with ".*".
+}
+
+// NewScope returns an empty scope.
+func NewScope() *Scope {
+	return &Scope{Imports: make(map[string]string)}
+}
+
+// Line records a package or import declaration. It reports whether line
+// was one.
+func (s *Scope) Line(line string) bool {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "package ") {
+		s.Package = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "package "), ";"))
+		return true
+	}
+	if !strings.HasPrefix(line, "import ") || strings.HasPrefix(line, "import static ") {
+		return false
+	}
+	name := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line, "import "), ";"))
+	if strings.HasSuffix(name, ".*") {
+		s.Wildcards = append(s.Wildcards, strings.TrimSuffix(name, ".*"))
+	} else {
+		s.Imports[SimpleName(name)] = name
+	}
+	return true
+}
+
+// Qualify returns the qualified name of a class declared in the file.
+func (s *Scope) Qualify(class string) string {
+	if s.Package == "" {
+		return class
+	}
+	return s.Package + "." + class
+}
+
+// Candidates returns the qualified names a class name used in the file may
+// stand for, in order of precedence: the name itself if qualified, its
+// single-type import, the file's own package and the wildcard imports.
+func (s *Scope) Candidates(name string) []string {
+	names := make([]string, 0, 3+len(s.Wildcards))
+	if strings.Contains(name, ".") {
+		names = append(names, name)
+	}
+	if q, ok := s.Imports[name]; ok {
+		names = append(names, q)
+	}
+	names = append(names, s.Qualify(name))
+	for _, w := range s.Wildcards {
+		names = append(names, w+"."+name)
+	}
+	return names
+}
+
+// SimpleName returns the class name of a qualified name.
+func SimpleName(name string) string {
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// SplitParams splits a parameter list at the commas outside of generics
+// and annotation arguments.
+func SplitParams(s string) []string {
+	params := make([]string, 0)
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '<', '(':
+			depth++
+		case '>', ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				params = append(params, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if strings.TrimSpace(s[start:]) != "" {
+		params = append(params, s[start:])
+	}
+	return params
+}
+
+// StripAnnotations removes the leading annotations of a declaration such
+// as `@RequestParam(required = false) Long id`. It returns the annotation
+// names, "RequestParam", and the rest, "Long id".
+func StripAnnotations(s string) (names []string, rest string) {
+	s = strings.TrimSpace(s)
+	for strings.HasPrefix(s, "@") {
+		end := strings.IndexAny(s, " (")
+		if end < 0 {
+			return append(names, s[1:]), ""
+		}
+		names = append(names, s[1:end])
+		s = strings.TrimSpace(s[end:])
+		if strings.HasPrefix(s, "(") {
+			depth := 0
+			for i, c := range s {
+				if c == '(' {
+					depth++
+				} else if c == ')' {
+					depth--
+				}
+				if depth == 0 {
+					s = strings.TrimSpace(s[i+1:])
+					break
+				}
+			}
+		}
+	}
+	return names, s
+}
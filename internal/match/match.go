@@ -1,57 +1,45 @@
+// Package match extracts quoted strings and regular expression groups from
+// source lines. The quote helpers scan the line instead of running a
+// regular expression, and FindFix compiles each pattern once, so that they
+// are cheap enough to call several times for every line of a large code
+// base. Helpers returning a single match return "" if there is none.
 package match
 
 import (
 	"regexp"
+	"strings"
+	"sync"
 )
 
-func Remove(str, remove string) string {
-	return str
-	//	return strings.Replace(str, remove, "", -1)
-}
-
 // 反引号匹配
 func FindBacktick(str string) string {
-	re := regexp.MustCompile("`([^`]+)`")
-	matches := re.FindAllString(str, -1)
-	return matches[0]
+	return first(FindBackticks(str))
 }
 
 // 反引号匹配
 func FindBackticks(str string) []string {
-	re := regexp.MustCompile("`([^`]+)`")
-	matches := re.FindAllString(str, -1)
-	return matches
+	return quoted(str, '`', false)
 }
 
 // 单引号匹配
 func FindQuote(str string) string {
-	re := regexp.MustCompile("('(.*?)')")
-	matches := re.FindAllString(str, -1)
-	return matches[0]
+	return first(quoted(str, '\'', true))
 }
 
 // 双引号匹配
 func FindDoubleQuote(str string) string {
-	re := regexp.MustCompile("(\"(.*?)\")")
-	matches := re.FindAllString(str, -1)
-	return matches[0]
+	return first(FindDoubleQuotes(str))
 }
 
 // 双引号匹配
 func FindDoubleQuotes(str string) []string {
-	re := regexp.MustCompile("(\"(.*?)\")")
-	matches := re.FindAllString(str, -1)
-	return matches
+	return quoted(str, '"', true)
 }
 
 // 字符串匹配
 func FindFix(str string, fix string) string {
-	re := regexp.MustCompile(fix)
-	matches := re.FindStringSubmatch(str)
-	if len(matches) == 0 {
-		return ""
-	}
-	if len(matches) == 1 {
+	matches := compile(fix).FindStringSubmatch(str)
+	if len(matches) < 2 {
 		return ""
 	}
 	return matches[1]
@@ -59,7 +47,52 @@ func FindFix(str string, fix string) string {
 
 // 字符串匹配
 func FindFixs(str string, fix string) []string {
-	re := regexp.MustCompile(fix)
-	matches := re.FindStringSubmatch(str)
-	return matches
+	return compile(fix).FindStringSubmatch(str)
+}
+
+// patterns caches the compiled patterns of FindFix by source. Generators
+// run concurrently, hence the sync.Map.
+var patterns sync.Map
+
+// compile returns the compiled pattern. It panics if the pattern is not a
+// valid regular expression, which is a bug of the caller.
+func compile(fix string) *regexp.Regexp {
+	if re, ok := patterns.Load(fix); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, _ := patterns.LoadOrStore(fix, regexp.MustCompile(fix))
+	return re.(*regexp.Regexp)
+}
+
+// quoted returns the strings of str enclosed in q, quotes included, as the
+// regular expression q(.*?)q would, or q([^q]+)q if the content may not be
+// empty. Quotes on different lines, where . stops, do not pair up.
+func quoted(str string, q byte, empty bool) []string {
+	matches := make([]string, 0)
+	for i := 0; ; {
+		open := strings.IndexByte(str[i:], q)
+		if open < 0 {
+			return matches
+		}
+		open += i
+		end := strings.IndexByte(str[open+1:], q)
+		if end < 0 {
+			return matches
+		}
+		end += open + 1
+		if (end == open+1 && !empty) || (empty && strings.IndexByte(str[open:end], '\n') >= 0) {
+			// the closing quote may open the next match.
+			i = end
+			continue
+		}
+		matches = append(matches, str[open:end+1])
+		i = end + 1
+	}
+}
+
+func first(matches []string) string {
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[0]
 }
@@ -0,0 +1,167 @@
+package match
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// The regular expressions the helpers implemented before, and still
+// implement. legacy compiles them on every call, as the helpers did.
+var legacy = map[string]func(string) []string{
+	"backticks": func(s string) []string { return regexp.MustCompile("`([^`]+)`").FindAllString(s, -1) },
+	"quotes":    func(s string) []string { return regexp.MustCompile("('(.*?)')").FindAllString(s, -1) },
+	"double":    func(s string) []string { return regexp.MustCompile("(\"(.*?)\")").FindAllString(s, -1) },
+}
+
+func TestQuoted(t *testing.T) {
+	current := map[string]func(string) []string{
+		"backticks": FindBackticks,
+		"quotes":    func(s string) []string { return quoted(s, '\'', true) },
+		"double":    FindDoubleQuotes,
+	}
+	lines := []string{
+		"",
+		"no quotes at all",
+		"  `id` bigint(20) NOT NULL COMMENT 'id',",
+		"  `` `name` varchar(64) COMMENT '',",
+		"@ApiModelProperty(value = \"设备名称\", example = \"\")",
+		"@GetMapping(\"/list\") \"unterminated",
+		"\"first line\nsecond\" \"third\"",
+		"`across\nlines` ```tri```",
+		"'a''b' '",
+	}
+	for name, find := range current {
+		for _, line := range lines {
+			want := legacy[name](line)
+			if want == nil {
+				want = []string{}
+			}
+			if got := find(line); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s(%q) = %q, want %q", name, line, got, want)
+			}
+		}
+	}
+}
+
+func TestNoMatch(t *testing.T) {
+	for name, got := range map[string]string{
+		"FindBacktick":    FindBacktick("id bigint"),
+		"FindQuote":       FindQuote("COMMENT"),
+		"FindDoubleQuote": FindDoubleQuote("@ApiOperation(value)"),
+		"FindFix":         FindFix("public interface X", `public class (.*?) `),
+		"FindFix group":   FindFix("public class X {", `public class`),
+	} {
+		if got != "" {
+			t.Errorf("%s = %q, want \"\"", name, got)
+		}
+	}
+	if got := FindFix("    private List<DeviceVO> list;", `private (.*?);`); got != "List<DeviceVO> list" {
+		t.Errorf("FindFix = %q", got)
+	}
+}
+
+// corpus returns the lines of a synthetic code base of n files: Spring
+// controllers, VOs and DOs, and MySQL schema files.
+func corpus(n int) [][]string {
+	files := make([][]string, 0, n)
+	for i := 0; i < n; i++ {
+		var lines []string
+		switch i % 4 {
+		case 0:
+			lines = append(lines,
+				"package com.example.device;",
+				"@Api(tags = \"设备管理\")",
+				"@RequestMapping(\"/device/api\")",
+				fmt.Sprintf("public class Device%dController {", i))
+			for j := 0; j < 5; j++ {
+				lines = append(lines,
+					fmt.Sprintf("    @GetMapping(\"/get-%d\")", j),
+					fmt.Sprintf("    @ApiOperation(\"查询设备%d\")", j),
+					fmt.Sprintf("    public DataGrid<DeviceVO> getDevice%d(@RequestParam Long id) {", j),
+					"        return deviceService.getDevice(id);",
+					"    }")
+			}
+		case 1, 2:
+			lines = append(lines,
+				"package com.example.device.vo;",
+				"@ApiModel(value = \"设备\")",
+				fmt.Sprintf("public class Device%dVO {", i))
+			for j := 0; j < 10; j++ {
+				lines = append(lines,
+					fmt.Sprintf("    @ApiModelProperty(value = \"字段%d\", example = \"1\")", j),
+					fmt.Sprintf("    private List<String> field%d;", j))
+			}
+		default:
+			lines = append(lines, fmt.Sprintf("CREATE TABLE `t_device_%d` (", i))
+			for j := 0; j < 10; j++ {
+				lines = append(lines, fmt.Sprintf("  `field_%d` varchar(64) DEFAULT NULL COMMENT '字段%d',", j, j))
+			}
+			lines = append(lines, ") ENGINE=InnoDB COMMENT = '设备';")
+		}
+		files = append(files, lines)
+	}
+	return files
+}
+
+// extract makes the calls the generators make for a line.
+func extract(line string, doubleQuotes, backticks func(string) []string, fix func(string, string) string) {
+	switch {
+	case strings.Contains(line, "@Api"), strings.Contains(line, "Mapping("):
+		doubleQuotes(line)
+	case strings.Contains(line, "public class "):
+		fix(line, `public class (.*?) `)
+	case strings.Contains(line, "public "):
+		fix(line, `(.*?)<`)
+		fix(line, `DataGrid<(.*)>`)
+	case strings.Contains(line, "private "):
+		fix(fix(line, `private (.*?);`), `List<(.*?)>`)
+	case strings.HasPrefix(line, "  `"):
+		backticks(line)
+		fix(line, `COMMENT '(.*?)'`)
+		fix(line, `DEFAULT (\S+)`)
+	}
+}
+
+func BenchmarkCorpus(b *testing.B) {
+	files := corpus(10000)
+	b.Run("legacy", func(b *testing.B) {
+		fix := func(s, fix string) string {
+			m := regexp.MustCompile(fix).FindStringSubmatch(s)
+			if len(m) < 2 {
+				return ""
+			}
+			return m[1]
+		}
+		for i := 0; i < b.N; i++ {
+			for _, lines := range files {
+				for _, line := range lines {
+					extract(line, legacy["double"], legacy["backticks"], fix)
+				}
+			}
+		}
+	})
+	b.Run("match", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, lines := range files {
+				for _, line := range lines {
+					extract(line, FindDoubleQuotes, FindBackticks, FindFix)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkFindFix(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		FindFix("    private List<DeviceVO> devices;", `private (.*?);`)
+	}
+}
+
+func BenchmarkFindDoubleQuotes(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		FindDoubleQuotes("@ApiModelProperty(value = \"设备名称\", example = \"1\")")
+	}
+}
@@ -0,0 +1,327 @@
+// Package ognl parses the OGNL expressions of MyBatis mapper files: the
+// tests of <if> and <when> and the values of <bind>. Only the subset found
+// in mappers is supported: property paths, literals, comparisons, logical
+// operators, arithmetic and string concatenation, and method calls such as
+// list.size().
+package ognl
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+// An Expr is a parsed expression.
+type Expr interface {
+	expr()
+}
+
+// An Ident is a property path, e.g. "name" or "user.name".
+type Ident struct {
+	Path string
+}
+
+// LitKind is the kind of a literal.
+type LitKind int
+
+const (
+	Null LitKind = iota
+	String
+	Number
+	Bool
+)
+
+// A Lit is a literal. Value holds the unquoted text of a string, the text
+// of a number, or "true" or "false".
+type Lit struct {
+	Kind  LitKind
+	Value string
+}
+
+// A Call is a method call, e.g. list.size() or name.trim().
+type Call struct {
+	X      Expr
+	Method string
+	Args   []Expr
+}
+
+// A Unary is "!" or "-" applied to X.
+type Unary struct {
+	Op string
+	X  Expr
+}
+
+// A Binary is X Op Y. The word operators of OGNL are normalized to their
+// symbols: "and" is "&&", "or" is "||", "eq" is "==", "neq" is "!=", "lt"
+// is "<" and so on.
+type Binary struct {
+	Op   string
+	X, Y Expr
+}
+
+func (*Ident) expr()  {}
+func (*Lit) expr()    {}
+func (*Call) expr()   {}
+func (*Unary) expr()  {}
+func (*Binary) expr() {}
+
+// words are the word operators and their symbols.
+var words = map[string]string{
+	"and": "&&", "or": "||", "not": "!",
+	"eq": "==", "neq": "!=", "lt": "<", "lte": "<=", "gt": ">", "gte": ">=",
+}
+
+// Parse parses an expression.
+func Parse(s string) (Expr, error) {
+	p := &parser{src: s}
+	if err := p.lex(); err != nil {
+		return nil, err
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, errors.Errorf("无法解析的表达式：%s", s)
+	}
+	return e, nil
+}
+
+// Walk calls fn for e and, depth first, for every expression below it.
+func Walk(e Expr, fn func(Expr)) {
+	fn(e)
+	switch e := e.(type) {
+	case *Call:
+		Walk(e.X, fn)
+		for _, a := range e.Args {
+			Walk(a, fn)
+		}
+	case *Unary:
+		Walk(e.X, fn)
+	case *Binary:
+		Walk(e.X, fn)
+		Walk(e.Y, fn)
+	}
+}
+
+type token struct {
+	kind byte // 'i' identifier, 's' string, 'n' number, 'o' operator.
+	text string
+}
+
+type parser struct {
+	src  string
+	toks []token
+	pos  int
+}
+
+func (p *parser) lex() error {
+	s := p.src
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || c == '$' || unicode.IsLetter(c):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] == '$' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			p.toks = append(p.toks, token{'i', s[i:j]})
+			i = j
+		case unicode.IsDigit(c):
+			j := i + 1
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			// Java suffixes such as 1L or 2.0d.
+			if j < len(s) && strings.ContainsRune("lLdDfF", rune(s[j])) {
+				j++
+			}
+			p.toks = append(p.toks, token{'n', strings.TrimRight(s[i:j], "lLdDfF")})
+			i = j
+		case c == '\'' || c == '"':
+			j := strings.IndexRune(s[i+1:], c)
+			if j < 0 {
+				return errors.Errorf("无法解析的表达式：%s", s)
+			}
+			p.toks = append(p.toks, token{'s', s[i+1 : i+1+j]})
+			i += j + 2
+		default:
+			op := ""
+			for _, o := range []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", ",", "."} {
+				if strings.HasPrefix(s[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return errors.Errorf("无法解析的表达式：%s", s)
+			}
+			p.toks = append(p.toks, token{'o', op})
+			i += len(op)
+		}
+	}
+	return nil
+}
+
+// peek returns the operator at the current position, with word operators
+// replaced by their symbols, or "".
+func (p *parser) peek() string {
+	if p.pos >= len(p.toks) {
+		return ""
+	}
+	t := p.toks[p.pos]
+	if t.kind == 'o' {
+		return t.text
+	}
+	if t.kind == 'i' {
+		return words[t.text]
+	}
+	return ""
+}
+
+func (p *parser) err() error {
+	return errors.Errorf("无法解析的表达式：%s", p.src)
+}
+
+// binary parses a left-associative chain of the operators ops, whose
+// operands are parsed by next.
+func (p *parser) binary(next func() (Expr, error), ops ...string) (Expr, error) {
+	x, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		found := false
+		for _, o := range ops {
+			found = found || o == op
+		}
+		if !found {
+			return x, nil
+		}
+		p.pos++
+		y, err := next()
+		if err != nil {
+			return nil, err
+		}
+		x = &Binary{Op: op, X: x, Y: y}
+	}
+}
+
+func (p *parser) or() (Expr, error) {
+	return p.binary(p.and, "||")
+}
+
+func (p *parser) and() (Expr, error) {
+	return p.binary(p.equality, "&&")
+}
+
+func (p *parser) equality() (Expr, error) {
+	return p.binary(p.relational, "==", "!=")
+}
+
+func (p *parser) relational() (Expr, error) {
+	return p.binary(p.additive, "<", "<=", ">", ">=")
+}
+
+func (p *parser) additive() (Expr, error) {
+	return p.binary(p.multiplicative, "+", "-")
+}
+
+func (p *parser) multiplicative() (Expr, error) {
+	return p.binary(p.unary, "*", "/", "%")
+}
+
+func (p *parser) unary() (Expr, error) {
+	if op := p.peek(); op == "!" || op == "-" {
+		p.pos++
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &Unary{Op: op, X: x}, nil
+	}
+	return p.postfix()
+}
+
+// postfix parses a primary expression followed by property accesses and
+// method calls.
+func (p *parser) postfix() (Expr, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "." {
+		p.pos++
+		if p.pos >= len(p.toks) || p.toks[p.pos].kind != 'i' {
+			return nil, p.err()
+		}
+		name := p.toks[p.pos].text
+		p.pos++
+		if p.peek() != "(" {
+			id, ok := x.(*Ident)
+			if !ok {
+				return nil, p.err()
+			}
+			x = &Ident{Path: id.Path + "." + name}
+			continue
+		}
+		p.pos++
+		call := &Call{X: x, Method: name}
+		for p.peek() != ")" {
+			arg, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, arg)
+			if p.peek() == "," {
+				p.pos++
+			} else if p.peek() != ")" {
+				return nil, p.err()
+			}
+		}
+		p.pos++
+		x = call
+	}
+	return x, nil
+}
+
+func (p *parser) primary() (Expr, error) {
+	if p.pos >= len(p.toks) {
+		return nil, p.err()
+	}
+	t := p.toks[p.pos]
+	p.pos++
+	switch t.kind {
+	case 's':
+		return &Lit{Kind: String, Value: t.text}, nil
+	case 'n':
+		return &Lit{Kind: Number, Value: t.text}, nil
+	case 'i':
+		switch t.text {
+		case "null":
+			return &Lit{Kind: Null}, nil
+		case "true", "false":
+			return &Lit{Kind: Bool, Value: t.text}, nil
+		}
+		if _, ok := words[t.text]; ok {
+			return nil, p.err()
+		}
+		return &Ident{Path: t.text}, nil
+	}
+	if t.text == "(" {
+		x, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, p.err()
+		}
+		p.pos++
+		return x, nil
+	}
+	return nil, p.err()
+}
@@ -120,6 +120,22 @@ func LetterCamelCase(s string) string {
 func JSONSnakeCase(s string) string {
 	var b []byte
 	for i := 0; i < len(s); i++ { // proto identifiers are always ASCII
+		c := s[i]
+		if isASCIIUpper(c) {
+			b = append(b, '_')
+			c += 'a' - 'A' // convert to lowercase
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+// SnakeCase converts a CamelCase identifier to a snake_case identifier
+// suitable for generated file names. Unlike JSONSnakeCase it never starts
+// the result with an underscore.
+func SnakeCase(s string) string {
+	var b []byte
+	for i := 0; i < len(s); i++ {
 		c := s[i]
 		if isASCIIUpper(c) {
 			if len(b) != 0 {
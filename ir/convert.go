@@ -0,0 +1,45 @@
+package ir
+
+// A Converter is a MapStruct mapper: an interface or abstract class whose
+// abstract methods convert between DOs, VOs and requests.
+type Converter struct {
+	Name    string        `json:"name"`
+	Comment string        `json:"comment,omitempty"`
+	Class   string        `json:"class,omitempty"` // qualified name of the mapper.
+	Methods []*Conversion `json:"methods,omitempty"`
+	Source  string        `json:"source,omitempty"`
+}
+
+// A Conversion is an abstract method of a Converter. Its params and its
+// result reference entities, repeated for lists of them.
+type Conversion struct {
+	Name    string   `json:"name"`
+	Java    string   `json:"java,omitempty"` // name of the Java method.
+	Comment string   `json:"comment,omitempty"`
+	Params  []*Param `json:"params"`
+	Result  *Param   `json:"result"`
+	// Update is set if the result is the @MappingTarget param, updated in
+	// place rather than returned.
+	Update   bool       `json:"update,omitempty"`
+	Mappings []*Mapping `json:"mappings,omitempty"`
+	// Explicit is set if only the targets of Mappings are set, as with
+	// @BeanMapping(ignoreByDefault = true); otherwise the other fields of
+	// the result are set from the source fields of the same name.
+	Explicit bool `json:"explicit,omitempty"`
+	Line     int  `json:"line,omitempty"`
+}
+
+// A Mapping is a @Mapping of a Conversion: where a field of the result is
+// set from. Paths are property names separated by dots, e.g.
+// "owner.name"; source paths start with the name of the param if there
+// are several.
+type Mapping struct {
+	Target string `json:"target"`
+	Source string `json:"source,omitempty"`
+	// Constant is the literal value of the target, unquoted.
+	Constant *string `json:"constant,omitempty"`
+	// Expression is the Java expression of the target, as written. It is
+	// not converted.
+	Expression string `json:"expression,omitempty"`
+	Ignore     bool   `json:"ignore,omitempty"`
+}
@@ -0,0 +1,211 @@
+// Package ir defines the intermediate representation shared by the java2go
+// front-ends and back-ends.
+//
+// Front-ends (Java controllers and VOs, Java DOs, SQL DDL, Java services,
+// MyBatis and MapStruct mappers) parse their sources into a Model;
+// back-ends (protobuf, ent, Kratos service, biz and data code) only ever
+// look at the Model, so any input can be combined with any output.
+package ir
+
+import "strings"
+
+// A Model is everything the front-ends understood from a set of sources.
+type Model struct {
+	Services []*Service `json:"services,omitempty"`
+	Entities []*Entity  `json:"entities,omitempty"`
+	Usecases []*Usecase `json:"usecases,omitempty"`
+	Mappers  []*Mapper  `json:"mappers,omitempty"`
+	// Converters are the MapStruct mappers between the entities.
+	Converters []*Converter `json:"converters,omitempty"`
+}
+
+// Entity returns the entity with the given name, or nil.
+func (m *Model) Entity(name string) *Entity {
+	for _, e := range m.Entities {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// An Entity is a structured type: a Java VO or request class, a DO, a
+// database table or a message synthesized for an endpoint.
+type Entity struct {
+	Name    string   `json:"name"`
+	Comment string   `json:"comment,omitempty"`
+	Table   string   `json:"table,omitempty"`
+	Fields  []*Field `json:"fields,omitempty"`
+	Indexes []*Index `json:"indexes,omitempty"`
+	// SoftDelete is set if rows are deleted by marking them, e.g. with a
+	// MyBatis-Plus @TableLogic field.
+	SoftDelete bool `json:"softDelete,omitempty"`
+	// Annotations are the source annotations of the class, as written.
+	Annotations []string `json:"annotations,omitempty"`
+	// Class is the qualified name of the Java class, if any.
+	Class  string `json:"class,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
+// Deps returns the names of the entities referenced by the fields of e,
+// in field order and without duplicates.
+func (e *Entity) Deps() []string {
+	deps := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, f := range e.Fields {
+		if f.Type.Kind != Message || seen[f.Type.Name] {
+			continue
+		}
+		seen[f.Type.Name] = true
+		deps = append(deps, f.Type.Name)
+	}
+	return deps
+}
+
+// Property returns the field a Java property is mapped onto: the field
+// with that name, else the one whose name or column is the property
+// regardless of case and underscores, as for the tables defined in SQL,
+// whose fields are named after their columns. It returns nil if there is
+// none.
+func (e *Entity) Property(name string) *Field {
+	for _, f := range e.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	key := func(s string) string {
+		return strings.ToLower(strings.Replace(s, "_", "", -1))
+	}
+	for _, f := range e.Fields {
+		if key(f.Name) == key(name) || f.Column != "" && key(f.Column) == key(name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// A Field is a member of an Entity.
+type Field struct {
+	Name     string  `json:"name"`
+	Column   string  `json:"column,omitempty"` // database column, if known.
+	Comment  string  `json:"comment,omitempty"`
+	Type     Type    `json:"type"`
+	Repeated bool    `json:"repeated,omitempty"`
+	ID       bool    `json:"id,omitempty"` // primary key.
+	Nillable bool    `json:"nillable,omitempty"`
+	Default  *string `json:"default,omitempty"` // literal default value, unquoted.
+	MaxLen   int     `json:"maxLen,omitempty"`
+
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// An Index is a database index over one or more columns.
+type Index struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+}
+
+// A Service is a set of endpoints, typically one Spring controller.
+type Service struct {
+	Name      string      `json:"name"`
+	Comment   string      `json:"comment,omitempty"`
+	Path      string      `json:"path,omitempty"` // base path of every endpoint.
+	Endpoints []*Endpoint `json:"endpoints,omitempty"`
+	// Messages are the entities the service's output must define, in
+	// output order: referenced VOs and synthesized request/reply types.
+	Messages    []*Entity `json:"messages,omitempty"`
+	Annotations []string  `json:"annotations,omitempty"`
+	Class       string    `json:"class,omitempty"` // qualified name of the controller.
+	Source      string    `json:"source,omitempty"`
+}
+
+// Sources returns the paths of the sources s is parsed from: its own
+// first, then those of its messages, without duplicates.
+func (s *Service) Sources() []string {
+	srcs := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if path != "" && !seen[path] {
+			seen[path] = true
+			srcs = append(srcs, path)
+		}
+	}
+	add(s.Source)
+	for _, msg := range s.Messages {
+		add(msg.Source)
+	}
+	return srcs
+}
+
+// An Endpoint is a single operation of a Service.
+type Endpoint struct {
+	Name    string   `json:"name"`
+	Comment string   `json:"comment,omitempty"`
+	Method  string   `json:"method,omitempty"` // lower-case HTTP verb.
+	Path    string   `json:"path,omitempty"`   // relative to Service.Path.
+	Params  []*Param `json:"params,omitempty"`
+	Request string   `json:"request"` // entity name.
+	Reply   string   `json:"reply"`   // entity name.
+	// Call is the Java service call the method delegates to, e.g.
+	// "deviceMonitorService.getMonitorConfig(deviceId)".
+	Call string `json:"call,omitempty"`
+
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// Body reports whether the request message of ep is sent as the HTTP
+// body, which is the case for POST, PUT and PATCH; the other methods send
+// its fields as path and query parameters.
+func (ep *Endpoint) Body() bool {
+	switch ep.Method {
+	case "post", "put", "patch":
+		return true
+	}
+	return false
+}
+
+// A Param is a parameter of the original endpoint method.
+type Param struct {
+	Name     string `json:"name"`
+	In       string `json:"in,omitempty"` // body, query or path.
+	Type     Type   `json:"type"`
+	Repeated bool   `json:"repeated,omitempty"`
+	Nillable bool   `json:"nillable,omitempty"` // may be null.
+}
+
+// A Usecase is a unit of business logic, typically a Spring @Service
+// interface and its implementation.
+type Usecase struct {
+	Name    string    `json:"name"`
+	Comment string    `json:"comment,omitempty"`
+	Methods []*Method `json:"methods,omitempty"`
+	Class   string    `json:"class,omitempty"` // qualified name of the interface or class.
+	Impl    string    `json:"impl,omitempty"`  // qualified name of the implementation.
+	Source  string    `json:"source,omitempty"`
+}
+
+// A Method is an operation of a Usecase.
+type Method struct {
+	Name    string   `json:"name"`
+	Java    string   `json:"java,omitempty"` // name of the Java method.
+	Comment string   `json:"comment,omitempty"`
+	Params  []*Param `json:"params,omitempty"`
+	Result  *Param   `json:"result,omitempty"` // nil for void.
+}
+
+// ParamsSuffix returns the name suffix that tells overloaded methods apart
+// by their parameter types, e.g. "ByInt64String".
+func ParamsSuffix(params []*Param) string {
+	if len(params) == 0 {
+		return ""
+	}
+	suffix := "By"
+	for _, p := range params {
+		suffix += p.Type.Title()
+		if p.Repeated {
+			suffix += "List"
+		}
+	}
+	return suffix
+}
@@ -0,0 +1,35 @@
+package ir
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Load reads a model written by Write, possibly edited by hand.
+func Load(r io.Reader) (*Model, error) {
+	m := new(Model)
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Write writes the model as indented JSON.
+func (m *Model) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(m)
+}
+
+// Merge appends the services, entities, usecases, mappers and converters
+// of other to m.
+func (m *Model) Merge(other *Model) {
+	m.Services = append(m.Services, other.Services...)
+	m.Entities = append(m.Entities, other.Entities...)
+	m.Usecases = append(m.Usecases, other.Usecases...)
+	m.Mappers = append(m.Mappers, other.Mappers...)
+	m.Converters = append(m.Converters, other.Converters...)
+}
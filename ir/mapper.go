@@ -0,0 +1,110 @@
+package ir
+
+// A Mapper is a MyBatis mapper: the SQL statements of a mapper XML file
+// and of the annotated methods of its interface.
+type Mapper struct {
+	Name       string       `json:"name"`
+	Namespace  string       `json:"namespace,omitempty"` // qualified name of the mapper interface.
+	Statements []*Statement `json:"statements,omitempty"`
+	// Results are the row types of the select statements.
+	Results []*Result `json:"results,omitempty"`
+	Source  string    `json:"source,omitempty"`
+}
+
+// Result returns the result with the given name, or nil.
+func (m *Mapper) Result(name string) *Result {
+	for _, r := range m.Results {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// Statement kinds.
+const (
+	Select = "select"
+	Insert = "insert"
+	Update = "update"
+	Delete = "delete"
+)
+
+// A Statement is a select, insert, update or delete of a Mapper.
+type Statement struct {
+	Name    string `json:"name"`
+	ID      string `json:"id"`   // statement id, the name of the Java method.
+	Kind    string `json:"kind"` // Select, Insert, Update or Delete.
+	Comment string `json:"comment,omitempty"`
+	// Params are the properties the statement reads, named by their path
+	// as written, e.g. "user.name". Nillable params are compared with null.
+	Params []*Param `json:"params,omitempty"`
+	// Result is the row type of a select: a scalar or a reference to one
+	// of the Results of the mapper.
+	Result *Param `json:"result,omitempty"`
+	// One is set if a select returns a single row rather than a list.
+	One bool `json:"one,omitempty"`
+	// GeneratedKey is set if an insert returns the generated id.
+	GeneratedKey bool `json:"generatedKey,omitempty"`
+	// Positional is set if the params are passed one by one rather than
+	// in a struct, as for the annotated methods of mapper interfaces.
+	Positional bool   `json:"positional,omitempty"`
+	SQL        []*SQL `json:"sql,omitempty"`
+	Line       int    `json:"line,omitempty"`
+}
+
+// A Result maps the columns of a select onto a Go struct: the ent entity
+// named Entity if it names an entity backed by a table, else a struct of
+// its own named Name.
+type Result struct {
+	Name    string    `json:"name"`
+	Entity  string    `json:"entity,omitempty"`
+	Columns []*Column `json:"columns,omitempty"`
+}
+
+// A Column maps a result column onto a field.
+type Column struct {
+	Column string `json:"column"`
+	Field  string `json:"field"` // entity field or property name.
+	Type   Type   `json:"type"`
+}
+
+// SQL node kinds, see the MyBatis XML elements of the same names. The
+// <where> and <set> elements are trims, <include> is replaced by the
+// fragment it includes.
+const (
+	Text      = "text"
+	If        = "if"
+	Choose    = "choose"
+	When      = "when"
+	Otherwise = "otherwise"
+	Trim      = "trim"
+	Foreach   = "foreach"
+	Bind      = "bind"
+)
+
+// An SQL is a node of the dynamic SQL of a Statement.
+type SQL struct {
+	Kind string `json:"kind"`
+	// Text is the SQL of a Text node, with the #{} parameters and ${}
+	// substitutions as written.
+	Text string `json:"text,omitempty"`
+	// Test is the OGNL condition of If and When.
+	Test string `json:"test,omitempty"`
+	// Trim: Prefix and Suffix are added around non-empty content after
+	// removing the overrides.
+	Prefix          string   `json:"prefix,omitempty"`
+	Suffix          string   `json:"suffix,omitempty"`
+	PrefixOverrides []string `json:"prefixOverrides,omitempty"`
+	SuffixOverrides []string `json:"suffixOverrides,omitempty"`
+	// Foreach: Nodes are repeated for every Item of Collection.
+	Collection string `json:"collection,omitempty"`
+	Item       string `json:"item,omitempty"`
+	Index      string `json:"index,omitempty"`
+	Open       string `json:"open,omitempty"`
+	Close      string `json:"close,omitempty"`
+	Separator  string `json:"separator,omitempty"`
+	// Bind: Name is bound to the OGNL expression Value.
+	Name  string `json:"name,omitempty"`
+	Value string `json:"value,omitempty"`
+	Nodes []*SQL `json:"nodes,omitempty"`
+}
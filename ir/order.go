@@ -0,0 +1,82 @@
+package ir
+
+// SortMessages puts the messages of the service in canonical order: the
+// request and reply of every endpoint, in endpoint order, then the
+// messages they depend on, each after every message that uses it. Ties
+// are broken by the order the messages are first referenced in, and a
+// cycle is entered at its first referenced message. Messages nothing
+// refers to come last, in their previous order.
+//
+// The order only depends on the endpoints and the fields of the messages,
+// not on the order the sources were read in.
+func (s *Service) SortMessages() {
+	byName := make(map[string]*Entity, len(s.Messages))
+	for _, msg := range s.Messages {
+		byName[msg.Name] = msg
+	}
+	sorted := make([]*Entity, 0, len(s.Messages))
+	placed := make(map[*Entity]bool)
+	place := func(msg *Entity) {
+		if msg != nil && !placed[msg] {
+			placed[msg] = true
+			sorted = append(sorted, msg)
+		}
+	}
+	for _, ep := range s.Endpoints {
+		place(byName[ep.Request])
+		place(byName[ep.Reply])
+	}
+
+	// the dependencies in the order they are first referenced.
+	deps := make([]*Entity, 0)
+	seen := make(map[*Entity]bool)
+	queue := append([]*Entity{}, sorted...)
+	for i := 0; i < len(queue); i++ {
+		for _, name := range queue[i].Deps() {
+			dep := byName[name]
+			if dep == nil || placed[dep] || seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			deps = append(deps, dep)
+			queue = append(queue, dep)
+		}
+	}
+	// users counts the dependencies not yet placed that use each one.
+	users := make(map[*Entity]int)
+	for _, msg := range deps {
+		for _, name := range msg.Deps() {
+			if dep := byName[name]; dep != nil && dep != msg && !placed[dep] {
+				users[dep]++
+			}
+		}
+	}
+	for len(sorted) < len(s.Messages) {
+		next := (*Entity)(nil)
+		for _, msg := range deps {
+			if placed[msg] {
+				continue
+			}
+			if users[msg] == 0 {
+				next = msg
+				break
+			}
+			if next == nil {
+				next = msg // the first of a cycle, unless a message is ready.
+			}
+		}
+		if next == nil {
+			break
+		}
+		place(next)
+		for _, name := range next.Deps() {
+			if dep := byName[name]; dep != nil && dep != next {
+				users[dep]--
+			}
+		}
+	}
+	for _, msg := range s.Messages {
+		place(msg)
+	}
+	s.Messages = sorted
+}
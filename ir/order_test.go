@@ -0,0 +1,60 @@
+package ir
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMessageOrder(t *testing.T) {
+	msg := func(name string, deps ...string) *Entity {
+		e := &Entity{Name: name, Fields: []*Field{{Name: "name", Type: Scalar(String)}}}
+		for _, dep := range deps {
+			e.Fields = append(e.Fields, &Field{Name: strings.ToLower(dep), Type: Ref(dep)})
+		}
+		return e
+	}
+	messages := func() []*Entity {
+		return []*Entity{
+			msg("ItemVO", "SkuVO"),
+			msg("OrderVO", "UserVO", "ItemVO"),
+			msg("UnusedVO"),
+			msg("UserVO"),
+			msg("SkuVO"),
+			msg("CategoryVO", "CategoryVO"),
+			msg("PageCategoryVO", "CategoryVO"),
+			msg("GetOrderRequest"),
+			msg("GetOrderReply", "OrderVO"),
+			msg("SaveOrderReply"),
+			msg("ListCategoryRequest"),
+			msg("ListCategoryReply", "PageCategoryVO"),
+		}
+	}
+	order := func(msgs []*Entity) string {
+		svc := &Service{
+			Endpoints: []*Endpoint{
+				{Name: "GetOrder", Request: "GetOrderRequest", Reply: "GetOrderReply"},
+				{Name: "SaveOrder", Request: "OrderVO", Reply: "SaveOrderReply"},
+				{Name: "ListCategory", Request: "ListCategoryRequest", Reply: "ListCategoryReply"},
+			},
+			Messages: msgs,
+		}
+		svc.SortMessages()
+		names := make([]string, 0, len(svc.Messages))
+		for _, m := range svc.Messages {
+			names = append(names, m.Name)
+		}
+		return strings.Join(names, " ")
+	}
+	want := "GetOrderRequest GetOrderReply OrderVO SaveOrderReply ListCategoryRequest ListCategoryReply " +
+		"UserVO ItemVO PageCategoryVO SkuVO CategoryVO UnusedVO"
+	if got := order(messages()); got != want {
+		t.Errorf("messages = %s\nwant       %s", got, want)
+	}
+	reversed := messages()
+	for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+		reversed[i], reversed[j] = reversed[j], reversed[i]
+	}
+	if got := order(reversed); got != want {
+		t.Errorf("messages of reversed input = %s\nwant                         %s", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package ir
+
+// Page returns the entity of a page of elements, the reply shape of the
+// DataGrid<T> results of Spring controllers: the elements followed by the
+// pagination counters, e.g. "PageDeviceVO".
+func Page(elem Type) *Entity {
+	name := "Page" + elem.Title()
+	page := &Entity{Name: name, Comment: name}
+	page.Fields = append(page.Fields, elemField(elem))
+	for _, counter := range []string{"pages", "offset", "total", "prePage", "nextPage"} {
+		page.Fields = append(page.Fields, &Field{
+			Name:    counter,
+			Comment: counter,
+			Type:    Scalar(Int32),
+		})
+	}
+	return page
+}
+
+// List returns the entity of a list of elements, e.g. "ListDeviceVO".
+func List(elem Type) *Entity {
+	name := "List" + elem.Title()
+	list := &Entity{Name: name, Comment: name}
+	list.Fields = append(list.Fields, elemField(elem))
+	return list
+}
+
+// elemField returns the repeated field of a page or list.
+func elemField(typ Type) *Field {
+	if typ.Kind == Message {
+		return &Field{Name: typ.Name, Comment: typ.Name, Type: typ, Repeated: true}
+	}
+	return &Field{Name: "data", Type: typ, Repeated: true}
+}
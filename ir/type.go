@@ -0,0 +1,125 @@
+package ir
+
+import (
+	"strings"
+
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/pkg/errors"
+)
+
+// A Kind is the basic category of a Type.
+type Kind string
+
+const (
+	Int32   Kind = "int32"
+	Int64   Kind = "int64"
+	Float32 Kind = "float32"
+	Float64 Kind = "float64"
+	Bool    Kind = "bool"
+	String  Kind = "string"
+	Bytes   Kind = "bytes"
+	Time    Kind = "time"
+	Message Kind = "message" // a reference to an Entity.
+)
+
+// A Type is the type of a Field or Param.
+type Type struct {
+	Kind Kind   `json:"kind"`
+	Name string `json:"name,omitempty"` // entity name, for Message.
+}
+
+// Scalar returns the type of the given scalar kind.
+func Scalar(kind Kind) Type {
+	return Type{Kind: kind}
+}
+
+// Ref returns a type referencing the named entity.
+func Ref(name string) Type {
+	return Type{Kind: Message, Name: name}
+}
+
+func (t Type) String() string {
+	if t.Kind == Message {
+		return t.Name
+	}
+	return string(t.Kind)
+}
+
+// Title returns the name of t as part of a generated name, e.g. "DeviceVO"
+// or "Int64".
+func (t Type) Title() string {
+	if t.Kind == Message {
+		return t.Name
+	}
+	return strs.GoCamelCase(string(t.Kind))
+}
+
+var javaTypes = map[string]Kind{
+	"int":                Int32,
+	"Integer":            Int32,
+	"short":              Int32,
+	"Short":              Int32,
+	"long":               Int64,
+	"Long":               Int64,
+	"float":              Float32,
+	"Float":              Float32,
+	"double":             Float64,
+	"Double":             Float64,
+	"BigDecimal":         Float64,
+	"boolean":            Bool,
+	"Boolean":            Bool,
+	"String":             String,
+	"JSONObject":         String,
+	"JSONArray":          String,
+	"MultipartFile":      String,
+	"HttpServletRequest": String,
+	"byte[]":             Bytes,
+	"Date":               Time,
+	"LocalDate":          Time,
+	"LocalDateTime":      Time,
+}
+
+// JavaType maps a Java scalar type name onto its IR type. An error is
+// returned for any other type, which callers usually treat as a reference
+// to a class.
+func JavaType(name string) (Type, error) {
+	name = strings.TrimSpace(name)
+	if i := strings.LastIndex(name, "."); i >= 0 && !strings.HasSuffix(name, "[]") {
+		name = name[i+1:] // java.util.Date
+	}
+	if kind, ok := javaTypes[name]; ok {
+		return Scalar(kind), nil
+	}
+	if strings.Contains(name, "?") {
+		return Scalar(String), nil
+	}
+	return Type{}, errors.New("没有这个类型：" + name)
+}
+
+// SQLType maps a MySQL column type, e.g. "bigint(20)" or "varchar(64)",
+// onto its IR type.
+func SQLType(column string) (Type, error) {
+	typ := strings.ToLower(strings.TrimSpace(column))
+	if i := strings.IndexAny(typ, "( "); i >= 0 {
+		typ = typ[:i]
+	}
+	switch typ {
+	case "bigint":
+		return Scalar(Int64), nil
+	case "int", "integer", "tinyint", "smallint", "mediumint":
+		return Scalar(Int32), nil
+	case "varchar", "char", "text", "tinytext", "mediumtext", "longtext", "json", "enum":
+		return Scalar(String), nil
+	case "double", "decimal":
+		return Scalar(Float64), nil
+	case "float":
+		return Scalar(Float32), nil
+	case "bit", "bool", "boolean":
+		return Scalar(Bool), nil
+	case "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary":
+		return Scalar(Bytes), nil
+	case "datetime", "timestamp", "date":
+		return Scalar(Time), nil
+	}
+	return Type{}, errors.New("暂不支持的类型：" + column)
+}
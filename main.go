@@ -1,9 +1,17 @@
 package main
 
 import (
+	"github.com/luobote55/java2go/config"
 	"github.com/luobote55/java2go/ctl"
 	"github.com/luobote55/java2go/do"
+	"github.com/luobote55/java2go/emit"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/inspect"
+	"github.com/luobote55/java2go/mapper"
+	"github.com/luobote55/java2go/project"
+	"github.com/luobote55/java2go/service"
 	"github.com/luobote55/java2go/sql"
+	"github.com/luobote55/java2go/verify"
 	"github.com/spf13/cobra"
 	"log"
 )
@@ -12,13 +20,22 @@ var rootCmd = &cobra.Command{
 	Use:     "java2go",
 	Short:   "java2go 2 go.",
 	Long:    `java2go 2 go`,
-	Version: "1.0.0",
+	Version: gen.Version,
 }
 
 func init() {
+	rootCmd.PersistentFlags().StringVar(&config.File, "config", config.File, "project configuration file")
+	rootCmd.PersistentFlags().IntVar(&gen.Workers, "workers", gen.Workers, "number of source files parsed at the same time")
+	rootCmd.AddCommand(config.CmdInit)
 	rootCmd.AddCommand(do.CmdDo)
 	rootCmd.AddCommand(sql.CmdSql)
 	rootCmd.AddCommand(ctl.CmdCtl)
+	rootCmd.AddCommand(inspect.CmdInspect)
+	rootCmd.AddCommand(emit.CmdEmit)
+	rootCmd.AddCommand(project.CmdProject)
+	rootCmd.AddCommand(service.CmdService)
+	rootCmd.AddCommand(mapper.CmdMapper)
+	rootCmd.AddCommand(verify.CmdVerify)
 }
 
 // help:
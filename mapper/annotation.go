@@ -0,0 +1,403 @@
+package mapper
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/classes"
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
+	"github.com/pkg/errors"
+)
+
+// text returns the value of a string element, e.g. "a" + "b", or of an
+// array of strings, e.g. {"a", "b"}, whose strings MyBatis joins with
+// spaces. It reports false if the value is not made of literals.
+func text(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") {
+		lines := make([]string, 0)
+		for _, v := range java.SplitTop(value[1:len(value)-1], ',') {
+			s, ok := text(v)
+			if !ok {
+				return "", false
+			}
+			lines = append(lines, s)
+		}
+		return strings.Join(lines, " "), true
+	}
+	var b strings.Builder
+	for _, v := range java.SplitTop(value, '+') {
+		v = strings.TrimSpace(v)
+		if !strings.HasPrefix(v, `"`) || len(java.Literal(v)) != len(v) {
+			return "", false
+		}
+		b.WriteString(java.Unquote(v))
+	}
+	return b.String(), b.Len() > 0
+}
+
+// statements are the annotations of mapper methods holding their SQL,
+// by statement kind.
+var statements = map[string]string{
+	"Select": ir.Select, "Insert": ir.Insert, "Update": ir.Update, "Delete": ir.Delete,
+}
+
+var (
+	ifaceRe = regexp.MustCompile(`\binterface\s+(\w+)`)
+	baseRe  = regexp.MustCompile(`\bextends\s+(?:[\w.]+\.)?BaseMapper\s*<\s*([\w.]+)\s*>`)
+)
+
+// members walks a Java file declaring a mapper interface and calls f with
+// the abstract methods and constants of the interface. The package and
+// imports of the file are read into scope. It returns the qualified name
+// of the interface, "" if the file declares none, and the entity class of
+// the BaseMapper it extends, if any.
+func members(src string, scope *java.Scope, f func(ns string, d *java.Decl)) (ns, entity string) {
+	for _, d := range java.Declarations(src) {
+		if d.Depth == 0 {
+			if scope.Line(d.Text + ";") {
+				continue
+			}
+			_, rest := java.Annotations(d.Text)
+			if m := ifaceRe.FindStringSubmatch(rest); m != nil && d.Body && ns == "" && !strings.Contains(rest, "@interface") {
+				ns = scope.Qualify(m[1])
+				if m := baseRe.FindStringSubmatch(rest); m != nil {
+					entity = m[1]
+				}
+			}
+			continue
+		}
+		if ns == "" || d.Body {
+			continue // members of a class, or default methods.
+		}
+		f(ns, d)
+	}
+	return ns, entity
+}
+
+// runJava parses a mapper interface into the statements of its annotated
+// methods. It returns nil if the file declares no such methods.
+func (g *Generator) runJava() (*ir.Mapper, error) {
+	b, err := io.ReadAll(g.r)
+	if err != nil {
+		return nil, err
+	}
+	var methods *naming.Namer
+	g.scope = java.NewScope()
+	members(string(b), g.scope, func(ns string, d *java.Decl) {
+		if g.mapper == nil {
+			if !annotated(d.Text) {
+				return
+			}
+			name, err := g.names.Assign(ns, strs.GoCamelCase(java.SimpleName(ns)))
+			if err != nil {
+				g.warnf(d.Line, "%v", err)
+			}
+			g.mapper = &ir.Mapper{Name: name, Namespace: ns, Source: g.path}
+			g.base = strings.TrimSuffix(strings.TrimSuffix(name, "Mapper"), "Dao")
+			g.iface = g.ifaces[ns]
+			methods = naming.NewNamer()
+		}
+		if st := g.method(d, methods); st != nil {
+			g.mapper.Statements = append(g.mapper.Statements, st)
+		}
+	})
+	return g.mapper, nil
+}
+
+// An iface is what the statements of a mapper XML file take from the
+// mapper interface of their namespace.
+type iface struct {
+	methods map[string][]*arg // parameters of the methods without SQL, by name.
+	entity  *ir.Entity        // of the BaseMapper it extends.
+}
+
+// interfaces parses the mapper interfaces among srcs, by namespace, into
+// what the mapper XML files need of them. Overloaded methods are skipped,
+// as MyBatis cannot bind them to a statement either.
+func interfaces(srcs []gen.Source, cfg *config.Config, c *classes.Classes, out *gen.Output) (map[string]*iface, error) {
+	ifaces := make(map[string]*iface)
+	for i, src := range srcs {
+		if !strings.HasSuffix(src.Path, ".java") {
+			continue
+		}
+		b, err := io.ReadAll(src.R)
+		if err != nil {
+			return nil, errors.Wrap(err, src.Path)
+		}
+		srcs[i].R = bytes.NewReader(b)
+		g := &Generator{path: src.Path, out: out, cfg: cfg, scope: java.NewScope()}
+		f := &iface{methods: make(map[string][]*arg)}
+		overloaded := make(map[string]bool)
+		ns, entity := members(string(b), g.scope, func(ns string, d *java.Decl) {
+			if annotated(d.Text) {
+				return
+			}
+			_, s := java.Annotations(d.Text)
+			id, _, args := g.signature(s, d.Line)
+			if id == "" {
+				return
+			}
+			if _, ok := f.methods[id]; ok {
+				overloaded[id] = true
+			}
+			f.methods[id] = args
+		})
+		for id := range overloaded {
+			delete(f.methods, id)
+		}
+		if ns == "" {
+			continue
+		}
+		if entity != "" {
+			f.entity = c.Find(g.scope, entity)
+		}
+		ifaces[ns] = f
+	}
+	return ifaces, nil
+}
+
+// annotated reports whether a member declaration has a statement
+// annotation.
+func annotated(text string) bool {
+	list, _ := java.Annotations(text)
+	for _, a := range list {
+		if _, ok := statements[a.Name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// An arg is a parameter of a mapper method.
+type arg struct {
+	name  string      // the @Param name, else the Java name.
+	decl  string      // declared type.
+	named bool        // annotated with @Param.
+	scope *java.Scope // of the file declaring the method.
+}
+
+// method converts a method annotated with @Select, @Insert, @Update or
+// @Delete into a statement whose parameters are typed by the method
+// signature. It returns nil for other members.
+func (g *Generator) method(d *java.Decl, methods *naming.Namer) *ir.Statement {
+	list, s := java.Annotations(d.Text)
+	id, result, args := g.signature(s, d.Line)
+	if id == "" {
+		return nil // a constant.
+	}
+	st := &ir.Statement{ID: id, Comment: d.Comment, Line: d.Line, Positional: true}
+	sql, generatedKey := "", false
+	for _, a := range list {
+		if kind, ok := statements[a.Name]; ok {
+			st.Kind = kind
+			s, ok := text(java.Attrs(a.Args)["value"])
+			if !ok {
+				g.warnf(d.Line, "暂不支持的SQL，只能是字符串：%s", id)
+			}
+			sql = s
+			continue
+		}
+		switch a.Name {
+		case "Options":
+			generatedKey = java.Attrs(a.Args)["useGeneratedKeys"] == "true"
+		case "SelectProvider", "InsertProvider", "UpdateProvider", "DeleteProvider", "Results", "ResultMap", "SelectKey":
+			g.warnf(d.Line, "暂不支持@%s，已忽略：%s", a.Name, id)
+		}
+	}
+	if st.Kind == "" {
+		return nil
+	}
+	if strings.HasPrefix(result, "<") {
+		g.warnf(d.Line, "暂不支持泛型方法：%s", id)
+		return nil
+	}
+	var err error
+	st.Name, err = methods.Assign(id, strs.GoCamelCase(id))
+	if err != nil {
+		g.warnf(d.Line, "%v", err)
+	}
+	st.SQL = g.script(sql, d.Line)
+	st.GeneratedKey = st.Kind == ir.Insert && generatedKey
+	if st.Kind == ir.Select {
+		class, repeated := java.Element(result)
+		if class == "void" {
+			g.warnf(d.Line, "select没有返回值：%s", id)
+			st.Result = &ir.Param{}
+		} else {
+			st.Result = g.resultType(class, d.Line)
+			st.One = !repeated
+		}
+	}
+	in := g.infer(st)
+	in.signature(args)
+	in.entity(g.entity(st))
+	st.Params = in.params
+	return st
+}
+
+// signature splits the signature of a method, without its annotations,
+// into its name, its declared result type and its parameters. The name is
+// "" if the declaration is not a method.
+func (g *Generator) signature(sig string, line int) (id, result string, args []*arg) {
+	open, end := strings.Index(sig, "("), strings.LastIndex(sig, ")")
+	if open < 0 || end < open || strings.Contains(sig[:open], "=") {
+		return "", "", nil
+	}
+	words := strings.Fields(sig[:open])
+	if len(words) > 0 && words[0] == "public" {
+		words = words[1:]
+	}
+	if len(words) < 2 {
+		return "", "", nil
+	}
+	args = make([]*arg, 0)
+	for _, s := range java.SplitTop(sig[open+1:end], ',') {
+		params, s := java.Annotations(s)
+		s = strings.TrimPrefix(s, "final ")
+		i := strings.LastIndexAny(s, " \t\n")
+		if i < 0 {
+			if strings.TrimSpace(s) != "" {
+				g.warnf(line, "无法识别的参数：%s", s)
+			}
+			continue
+		}
+		a := &arg{name: strings.TrimSpace(s[i+1:]), decl: strings.TrimSpace(s[:i]), scope: g.scope}
+		for _, p := range params {
+			if p.Name == "Param" {
+				a.name, a.named = java.Unquote(strings.TrimSpace(java.Attrs(p.Args)["value"])), true
+			}
+		}
+		args = append(args, a)
+	}
+	return words[len(words)-1], strings.Join(words[:len(words)-1], " "), args
+}
+
+// script converts the SQL of an annotation into SQL nodes. SQL within
+// <script> is dynamic SQL as in mapper XML files.
+func (g *Generator) script(sql string, line int) []*ir.SQL {
+	s := strings.TrimSpace(sql)
+	if !strings.HasPrefix(s, "<script>") {
+		if s = strings.Join(strings.Fields(s), " "); s == "" {
+			return nil
+		}
+		return []*ir.SQL{{Kind: ir.Text, Text: s}}
+	}
+	doc, err := readXML(strings.NewReader(s))
+	if err != nil {
+		g.warnf(line, "无法解析的<script>：%v", err)
+		return nil
+	}
+	root := doc.child("script")
+	shift(root, line-1)
+	return g.sql(root, 0)
+}
+
+// shift moves the line numbers of an element of a <script> to the lines
+// of the Java file.
+func shift(e *element, n int) {
+	e.line += n
+	for _, c := range e.nodes {
+		shift(c, n)
+	}
+}
+
+// signature types the parameters from the parameters of the mapper
+// method. A single parameter without @Param is the parameter object of
+// the statement, as a parameterType; a single collection is also named
+// list, collection or array. Other parameters are named by @Param, by
+// their Java name or by position, param1 for the first one. A DO
+// parameter whose properties are read is passed as its ent entity.
+func (in *inference) signature(args []*arg) {
+	if len(args) == 1 && !args[0].named {
+		a := args[0]
+		class, repeated := java.Element(a.decl)
+		if !repeated {
+			in.parameterType(a.scope, class)
+			return
+		}
+		for _, p := range in.params {
+			switch p.Name {
+			case a.name, "list", "collection", "array":
+				in.typeOf(p, a.scope, class, true)
+			}
+		}
+		return
+	}
+	byName := make(map[string]*arg)
+	for i, a := range args {
+		byName["param"+strconv.Itoa(i+1)] = a
+	}
+	for _, a := range args {
+		byName[a.name] = a
+	}
+	beans := make(map[*ir.Param]*ir.Entity)
+	for _, p := range in.params {
+		a, ok := byName[head(p.Name)]
+		if !ok {
+			in.g.warnf(in.line, "方法没有这个参数：%s", p.Name)
+			continue
+		}
+		class, repeated := java.Element(a.decl)
+		if p.Name == head(p.Name) {
+			in.typeOf(p, a.scope, class, repeated)
+			continue
+		}
+		e := in.g.classes.Find(a.scope, class)
+		f := field(e, strings.TrimPrefix(p.Name, head(p.Name)+"."))
+		switch {
+		case f == nil:
+			in.g.warnf(in.line, "没有找到这个属性：%s", p.Name)
+		case e.Table != "" && !repeated:
+			beans[p] = e
+		default:
+			in.set(p.Name, f.Type, false, fromJava)
+		}
+	}
+	in.beans(beans)
+}
+
+// beans replaces the properties read of DO parameters, e.g. d.name, by
+// the parameters themselves, typed as their DOs.
+func (in *inference) beans(beans map[*ir.Param]*ir.Entity) {
+	if len(beans) == 0 {
+		return
+	}
+	params := make([]*ir.Param, 0, len(in.params))
+	for _, p := range in.params {
+		e, ok := beans[p]
+		if !ok {
+			params = append(params, p)
+			continue
+		}
+		delete(in.byName, p.Name)
+		name := head(p.Name)
+		if in.byName[name] == nil {
+			bean := &ir.Param{Name: name, Type: ir.Ref(e.Name)}
+			in.byName[name] = bean
+			in.strength[name] = fromJava
+			params = append(params, bean)
+		}
+	}
+	in.params = params
+}
+
+// typeOf types a parameter with a scalar Java class or a DO used in a
+// file of the given scope.
+func (in *inference) typeOf(p *ir.Param, scope *java.Scope, class string, repeated bool) {
+	if typ, ok := in.g.javaType(class); ok {
+		in.set(p.Name, typ, repeated, fromJava)
+	} else if e := in.g.classes.Find(scope, class); e != nil && e.Table != "" {
+		in.set(p.Name, ir.Ref(e.Name), repeated, fromJava)
+	} else if !isMap(class) {
+		in.g.warnf(in.line, "暂不支持的参数类型：%s", class)
+	}
+}
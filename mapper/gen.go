@@ -0,0 +1,265 @@
+package mapper
+
+import (
+	"encoding/xml"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/classes"
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
+)
+
+// An element is a node of a mapper XML file. Text nodes have no name.
+type element struct {
+	name    string
+	attrs   map[string]string
+	text    string
+	nodes   []*element
+	line    int
+	comment string // first line of the XML comment right before the element.
+}
+
+// readXML reads an XML document into a tree below an unnamed root.
+func readXML(r io.Reader) (*element, error) {
+	d := xml.NewDecoder(r)
+	root := &element{}
+	stack := []*element{root}
+	comment := ""
+	for {
+		line, _ := d.InputPos()
+		tok, err := d.Token()
+		if err == io.EOF {
+			return root, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &element{name: t.Name.Local, attrs: make(map[string]string), line: line, comment: comment}
+			for _, a := range t.Attr {
+				e.attrs[a.Name.Local] = a.Value
+			}
+			top.nodes = append(top.nodes, e)
+			stack = append(stack, e)
+			comment = ""
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if n := len(top.nodes); n > 0 && top.nodes[n-1].name == "" {
+				top.nodes[n-1].text += string(t)
+			} else {
+				top.nodes = append(top.nodes, &element{text: string(t), line: line})
+			}
+			if strings.TrimSpace(string(t)) != "" {
+				comment = ""
+			}
+		case xml.Comment:
+			comment = ""
+			for _, s := range strings.Split(string(t), "\n") {
+				if s = strings.TrimSpace(s); s != "" {
+					comment = s
+					break
+				}
+			}
+		}
+	}
+}
+
+// child returns the first child element with the given name, or nil.
+func (e *element) child(name string) *element {
+	for _, c := range e.nodes {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// A Generator represents the state of a single mapper XML file being
+// converted into a mapper.
+type Generator struct {
+	r       io.Reader
+	path    string // full rooted path name.
+	out     *gen.Output
+	cfg     *config.Config
+	classes *classes.Classes
+	names   *naming.Namer // mapper names.
+	results *naming.Namer // result struct names.
+	ifaces  map[string]*iface
+
+	iface *iface      // of the namespace, nil if there is none.
+	scope *java.Scope // of a Java file, nil for XML files.
+
+	mapper     *ir.Mapper
+	base       string // mapper name without the Mapper suffix, e.g. Device.
+	fragments  map[string]*element
+	resultMaps map[string]*element
+}
+
+// run parses the file into a mapper. It returns nil if the file is not a
+// MyBatis mapper.
+func (g *Generator) run() (*ir.Mapper, error) {
+	doc, err := readXML(g.r)
+	if err != nil {
+		return nil, err
+	}
+	root := doc.child("mapper")
+	if root == nil {
+		return nil, nil
+	}
+	ns := root.attrs["namespace"]
+	simple := java.SimpleName(ns)
+	if simple == "" {
+		simple = strings.TrimSuffix(path.Base(g.path), path.Ext(g.path))
+	}
+	key := ns
+	if key == "" {
+		key = g.path
+	}
+	name, err := g.names.Assign(key, strs.GoCamelCase(simple))
+	if err != nil {
+		g.warnf(root.line, "%v", err)
+	}
+	g.mapper = &ir.Mapper{Name: name, Namespace: ns, Source: g.path}
+	g.base = strings.TrimSuffix(strings.TrimSuffix(name, "Mapper"), "Dao")
+	g.iface = g.ifaces[ns]
+	g.fragments = make(map[string]*element)
+	g.resultMaps = make(map[string]*element)
+	for _, c := range root.nodes {
+		switch c.name {
+		case "sql":
+			g.fragments[c.attrs["id"]] = c
+		case "resultMap":
+			g.resultMaps[c.attrs["id"]] = c
+		}
+	}
+	methods := naming.NewNamer()
+	for _, c := range root.nodes {
+		switch c.name {
+		case ir.Select, ir.Insert, ir.Update, ir.Delete:
+		case "parameterMap":
+			g.warnf(c.line, "暂不支持<parameterMap>，已忽略：%s", c.attrs["id"])
+			continue
+		default:
+			continue
+		}
+		st := &ir.Statement{ID: c.attrs["id"], Kind: c.name, Comment: c.comment, Line: c.line}
+		st.Name, err = methods.Assign(st.ID, strs.GoCamelCase(st.ID))
+		if err != nil {
+			g.warnf(c.line, "%v", err)
+		}
+		st.SQL = g.sql(c, 0)
+		st.GeneratedKey = c.name == ir.Insert && c.attrs["useGeneratedKeys"] == "true"
+		if c.name == ir.Select {
+			st.Result = g.result(c)
+		}
+		g.params(st, c.attrs["parameterType"])
+		g.mapper.Statements = append(g.mapper.Statements, st)
+	}
+	return g.mapper, nil
+}
+
+func (g *Generator) warnf(line int, format string, args ...interface{}) {
+	g.out.Warnf(g.path, line, format, args...)
+}
+
+// local returns the id of a fragment or result map referenced as id or as
+// namespace.id from within the mapper.
+func (g *Generator) local(ref string) string {
+	return strings.TrimPrefix(ref, g.mapper.Namespace+".")
+}
+
+// maxInclude bounds the nesting of <include>, which guards against
+// fragments including themselves.
+const maxInclude = 8
+
+// sql converts the content of a statement or fragment into SQL nodes.
+// Fragments are inlined where they are included.
+func (g *Generator) sql(e *element, depth int) []*ir.SQL {
+	nodes := make([]*ir.SQL, 0)
+	for _, c := range e.nodes {
+		var n *ir.SQL
+		switch c.name {
+		case "":
+			if text := strings.Join(strings.Fields(c.text), " "); text != "" {
+				n = &ir.SQL{Kind: ir.Text, Text: text}
+			}
+		case "if":
+			n = &ir.SQL{Kind: ir.If, Test: c.attrs["test"]}
+		case "choose":
+			n = &ir.SQL{Kind: ir.Choose}
+		case "when":
+			n = &ir.SQL{Kind: ir.When, Test: c.attrs["test"]}
+		case "otherwise":
+			n = &ir.SQL{Kind: ir.Otherwise}
+		case "where":
+			n = &ir.SQL{Kind: ir.Trim, Prefix: "WHERE", PrefixOverrides: []string{"AND ", "OR "}}
+		case "set":
+			n = &ir.SQL{Kind: ir.Trim, Prefix: "SET", SuffixOverrides: []string{","}}
+		case "trim":
+			n = &ir.SQL{
+				Kind:            ir.Trim,
+				Prefix:          c.attrs["prefix"],
+				Suffix:          c.attrs["suffix"],
+				PrefixOverrides: overrides(c.attrs["prefixOverrides"]),
+				SuffixOverrides: overrides(c.attrs["suffixOverrides"]),
+			}
+		case "foreach":
+			n = &ir.SQL{
+				Kind:       ir.Foreach,
+				Collection: c.attrs["collection"],
+				Item:       c.attrs["item"],
+				Index:      c.attrs["index"],
+				Open:       c.attrs["open"],
+				Close:      c.attrs["close"],
+				Separator:  c.attrs["separator"],
+			}
+		case "bind":
+			nodes = append(nodes, &ir.SQL{Kind: ir.Bind, Name: c.attrs["name"], Value: c.attrs["value"]})
+			continue
+		case "include":
+			if c.child("property") != nil {
+				g.warnf(c.line, "暂不支持<include>的<property>，已忽略：%s", c.attrs["refid"])
+			}
+			frag := g.fragments[g.local(c.attrs["refid"])]
+			if frag == nil {
+				g.warnf(c.line, "没有找到这个sql片段：%s", c.attrs["refid"])
+			} else if depth >= maxInclude {
+				g.warnf(c.line, "sql片段嵌套太深：%s", c.attrs["refid"])
+			} else {
+				nodes = append(nodes, g.sql(frag, depth+1)...)
+			}
+			continue
+		case "selectKey":
+			g.warnf(c.line, "暂不支持<selectKey>，已忽略")
+			continue
+		default:
+			g.warnf(c.line, "暂不支持的元素：<%s>", c.name)
+			continue
+		}
+		if n == nil {
+			continue
+		}
+		if n.Kind != ir.Text {
+			n.Nodes = g.sql(c, depth)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes
+}
+
+// overrides splits the prefixOverrides or suffixOverrides of a <trim>.
+func overrides(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "|")
+}
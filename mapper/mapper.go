@@ -0,0 +1,228 @@
+// Package mapper converts MyBatis mapper XML files and annotated mapper
+// interfaces into Go functions that run their statements with database/sql.
+package mapper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/ctl"
+	"github.com/luobote55/java2go/do"
+	"github.com/luobote55/java2go/emit"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/classes"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdMapper represents the mapper command.
+var CmdMapper = &cobra.Command{
+	Use:   "mapper [xml_dir] [go_dir]",
+	Short: "Generate Go query functions from MyBatis xxxMapper.xml and annotated xxxMapper.java",
+	Long:  "Generate Go query functions from MyBatis xxxMapper.xml and the @Select, @Insert, @Update and @Delete methods of xxxMapper.java. Example: ./j2g.exe mapper -m ./test/do ./test/mapper ./internal/data",
+	Args:  cobra.MaximumNArgs(2),
+	Run:   run,
+}
+
+var (
+	xmlPaths   []string
+	modelPaths []string
+	goPath     string
+)
+
+func init() {
+	CmdMapper.Flags().StringSliceVarP(&xmlPaths, "xml_path", "p", []string{"./"}, "directories or jars of the mapper XML files and mapper interfaces")
+	CmdMapper.Flags().StringSliceVarP(&modelPaths, "model_path", "m", nil, "source directories or sources jars of the DO and VO classes the mappers read and return")
+	CmdMapper.Flags().StringVarP(&goPath, "output", "o", "./", "data directory")
+}
+
+// run resolves the paths from, in order of precedence, the arguments, the
+// flags given on the command line, java2go.yaml and the flag defaults.
+func run(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load(config.File)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	flags := cmd.Flags()
+	roots := cfg.Mapper.Sources
+	if flags.Changed("xml_path") || len(roots) == 0 {
+		roots = xmlPaths
+	}
+	models := cfg.Mapper.Models
+	if flags.Changed("model_path") {
+		models = modelPaths
+	}
+	goo := cfg.Mapper.Output
+	if flags.Changed("output") || goo == "" {
+		goo = goPath
+	}
+	if len(args) > 0 {
+		roots = []string{strings.TrimSpace(args[0])}
+	}
+	if len(args) > 1 {
+		goo = strings.TrimSpace(args[1])
+	}
+	srcs, err := gen.ReadSourceRoots(roots, ".xml", nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	ifaces, err := gen.ReadSourceRoots(roots, ".java", nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	srcs = append(srcs, ifaces...)
+	modelSrcs, err := gen.ReadSourceRoots(models, ".java", nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	out, err := Generate(srcs, modelSrcs, cfg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	gen.PrintDiagnostics(out.Diagnostics)
+	if err = os.MkdirAll(goo, 0755); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err = gen.WriteFiles(goo, out.Files, cfg.Overwrite); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Generate converts the mapper XML files and mapper interfaces into Go
+// files of the data layer.
+// The classes their statements read and return are looked up in the
+// model classes.
+func Generate(srcs, models []gen.Source, cfg *config.Config) (*gen.Output, error) {
+	out := new(gen.Output)
+	m, err := Parse(srcs, models, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	emit.Queries(m, cfg, out)
+	return out, nil
+}
+
+// Parse parses the mapper XML files and mapper interfaces into mappers and
+// the model classes into the entities they use: @TableName classes as DOs,
+// the others as VOs. Files other than MyBatis mappers are skipped.
+func Parse(srcs, models []gen.Source, cfg *config.Config, out *gen.Output) (*ir.Model, error) {
+	dos := make([]gen.Source, 0)
+	vos := make([]gen.Source, 0)
+	for _, src := range models {
+		b, err := io.ReadAll(src.R)
+		if err != nil {
+			return nil, err
+		}
+		src.R = bytes.NewReader(b)
+		if bytes.Contains(b, []byte("@TableName")) {
+			dos = append(dos, src)
+		} else {
+			vos = append(vos, src)
+		}
+	}
+	m, err := ctl.Parse(nil, vos, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := do.Parse(dos, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	m.Merge(tables)
+	if err = Add(srcs, m, cfg, out); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Add parses the mapper XML files and mapper interfaces into mappers of
+// m. The classes their statements read and return are looked up in the
+// entities of m. The statements of a mapper XML file are typed by the
+// methods of the mapper interface of its namespace.
+func Add(srcs []gen.Source, m *ir.Model, cfg *config.Config, out *gen.Output) error {
+	c := classes.New(m, cfg.Naming.Entities)
+	names, results := naming.NewNamer(), naming.NewNamer()
+	srcs = append([]gen.Source(nil), srcs...)
+	ifaces, err := interfaces(srcs, cfg, c, out)
+	if err != nil {
+		return err
+	}
+	for _, src := range srcs {
+		mp, err := generate(src, cfg, c, ifaces, names, results, out)
+		if err != nil {
+			return err
+		}
+		if mp != nil {
+			m.Mappers = merge(m.Mappers, mp, out)
+		}
+	}
+	return nil
+}
+
+// merge adds mp to mappers. The statements of a mapper XML file and the
+// annotated methods of its interface share a namespace and are merged
+// into one mapper.
+func merge(mappers []*ir.Mapper, mp *ir.Mapper, out *gen.Output) []*ir.Mapper {
+	for _, old := range mappers {
+		if mp.Namespace == "" || old.Namespace != mp.Namespace {
+			continue
+		}
+		names := make(map[string]bool)
+		for _, st := range old.Statements {
+			names[st.Name] = true
+		}
+		for _, st := range mp.Statements {
+			if names[st.Name] {
+				out.Warnf(mp.Source, st.Line, "语句重复定义，已忽略：%s", st.ID)
+				continue
+			}
+			old.Statements = append(old.Statements, st)
+		}
+		for _, r := range mp.Results {
+			if old.Result(r.Name) == nil {
+				old.Results = append(old.Results, r)
+			}
+		}
+		return mappers
+	}
+	return append(mappers, mp)
+}
+
+// generate parses the specified XML file or Java interface into a mapper.
+func generate(src gen.Source, cfg *config.Config, c *classes.Classes, ifaces map[string]*iface, names, results *naming.Namer, out *gen.Output) (*ir.Mapper, error) {
+	b, err := io.ReadAll(src.R)
+	if err != nil {
+		return nil, err
+	}
+	g := &Generator{
+		r:       bytes.NewReader(b),
+		path:    src.Path,
+		out:     out,
+		cfg:     cfg,
+		classes: c,
+		ifaces:  ifaces,
+		names:   names,
+		results: results,
+	}
+	run := g.run
+	if strings.HasSuffix(src.Path, ".java") {
+		run = g.runJava
+	}
+	mp, err := run()
+	if err != nil {
+		return nil, errors.Wrap(err, src.Path)
+	}
+	return mp, nil
+}
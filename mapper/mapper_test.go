@@ -0,0 +1,146 @@
+package mapper
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+)
+
+// source returns an in-memory source named path.
+func source(path, src string) gen.Source {
+	return gen.Source{Path: path, R: bytes.NewReader([]byte(src))}
+}
+
+func TestMappers(t *testing.T) {
+	const xml = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE mapper PUBLIC "-//mybatis.org//DTD Mapper 3.0//EN" "http://mybatis.org/dtd/mybatis-3-mapper.dtd">
+<mapper namespace="com.example.mapper.DeviceMapper">
+    <!-- 按名称查询 -->
+    <select id="selectByName" resultType="com.example.entity.DeviceDO">
+        SELECT * FROM device
+        <where>
+            <if test="name != null and name != ''">AND name = #{name}</if>
+            <if test="ids != null">
+                AND id IN
+                <foreach collection="ids" item="id" open="(" separator="," close=")">#{id,jdbcType=BIGINT}</foreach>
+            </if>
+        </where>
+        ORDER BY ${order}
+    </select>
+    <insert id="insertAll">
+        INSERT INTO device (name) VALUES
+        <foreach collection="list" item="d" separator=",">(#{d.name})</foreach>
+    </insert>
+    <insert id="insertLabels">
+        INSERT INTO label (name) VALUES
+        <foreach collection="labels" item="l" separator=",">(#{l.label})</foreach>
+    </insert>
+</mapper>
+`
+	const iface = "package com.example.mapper;\n" +
+		"\n" +
+		"public interface DeviceMapper {\n" +
+		"    int insertAll(List<DeviceDO> list);\n" +
+		"}\n"
+	const do = "@TableName(\"device\")\n" +
+		"public class DeviceDO {\n" +
+		"    @ApiModelProperty(value = \"名称\")\n" +
+		"    @TableField(\"name\")\n" +
+		"    private String name;\n" +
+		"}\n"
+	srcs := func() []gen.Source {
+		return []gen.Source{source("DeviceMapper.xml", xml), source("DeviceMapper.java", iface)}
+	}
+	out := new(gen.Output)
+	m, err := Parse(srcs(), []gen.Source{source("DeviceDO.java", do)}, config.Default(), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Mappers) != 1 || len(m.Mappers[0].Statements) != 3 {
+		t.Fatalf("mappers = %+v", m.Mappers)
+	}
+	st := m.Mappers[0].Statements[0]
+	if st.Name != "SelectByName" || st.Comment != "按名称查询" || st.Result.Type.Name != "Device" {
+		t.Errorf("statement = %+v", st)
+	}
+	if diags := out.Diagnostics; len(diags) != 2 || !strings.Contains(diags[0].Message, "${order}") || !strings.Contains(diags[1].Message, "#{l.label}") {
+		t.Errorf("got diagnostics %v, want the ${order} substitution and #{l.label}", diags)
+	}
+	res, err := Generate(srcs(), []gen.Source{source("DeviceDO.java", do)}, config.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Files) != 2 {
+		t.Fatalf("got %d files, want the mapper and the helpers", len(res.Files))
+	}
+	for _, want := range []string{
+		"func (m *DeviceMapper) SelectByName(ctx context.Context, p *DeviceSelectByNameParams) ([]*ent.Device, error) {",
+		"Name  *string",
+		"Ids   []int64",
+		`if p.Name != nil && *p.Name != "" {`,
+		"q.WriteString(fmt.Sprint(p.Order))",
+		"dest[i] = &v.Name",
+		"func (m *DeviceMapper) InsertAll(ctx context.Context, list []*ent.Device) (int64, error) {",
+		"args = append(args, d.Name)",
+		"func (m *DeviceMapper) InsertLabels(ctx context.Context, labels []interface{}) (int64, error) {",
+		`return 0, errors.New("insertLabels: #{l.label} not translated")`,
+	} {
+		if !strings.Contains(string(res.Files[0].Content()), want) {
+			t.Errorf("generated mapper does not contain %q", want)
+		}
+	}
+}
+
+func TestAnnotatedMappers(t *testing.T) {
+	const iface = "package com.example.mapper;\n" +
+		"\n" +
+		"@Mapper\n" +
+		"public interface DeviceMapper extends BaseMapper<DeviceDO> {\n" +
+		"    /** 按名称查询 */\n" +
+		"    @Select(\"SELECT * FROM device WHERE name = #{name} AND type = ${type}\")\n" +
+		"    DeviceDO selectByName(@Param(\"name\") String name, @Param(\"type\") Integer type);\n" +
+		"\n" +
+		"    @Update({\"<script>UPDATE device SET name = #{d.name}\",\n" +
+		"            \"<if test='ids != null'>WHERE id IN <foreach collection='ids' item='id' open='(' separator=',' close=')'>#{id}</foreach></if></script>\"})\n" +
+		"    int rename(@Param(\"d\") DeviceDO d, @Param(\"ids\") List<Long> ids);\n" +
+		"\n" +
+		"    List<DeviceDO> selectAll();\n" +
+		"}\n"
+	const do = "@TableName(\"device\")\n" +
+		"public class DeviceDO {\n" +
+		"    @ApiModelProperty(value = \"名称\")\n" +
+		"    @TableField(\"name\")\n" +
+		"    private String name;\n" +
+		"}\n"
+	cfg := config.Default()
+	cfg.Go.Module = "example.com/demo"
+	res, err := Generate([]gen.Source{source("DeviceMapper.java", iface)}, []gen.Source{source("DeviceDO.java", do)}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Diagnostics) != 1 || !strings.Contains(res.Diagnostics[0].Message, "${type}") {
+		t.Errorf("got diagnostics %v, want the ${type} substitution", res.Diagnostics)
+	}
+	if len(res.Files) != 2 {
+		t.Fatalf("got %d files, want the mapper and the helpers", len(res.Files))
+	}
+	got := string(res.Files[0].Content())
+	for _, want := range []string{
+		"// SelectByName 按名称查询",
+		"func (m *DeviceMapper) SelectByName(ctx context.Context, name string, type_ int32) (*ent.Device, error) {",
+		"q.WriteString(fmt.Sprint(type_))",
+		"func (m *DeviceMapper) Rename(ctx context.Context, d *ent.Device, ids []int64) (int64, error) {",
+		"args = append(args, d.Name)",
+		"for i1, id := range ids {",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("generated mapper does not contain %q", want)
+		}
+	}
+	if strings.Contains(got, "SelectAll") {
+		t.Error("generated a method without SQL annotation")
+	}
+}
@@ -0,0 +1,375 @@
+package mapper
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/internal/ognl"
+	"github.com/luobote55/java2go/ir"
+)
+
+// paramRe matches the #{} parameters and ${} substitutions of SQL text.
+var paramRe = regexp.MustCompile(`([#$])\{([^}]*)\}`)
+
+// Strengths of the evidence for the type of a parameter; stronger evidence
+// replaces weaker.
+const (
+	fromLiteral = iota + 1 // compared with a literal in a test.
+	fromEntity             // named after a field of the entity of the statement.
+	fromOption             // jdbcType or javaType of a #{} parameter.
+	fromJava               // parameterType or the mapper interface.
+)
+
+// An inference collects the parameters of a statement from the properties
+// its SQL reads, and their types from how it reads them.
+type inference struct {
+	g        *Generator
+	line     int
+	params   []*ir.Param
+	byName   map[string]*ir.Param
+	strength map[string]int
+	items    []*item
+}
+
+// An item is a property read of the items of a collection, e.g. the
+// item.name of <foreach collection="list" item="item">.
+type item struct {
+	coll    string // the collection parameter.
+	prop    string
+	warning string // reported if the property cannot be resolved.
+}
+
+// params sets the parameters of st. The properties read by its SQL are
+// typed by parameterType if it is set, else by the method of the mapper
+// interface, then by the jdbcType and javaType options of #{}, the fields
+// of the entity of the statement and the literals they are compared with
+// in tests. Properties without any of these are left untyped.
+func (g *Generator) params(st *ir.Statement, parameterType string) {
+	in := g.infer(st)
+	if parameterType != "" {
+		in.parameterType(nil, parameterType)
+	} else if args, ok := g.iface.method(st.ID); ok {
+		in.signature(args)
+	}
+	in.entity(g.entity(st))
+	st.Params = in.params
+}
+
+// method returns the parameters of the method of the interface with the
+// given name.
+func (f *iface) method(id string) ([]*arg, bool) {
+	if f == nil {
+		return nil, false
+	}
+	args, ok := f.methods[id]
+	return args, ok
+}
+
+// entity returns the DO a statement is assumed to read or write if its
+// parameters are not typed otherwise: the entity of its rows, else that of
+// the BaseMapper the mapper interface extends. It returns nil if there is
+// none.
+func (g *Generator) entity(st *ir.Statement) *ir.Entity {
+	var e *ir.Entity
+	if st.Result != nil && st.Result.Type.Kind == ir.Message {
+		if r := g.mapper.Result(st.Result.Type.Name); r != nil && r.Entity != "" {
+			e = g.classes.Entity(r.Entity)
+		}
+	}
+	if e == nil && g.iface != nil {
+		e = g.iface.entity
+	}
+	if e == nil || e.Table == "" {
+		return nil
+	}
+	return e
+}
+
+// entity types the parameters left untyped after the fields of e, and the
+// collections whose items are only read for fields of e as lists of e. It
+// then warns about the properties of items it cannot resolve.
+func (in *inference) entity(e *ir.Entity) {
+	if e != nil {
+		for _, p := range in.params {
+			if in.strength[p.Name] > 0 {
+				continue
+			}
+			if !p.Repeated {
+				if f := field(e, p.Name); f != nil {
+					in.set(p.Name, f.Type, false, fromEntity)
+				}
+				continue
+			}
+			read := false
+			for _, it := range in.items {
+				if it.coll != p.Name {
+					continue
+				}
+				if read = field(e, it.prop) != nil; !read {
+					break
+				}
+			}
+			if read {
+				in.set(p.Name, ir.Ref(e.Name), true, fromEntity)
+			}
+		}
+	}
+	for _, it := range in.items {
+		p := in.byName[it.coll]
+		if p == nil || p.Type.Kind != ir.Message || field(in.g.classes.Entity(p.Type.Name), it.prop) == nil {
+			in.g.warnf(in.line, "%s", it.warning)
+		}
+	}
+}
+
+// item records a property read of the items of a collection, warning at
+// once if they are not items of a collection.
+func (in *inference) item(coll, path, warning string) {
+	if coll == "" {
+		in.g.warnf(in.line, "%s", warning)
+		return
+	}
+	in.items = append(in.items, &item{coll: coll, prop: strings.TrimPrefix(path, head(path)+"."), warning: warning})
+}
+
+// infer collects the parameters read by the SQL of st, typed by the
+// options and literals of the SQL alone.
+func (g *Generator) infer(st *ir.Statement) *inference {
+	in := &inference{
+		g:        g,
+		line:     st.Line,
+		byName:   make(map[string]*ir.Param),
+		strength: make(map[string]int),
+	}
+	in.nodes(st.SQL, make(map[string]string))
+	return in
+}
+
+// ref returns the parameter of the property path, adding it on first use.
+func (in *inference) ref(path string) *ir.Param {
+	if p, ok := in.byName[path]; ok {
+		return p
+	}
+	p := &ir.Param{Name: path}
+	in.params = append(in.params, p)
+	in.byName[path] = p
+	return p
+}
+
+// set types the parameter of path unless stronger evidence typed it.
+func (in *inference) set(path string, typ ir.Type, repeated bool, strength int) {
+	p := in.ref(path)
+	if in.strength[path] >= strength {
+		return
+	}
+	in.strength[path] = strength
+	p.Type = typ
+	p.Repeated = p.Repeated || repeated
+}
+
+// nodes walks SQL nodes. scope maps the foreach items in scope to their
+// collections, and foreach indexes and bound names to "".
+func (in *inference) nodes(nodes []*ir.SQL, scope map[string]string) {
+	for _, n := range nodes {
+		switch n.Kind {
+		case ir.Text:
+			in.text(n.Text, scope)
+		case ir.If, ir.When:
+			in.test(n.Test, scope)
+		case ir.Foreach:
+			if _, ok := scope[head(n.Collection)]; ok {
+				in.g.warnf(in.line, "暂不支持嵌套的集合：%s", n.Collection)
+			} else if n.Collection != "" {
+				in.ref(n.Collection).Repeated = true
+			}
+			inner := make(map[string]string)
+			for k, v := range scope {
+				inner[k] = v
+			}
+			if n.Item != "" {
+				inner[n.Item] = n.Collection
+			}
+			if n.Index != "" {
+				inner[n.Index] = ""
+			}
+			in.nodes(n.Nodes, inner)
+			continue
+		case ir.Bind:
+			in.test(n.Value, scope)
+			scope[n.Name] = ""
+		}
+		in.nodes(n.Nodes, scope)
+	}
+}
+
+// text collects the #{} parameters and ${} substitutions of SQL text.
+func (in *inference) text(text string, scope map[string]string) {
+	for _, m := range paramRe.FindAllStringSubmatch(text, -1) {
+		name, opts := option(m[2])
+		if m[1] == "$" {
+			in.g.warnf(in.line, "${%s} 是字符串替换，有SQL注入的风险", name)
+		}
+		if name == "" {
+			continue
+		}
+		typ, ok := in.option(opts)
+		if coll, inScope := scope[head(name)]; inScope {
+			if name != head(name) {
+				in.item(coll, name, "暂不支持的参数："+m[0])
+			} else if ok && coll != "" {
+				in.set(coll, typ, true, fromOption)
+			}
+			continue
+		}
+		in.ref(name)
+		if ok {
+			in.set(name, typ, false, fromOption)
+		}
+	}
+}
+
+// option splits a #{} parameter, e.g. "id,jdbcType=BIGINT", into the
+// property and its options.
+func option(param string) (name string, opts map[string]string) {
+	parts := strings.Split(param, ",")
+	opts = make(map[string]string)
+	for _, p := range parts[1:] {
+		if i := strings.Index(p, "="); i >= 0 {
+			opts[strings.TrimSpace(p[:i])] = strings.TrimSpace(p[i+1:])
+		}
+	}
+	return strings.TrimSpace(parts[0]), opts
+}
+
+// option returns the type given by the javaType or jdbcType option.
+func (in *inference) option(opts map[string]string) (ir.Type, bool) {
+	if t, ok := opts["javaType"]; ok {
+		if typ, ok := in.g.javaType(t); ok {
+			return typ, true
+		}
+	}
+	if t, ok := opts["jdbcType"]; ok {
+		return jdbcType(t)
+	}
+	return ir.Type{}, false
+}
+
+// test collects the properties read by an OGNL test or bind value.
+func (in *inference) test(test string, scope map[string]string) {
+	e, err := ognl.Parse(test)
+	if err != nil {
+		in.g.warnf(in.line, "%v", err)
+		return
+	}
+	param := func(x ognl.Expr) *ir.Param {
+		id, ok := x.(*ognl.Ident)
+		if !ok {
+			return nil
+		}
+		if coll, inScope := scope[head(id.Path)]; inScope {
+			if id.Path != head(id.Path) {
+				in.item(coll, id.Path, "暂不支持的表达式："+test)
+			}
+			return nil
+		}
+		return in.ref(id.Path)
+	}
+	ognl.Walk(e, func(x ognl.Expr) {
+		switch x := x.(type) {
+		case *ognl.Ident:
+			param(x)
+		case *ognl.Call:
+			p := param(x.X)
+			if p == nil {
+				return
+			}
+			switch x.Method {
+			case "size":
+				p.Repeated = true
+			case "length", "trim":
+				in.set(p.Name, ir.Scalar(ir.String), false, fromLiteral)
+			}
+		case *ognl.Binary:
+			p, lit := param(x.X), x.Y
+			if p == nil {
+				p, lit = param(x.Y), x.X
+			}
+			l, ok := lit.(*ognl.Lit)
+			if p == nil || !ok {
+				return
+			}
+			switch l.Kind {
+			case ognl.Null:
+				p.Nillable = true
+			case ognl.String:
+				in.set(p.Name, ir.Scalar(ir.String), false, fromLiteral)
+			case ognl.Number:
+				kind := ir.Int64
+				if strings.Contains(l.Value, ".") {
+					kind = ir.Float64
+				}
+				in.set(p.Name, ir.Scalar(kind), false, fromLiteral)
+			case ognl.Bool:
+				in.set(p.Name, ir.Scalar(ir.Bool), false, fromLiteral)
+			}
+		}
+	})
+}
+
+// parameterType types the parameters from the parameterType of the
+// statement, or the class of the single parameter of its method, used in
+// a file of the given scope: a scalar types the single parameter, a class
+// the parameters named after its fields.
+func (in *inference) parameterType(scope *java.Scope, class string) {
+	if typ, ok := in.g.javaType(class); ok {
+		if len(in.params) == 1 {
+			in.set(in.params[0].Name, typ, false, fromJava)
+		}
+		return
+	}
+	if isMap(class) {
+		return
+	}
+	e := in.g.classes.Find(scope, class)
+	if e == nil {
+		in.g.warnf(in.line, "没有找到这个类：%s", class)
+		return
+	}
+	for _, p := range in.params {
+		if f := e.Property(p.Name); f != nil {
+			in.set(p.Name, f.Type, f.Repeated, fromJava)
+		}
+	}
+}
+
+// head returns the first segment of a property path.
+func head(path string) string {
+	if i := strings.Index(path, "."); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// jdbcType maps a JDBC type name onto its IR type.
+func jdbcType(name string) (ir.Type, bool) {
+	switch strings.ToUpper(name) {
+	case "VARCHAR", "CHAR", "NVARCHAR", "NCHAR", "LONGVARCHAR", "LONGNVARCHAR", "CLOB", "NCLOB":
+		return ir.Scalar(ir.String), true
+	case "BIGINT":
+		return ir.Scalar(ir.Int64), true
+	case "INTEGER", "SMALLINT", "TINYINT":
+		return ir.Scalar(ir.Int32), true
+	case "BIT", "BOOLEAN":
+		return ir.Scalar(ir.Bool), true
+	case "DOUBLE", "DECIMAL", "NUMERIC":
+		return ir.Scalar(ir.Float64), true
+	case "FLOAT", "REAL":
+		return ir.Scalar(ir.Float32), true
+	case "DATE", "TIME", "TIMESTAMP":
+		return ir.Scalar(ir.Time), true
+	case "BLOB", "BINARY", "VARBINARY", "LONGVARBINARY":
+		return ir.Scalar(ir.Bytes), true
+	}
+	return ir.Type{}, false
+}
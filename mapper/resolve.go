@@ -0,0 +1,156 @@
+package mapper
+
+import (
+	"strings"
+
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+)
+
+// aliases are the MyBatis type aliases not understood by the Java type
+// mapping.
+var aliases = map[string]string{
+	"_byte": "Short", "_long": "Long", "_short": "Short", "_int": "Integer", "_integer": "Integer",
+	"_double": "Double", "_float": "Float", "_boolean": "Boolean",
+	"string": "String", "byte": "Short", "long": "Long", "short": "Short", "int": "Integer",
+	"integer": "Integer", "double": "Double", "float": "Float", "boolean": "Boolean",
+	"date": "Date", "decimal": "BigDecimal", "bigdecimal": "BigDecimal",
+}
+
+// javaType returns the IR type of a Java scalar class or type alias.
+func (g *Generator) javaType(class string) (ir.Type, bool) {
+	if alias, ok := aliases[strings.ToLower(class)]; ok {
+		class = alias
+	}
+	typ, err := g.cfg.JavaType(class)
+	return typ, err == nil
+}
+
+// isMap reports whether a class is a map, which MyBatis maps properties or
+// columns into by name.
+func isMap(class string) bool {
+	switch strings.ToLower(java.SimpleName(class)) {
+	case "map", "hashmap", "linkedhashmap":
+		return true
+	}
+	return false
+}
+
+// result returns the row type of a select, adding the result it refers to
+// to the mapper.
+func (g *Generator) result(c *element) *ir.Param {
+	if id := c.attrs["resultMap"]; id != "" {
+		if r := g.resultMap(g.local(id), 0); r != nil {
+			return &ir.Param{Type: ir.Ref(r.Name)}
+		}
+		g.warnf(c.line, "没有找到这个resultMap：%s", id)
+		return &ir.Param{}
+	}
+	class := c.attrs["resultType"]
+	if class == "" {
+		g.warnf(c.line, "没有resultType或resultMap：%s", c.attrs["id"])
+		return &ir.Param{}
+	}
+	return g.resultType(class, c.line)
+}
+
+// resultType returns the row type of a select returning class, adding the
+// result of an entity to the mapper.
+func (g *Generator) resultType(class string, line int) *ir.Param {
+	if typ, ok := g.javaType(class); ok {
+		return &ir.Param{Type: typ}
+	}
+	if isMap(class) {
+		g.warnf(line, "暂不支持的结果类型：%s", class)
+		return &ir.Param{}
+	}
+	e := g.classes.Find(nil, class)
+	if e == nil {
+		g.warnf(line, "没有找到这个类：%s", class)
+		return &ir.Param{}
+	}
+	name, _ := g.results.Assign("type:"+e.Name, e.Name)
+	r := g.mapper.Result(name)
+	if r == nil {
+		r = &ir.Result{Name: name}
+		if e.Table != "" {
+			r.Entity = e.Name
+		}
+		for _, f := range e.Fields {
+			if f.Type.Kind == ir.Message || f.Repeated {
+				continue
+			}
+			column := f.Column
+			if column == "" {
+				column = strs.SnakeCase(f.Name)
+			}
+			r.Columns = append(r.Columns, &ir.Column{Column: column, Field: f.Name, Type: f.Type})
+		}
+		g.mapper.Results = append(g.mapper.Results, r)
+	}
+	return &ir.Param{Type: ir.Ref(r.Name)}
+}
+
+// resultMap returns the result of the <resultMap> with the given id, or
+// nil if there is none.
+func (g *Generator) resultMap(id string, depth int) *ir.Result {
+	el := g.resultMaps[id]
+	if el == nil || depth >= maxInclude {
+		return nil
+	}
+	class := el.attrs["type"]
+	e := g.classes.Find(nil, class)
+	if e == nil && class != "" && !isMap(class) {
+		g.warnf(el.line, "没有找到这个类：%s", class)
+	}
+	// Results of DOs are ent entities and only need a name of their own.
+	names := []string{g.base + strs.GoCamelCase(id)}
+	if e != nil && e.Table == "" {
+		names = []string{e.Name, names[0]}
+	}
+	name, _ := g.results.Assign(g.mapper.Namespace+"."+id, names...)
+	if r := g.mapper.Result(name); r != nil {
+		return r
+	}
+	r := &ir.Result{Name: name}
+	if e != nil && e.Table != "" {
+		r.Entity = e.Name
+	}
+	if ext := el.attrs["extends"]; ext != "" {
+		if base := g.resultMap(g.local(ext), depth+1); base != nil {
+			r.Columns = append(r.Columns, base.Columns...)
+		} else {
+			g.warnf(el.line, "没有找到这个resultMap：%s", ext)
+		}
+	}
+	for _, c := range el.nodes {
+		switch c.name {
+		case "id", "result":
+			col := &ir.Column{Column: c.attrs["column"], Field: c.attrs["property"], Type: ir.Scalar(ir.String)}
+			if typ, ok := g.javaType(c.attrs["javaType"]); ok {
+				col.Type = typ
+			} else if f := field(e, col.Field); f != nil {
+				col.Type = f.Type
+			} else if typ, ok := jdbcType(c.attrs["jdbcType"]); ok {
+				col.Type = typ
+			}
+			r.Columns = append(r.Columns, col)
+		case "association", "collection", "constructor", "discriminator":
+			g.warnf(c.line, "暂不支持<%s>，已忽略：%s", c.name, c.attrs["property"])
+		}
+	}
+	g.mapper.Results = append(g.mapper.Results, r)
+	return r
+}
+
+// field returns the scalar field of e a property is mapped onto, or nil.
+func field(e *ir.Entity, name string) *ir.Field {
+	if e == nil {
+		return nil
+	}
+	if f := e.Property(name); f != nil && f.Type.Kind != ir.Message && !f.Repeated {
+		return f
+	}
+	return nil
+}
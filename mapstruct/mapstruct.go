@@ -0,0 +1,340 @@
+// Package mapstruct parses MapStruct mappers, the @Mapper interfaces and
+// abstract classes converting between DOs, VOs and requests, into the
+// converters whose Go functions gen/conv generates.
+package mapstruct
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/classes"
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
+	"github.com/pkg/errors"
+)
+
+// IsMapper reports whether a Java file imports MapStruct, as the files of
+// its mappers do.
+func IsMapper(b []byte) bool {
+	return bytes.Contains(b, []byte("import org.mapstruct.")) || bytes.Contains(b, []byte("@org.mapstruct.Mapper"))
+}
+
+// Add parses the MapStruct mappers among srcs into converters of m; other
+// files are skipped. The classes their methods convert are looked up in
+// the entities of m; DOs also by the entity names of their classes, as
+// the tables also defined in SQL are.
+func Add(srcs []gen.Source, m *ir.Model, cfg *config.Config, out *gen.Output) error {
+	c := classes.New(m, cfg.Naming.Entities)
+	names := naming.NewNamer()
+	for _, src := range srcs {
+		b, err := io.ReadAll(src.R)
+		if err != nil {
+			return errors.Wrap(err, src.Path)
+		}
+		if !IsMapper(b) {
+			continue
+		}
+		p := &parser{path: src.Path, classes: c, scope: java.NewScope(), out: out}
+		if cv := p.parse(string(b), names); cv != nil {
+			m.Converters = append(m.Converters, cv)
+		}
+	}
+	return nil
+}
+
+// A parser parses one Java file.
+type parser struct {
+	path    string
+	classes *classes.Classes
+	scope   *java.Scope
+	out     *gen.Output
+	// inherits are the methods inheriting the mappings of another one.
+	inherits []*inherit
+}
+
+// An inherit is a method annotated with @InheritConfiguration or
+// @InheritInverseConfiguration.
+type inherit struct {
+	cv      *ir.Conversion
+	inverse bool
+	name    string // Java name of the method inherited from, if given.
+}
+
+func (p *parser) warnf(line int, format string, args ...interface{}) {
+	p.out.Warnf(p.path, line, format, args...)
+}
+
+var typeRe = regexp.MustCompile(`\b(interface|class)\s+(\w+)`)
+
+// parse parses the mapper declared by a Java file, or returns nil if it
+// declares none.
+func (p *parser) parse(src string, names *naming.Namer) *ir.Converter {
+	var cv *ir.Converter
+	var methods *naming.Namer
+	done := false
+	for _, d := range java.Declarations(src) {
+		if d.Depth == 0 {
+			if p.scope.Line(d.Text + ";") {
+				continue
+			}
+			if cv != nil {
+				done = done || d.Body // another top-level type.
+				continue
+			}
+			list, rest := java.Annotations(d.Text)
+			m := typeRe.FindStringSubmatch(rest)
+			if m == nil || !d.Body || !p.mapper(list, d.Text) || strings.Contains(rest, "@interface") {
+				continue
+			}
+			if m[1] == "class" && !strings.Contains(rest[:strings.Index(rest, m[0])], "abstract") {
+				continue
+			}
+			class := p.scope.Qualify(m[2])
+			name, err := names.Assign(class, strs.GoCamelCase(m[2]))
+			if err != nil {
+				p.warnf(d.Line, "%v", err)
+			}
+			cv = &ir.Converter{Name: name, Comment: d.Comment, Class: class, Source: p.path}
+			methods = naming.NewNamer()
+			continue
+		}
+		if cv == nil || done || d.Body {
+			continue // default and concrete methods.
+		}
+		if c := p.method(d, methods); c != nil {
+			cv.Methods = append(cv.Methods, c)
+		}
+	}
+	if cv == nil {
+		return nil
+	}
+	for _, in := range p.inherits {
+		p.inherit(cv, in)
+	}
+	return cv
+}
+
+// mapper reports whether the annotations of a type declaration include
+// the @Mapper of MapStruct rather than, say, the one of MyBatis.
+func (p *parser) mapper(list []java.Annotation, text string) bool {
+	for _, a := range list {
+		if a.Name != "Mapper" {
+			continue
+		}
+		if strings.Contains(text, "@org.mapstruct.Mapper") || p.scope.Imports["Mapper"] == "org.mapstruct.Mapper" {
+			return true
+		}
+		for _, w := range p.scope.Wildcards {
+			if w == "org.mapstruct" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unsupported are the elements of @Mapping that are not converted.
+var unsupported = []string{"defaultValue", "defaultExpression", "qualifiedByName", "qualifiedBy", "dateFormat", "numberFormat", "conditionExpression", "dependsOn"}
+
+// method parses an abstract method of a mapper into a conversion, or
+// returns nil for other members.
+func (p *parser) method(d *java.Decl, methods *naming.Namer) *ir.Conversion {
+	list, sig := java.Annotations(d.Text)
+	open, end := strings.Index(sig, "("), strings.LastIndex(sig, ")")
+	if open < 0 || end < open || strings.Contains(sig[:open], "=") {
+		return nil // a constant, e.g. INSTANCE.
+	}
+	words := make([]string, 0)
+	for _, w := range strings.Fields(sig[:open]) {
+		switch w {
+		case "public", "protected", "abstract":
+		case "static", "private", "default":
+			return nil
+		default:
+			words = append(words, w)
+		}
+	}
+	if len(words) < 2 {
+		return nil
+	}
+	id := words[len(words)-1]
+	if strings.HasPrefix(words[0], "<") {
+		p.warnf(d.Line, "暂不支持泛型方法：%s", id)
+		return nil
+	}
+	cv := &ir.Conversion{Java: id, Comment: d.Comment, Line: d.Line}
+	var target *ir.Param
+	for _, s := range java.SplitTop(sig[open+1:end], ',') {
+		params, s := java.Annotations(s)
+		s = strings.TrimPrefix(s, "final ")
+		i := strings.LastIndexAny(s, " \t\n")
+		if i < 0 {
+			p.warnf(d.Line, "无法识别的参数：%s", s)
+			return nil
+		}
+		name, decl := strings.TrimSpace(s[i+1:]), strings.TrimSpace(s[:i])
+		context, mappingTarget := false, false
+		for _, a := range params {
+			context = context || a.Name == "Context"
+			mappingTarget = mappingTarget || a.Name == "MappingTarget"
+		}
+		if context {
+			continue // passed through to other mappers.
+		}
+		param := p.param(name, decl, d.Line, id)
+		if param == nil {
+			return nil
+		}
+		if mappingTarget {
+			target = param
+			continue
+		}
+		cv.Params = append(cv.Params, param)
+	}
+	switch result := strings.Join(words[:len(words)-1], " "); {
+	case target != nil:
+		cv.Result, cv.Update = target, true
+	case result == "void":
+		p.warnf(d.Line, "没有返回值也没有@MappingTarget：%s", id)
+		return nil
+	default:
+		if cv.Result = p.param("", result, d.Line, id); cv.Result == nil {
+			return nil
+		}
+	}
+	if len(cv.Params) == 0 {
+		p.warnf(d.Line, "没有来源参数：%s", id)
+		return nil
+	}
+	for _, param := range cv.Params {
+		if param.Repeated != cv.Result.Repeated || param.Repeated && (len(cv.Params) > 1 || cv.Update) {
+			p.warnf(d.Line, "暂不支持的转换方法：%s", id)
+			return nil
+		}
+	}
+	for _, a := range list {
+		switch a.Name {
+		case "Mapping":
+			p.mapping(cv, a.Args)
+		case "Mappings":
+			value := strings.TrimSpace(java.Attrs(a.Args)["value"])
+			value = strings.TrimSuffix(strings.TrimPrefix(value, "{"), "}")
+			for _, s := range java.SplitTop(value, ',') {
+				nested, _ := java.Annotations(s)
+				for _, m := range nested {
+					if m.Name == "Mapping" {
+						p.mapping(cv, m.Args)
+					}
+				}
+			}
+		case "BeanMapping":
+			cv.Explicit = java.Attrs(a.Args)["ignoreByDefault"] == "true"
+		case "InheritConfiguration", "InheritInverseConfiguration":
+			name := java.Unquote(strings.TrimSpace(java.Attrs(a.Args)["name"]))
+			p.inherits = append(p.inherits, &inherit{cv: cv, inverse: a.Name == "InheritInverseConfiguration", name: name})
+		case "IterableMapping", "MapMapping", "ValueMapping", "ValueMappings", "SubclassMapping":
+			p.warnf(d.Line, "暂不支持@%s，已忽略：%s", a.Name, id)
+		}
+	}
+	var err error
+	names := []string{strs.GoCamelCase(id)}
+	if cv.Result.Repeated {
+		names = append(names, strs.GoCamelCase(id)+"List")
+	}
+	cv.Name, err = methods.Assign(id+"("+sig[open+1:end]+")", names...)
+	if err != nil {
+		p.warnf(d.Line, "%v", err)
+	}
+	return cv
+}
+
+// param returns the param of a method declared with a class, or of its
+// result if name is empty. The class must be an entity or a list of
+// entities.
+func (p *parser) param(name, decl string, line int, method string) *ir.Param {
+	class, repeated := java.Element(decl)
+	e := p.classes.Find(p.scope, class)
+	if e == nil {
+		p.warnf(line, "暂不支持的类型 %s，已忽略：%s", decl, method)
+		return nil
+	}
+	return &ir.Param{Name: name, Type: ir.Ref(e.Name), Repeated: repeated}
+}
+
+// mapping adds the @Mapping with the given arguments to a conversion.
+func (p *parser) mapping(cv *ir.Conversion, args string) {
+	attrs := java.Attrs(args)
+	value := func(name string) string {
+		return java.Unquote(strings.TrimSpace(attrs[name]))
+	}
+	m := &ir.Mapping{Target: value("target"), Source: value("source"), Ignore: attrs["ignore"] == "true"}
+	if m.Target == "" {
+		p.warnf(cv.Line, "@Mapping缺少target：%s", cv.Java)
+		return
+	}
+	if _, ok := attrs["constant"]; ok {
+		c := value("constant")
+		m.Constant = &c
+	}
+	if e := value("expression"); e != "" {
+		m.Expression = strings.TrimSuffix(strings.TrimPrefix(e, "java("), ")")
+	}
+	for _, name := range unsupported {
+		if _, ok := attrs[name]; ok {
+			p.warnf(cv.Line, "暂不支持@Mapping的%s，已忽略：%s.%s", name, cv.Java, m.Target)
+		}
+	}
+	cv.Mappings = append(cv.Mappings, m)
+}
+
+// inherit adds the mappings of the method a conversion inherits from to
+// the conversion, but for the targets it maps itself. Inverse mappings
+// swap source and target; ignored targets, constants and expressions are
+// not inverted.
+func (p *parser) inherit(cv *ir.Converter, in *inherit) {
+	var from *ir.Conversion
+	for _, c := range cv.Methods {
+		if c == in.cv || in.name != "" && c.Java != in.name || len(c.Params) != 1 || len(in.cv.Params) != 1 ||
+			c.Result.Repeated != in.cv.Result.Repeated {
+			continue
+		}
+		src, dst := c.Params[0].Type.Name, c.Result.Type.Name
+		if in.inverse {
+			src, dst = dst, src
+		}
+		if src != in.cv.Params[0].Type.Name || dst != in.cv.Result.Type.Name {
+			continue
+		}
+		if from != nil {
+			p.warnf(in.cv.Line, "可继承配置的方法不唯一，已忽略：%s", in.cv.Java)
+			return
+		}
+		from = c
+	}
+	if from == nil {
+		p.warnf(in.cv.Line, "找不到可继承配置的方法：%s", in.cv.Java)
+		return
+	}
+	mapped := make(map[string]bool)
+	for _, m := range in.cv.Mappings {
+		mapped[m.Target] = true
+	}
+	for _, m := range from.Mappings {
+		if in.inverse {
+			if m.Source == "" || m.Ignore || m.Constant != nil || m.Expression != "" {
+				continue
+			}
+			m = &ir.Mapping{Target: m.Source, Source: m.Target}
+		}
+		if !mapped[m.Target] {
+			mapped[m.Target] = true
+			in.cv.Mappings = append(in.cv.Mappings, m)
+		}
+	}
+}
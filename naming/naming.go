@@ -0,0 +1,102 @@
+// Package naming turns Java class names and table names into the names of
+// generated services, messages and ent schemas.
+package naming
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Rule lists the affixes stripped from a name, e.g. the suffix VO of
+// DeviceVO or the table prefix t_ of t_device.
+type Rule struct {
+	Prefixes []string `yaml:"prefixes"`
+	Suffixes []string `yaml:"suffixes"`
+}
+
+// Apply strips the first matching prefix and the first matching suffix
+// from name. Affixes are never stripped down to an empty name.
+func (r Rule) Apply(name string) string {
+	for _, prefix := range r.Prefixes {
+		if len(name) > len(prefix) && strings.HasPrefix(name, prefix) {
+			name = strings.TrimPrefix(name, prefix)
+			break
+		}
+	}
+	for _, suffix := range r.Suffixes {
+		if len(name) > len(suffix) && strings.HasSuffix(name, suffix) {
+			name = strings.TrimSuffix(name, suffix)
+			break
+		}
+	}
+	return name
+}
+
+// A Namer hands out generated names and detects collisions, i.e. two
+// originals that collapse to the same name.
+type Namer struct {
+	names  map[string]string // original -> assigned name.
+	owners map[string]string // assigned name -> original.
+}
+
+// NewNamer returns an empty Namer.
+func NewNamer() *Namer {
+	return &Namer{
+		names:  make(map[string]string),
+		owners: make(map[string]string),
+	}
+}
+
+// Assign reserves the first free name of names for original and returns
+// it. If the first name already belongs to another original, a *Collision
+// is returned along with the name given instead; when every name is taken
+// the last one is numbered. Assigning the same original twice returns the
+// name of the first call.
+func (n *Namer) Assign(original string, names ...string) (string, error) {
+	if assigned, ok := n.names[original]; ok {
+		return assigned, nil
+	}
+	if len(names) == 0 {
+		names = []string{original}
+	}
+	name := names[0]
+	var err error
+	if owner, ok := n.owners[name]; ok {
+		c := &Collision{Original: original, Other: owner, Name: name}
+		for _, name = range names[1:] {
+			if n.owners[name] == "" {
+				break
+			}
+		}
+		last := name
+		for i := 2; n.owners[name] != ""; i++ {
+			name = last + strconv.Itoa(i)
+		}
+		c.Assigned = name
+		err = c
+	}
+	n.names[original] = name
+	n.owners[name] = original
+	return name, err
+}
+
+// Name returns the name assigned to original, or ok false if there is
+// none.
+func (n *Namer) Name(original string) (name string, ok bool) {
+	name, ok = n.names[original]
+	return name, ok
+}
+
+// A Collision is returned by Assign when two originals collapse to the
+// same name.
+type Collision struct {
+	Original string // the original being assigned.
+	Other    string // the original already owning Name.
+	Name     string // the contested name.
+	Assigned string // the name given to Original instead.
+}
+
+func (c *Collision) Error() string {
+	return fmt.Sprintf("名称冲突：%s 和 %s 都转换成 %s，%s 改为 %s", c.Other, c.Original, c.Name, c.Original, c.Assigned)
+}
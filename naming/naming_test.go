@@ -0,0 +1,50 @@
+package naming
+
+import "testing"
+
+func TestRuleApply(t *testing.T) {
+	r := Rule{
+		Prefixes: []string{"t_"},
+		Suffixes: []string{"VO", "DTO", "Request"},
+	}
+	tests := []struct {
+		in, want string
+	}{
+		{"DeviceVO", "Device"},
+		{"DeviceDTO", "Device"},
+		{"t_device", "device"},
+		{"VO", "VO"},
+		{"t_", "t_"},
+		{"Device", "Device"},
+		{"DeviceVODTO", "DeviceVO"},
+	}
+	for _, tc := range tests {
+		if got := r.Apply(tc.in); got != tc.want {
+			t.Errorf("Apply(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestNamerAssign(t *testing.T) {
+	n := NewNamer()
+	if name, err := n.Assign("DeviceVO", "Device", "DeviceVO"); name != "Device" || err != nil {
+		t.Fatalf("Assign(DeviceVO) = %q, %v", name, err)
+	}
+	name, err := n.Assign("DeviceDTO", "Device", "DeviceDTO")
+	if name != "DeviceDTO" {
+		t.Errorf("Assign(DeviceDTO) = %q, want DeviceDTO", name)
+	}
+	c, ok := err.(*Collision)
+	if !ok || c.Other != "DeviceVO" || c.Name != "Device" {
+		t.Errorf("Assign(DeviceDTO) error = %v", err)
+	}
+	if name, err := n.Assign("DeviceVO", "Other"); name != "Device" || err != nil {
+		t.Errorf("second Assign(DeviceVO) = %q, %v", name, err)
+	}
+	if name, ok := n.Name("DeviceDTO"); !ok || name != "DeviceDTO" {
+		t.Errorf("Name(DeviceDTO) = %q, %v", name, ok)
+	}
+	if name, _ := n.Assign("a.Device", "Device", "DeviceDTO"); name != "DeviceDTO2" {
+		t.Errorf("Assign(a.Device) = %q, want DeviceDTO2", name)
+	}
+}
@@ -0,0 +1,185 @@
+package project
+
+import (
+	"bufio"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+)
+
+// A Build is what the Maven or Gradle build files tell about a project.
+type Build struct {
+	GroupID    string
+	ArtifactID string
+	Modules    []Module
+}
+
+// A Module is a Maven module or Gradle subproject.
+type Module struct {
+	Name string // artifactId or project name.
+	Dir  string // directory relative to the project root.
+}
+
+// ReadBuild reads pom.xml, or else settings.gradle(.kts) and
+// build.gradle(.kts), in root. It returns nil if there is neither.
+func ReadBuild(root string) (*Build, error) {
+	if _, err := os.Stat(filepath.Join(root, "pom.xml")); err == nil {
+		b := new(Build)
+		if err := readPom(root, ".", "", b); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	for _, name := range []string{"settings.gradle", "settings.gradle.kts"} {
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			return readGradle(root, name)
+		}
+	}
+	return nil, nil
+}
+
+type pom struct {
+	GroupID    string   `xml:"groupId"`
+	ArtifactID string   `xml:"artifactId"`
+	Modules    []string `xml:"modules>module"`
+	Parent     struct {
+		GroupID string `xml:"groupId"`
+	} `xml:"parent"`
+}
+
+// readPom reads the pom.xml of the module in dir and, recursively, of its
+// modules.
+func readPom(root, dir, groupID string, b *Build) error {
+	data, err := os.ReadFile(filepath.Join(root, dir, "pom.xml"))
+	if err != nil {
+		return err
+	}
+	p := new(pom)
+	if err = xml.Unmarshal(data, p); err != nil {
+		return err
+	}
+	if p.GroupID == "" {
+		p.GroupID = p.Parent.GroupID
+	}
+	if p.GroupID == "" {
+		p.GroupID = groupID
+	}
+	if dir == "." {
+		b.GroupID, b.ArtifactID = p.GroupID, p.ArtifactID
+	} else {
+		b.Modules = append(b.Modules, Module{Name: p.ArtifactID, Dir: filepath.ToSlash(dir)})
+	}
+	for _, m := range p.Modules {
+		if err = readPom(root, filepath.Join(dir, strings.TrimSpace(m)), p.GroupID, b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	gradleName    = regexp.MustCompile(`rootProject\.name\s*=\s*['"]([^'"]+)['"]`)
+	gradleInclude = regexp.MustCompile(`['"]:?([^'"]+)['"]`)
+	gradleGroup   = regexp.MustCompile(`^\s*group\s*=?\s*['"]([^'"]+)['"]`)
+)
+
+// readGradle reads the subprojects from settings.gradle and the group from
+// build.gradle.
+func readGradle(root, settings string) (*Build, error) {
+	f, err := os.Open(filepath.Join(root, settings))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b := &Build{ArtifactID: filepath.Base(root)}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if m := gradleName.FindStringSubmatch(line); m != nil {
+			b.ArtifactID = m[1]
+		} else if strings.HasPrefix(line, "include") {
+			for _, m := range gradleInclude.FindAllStringSubmatch(line, -1) {
+				b.Modules = append(b.Modules, Module{
+					Name: m[1][strings.LastIndex(m[1], ":")+1:],
+					Dir:  strings.Replace(m[1], ":", "/", -1),
+				})
+			}
+		}
+	}
+	if err = s.Err(); err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"build.gradle", "build.gradle.kts"} {
+		data, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if m := gradleGroup.FindStringSubmatch(line); m != nil {
+				b.GroupID = m[1]
+				break
+			}
+		}
+	}
+	return b, nil
+}
+
+// ModulePath proposes a Go module path: the groupId as a domain followed by
+// the artifactId, e.g. "example.com/platform/demo" for com.example.platform
+// and demo.
+func (b *Build) ModulePath() string {
+	segs := make([]string, 0)
+	for _, s := range strings.Split(b.GroupID, ".") {
+		if s != "" {
+			segs = append(segs, strings.ToLower(s))
+		}
+	}
+	elems := make([]string, 0)
+	if len(segs) >= 2 {
+		elems = append(elems, segs[1]+"."+segs[0])
+		elems = append(elems, segs[2:]...)
+	} else {
+		elems = append(elems, segs...)
+	}
+	if b.ArtifactID != "" {
+		elems = append(elems, strings.ToLower(b.ArtifactID))
+	}
+	return strings.Join(elems, "/")
+}
+
+// PackageName proposes the Go package name of a module: its name without
+// the artifactId of the project, lower-cased and without punctuation, e.g.
+// "deviceapi" for demo-device-api.
+func (b *Build) PackageName(m Module) string {
+	name := strings.TrimPrefix(m.Name, b.ArtifactID+"-")
+	var sb strings.Builder
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			sb.WriteRune(c)
+		}
+	}
+	pkg := sb.String()
+	if pkg == "" || (pkg[0] >= '0' && pkg[0] <= '9') {
+		pkg = "x" + pkg
+	}
+	return pkg
+}
+
+// Configure fills in the Go module path and the package names of the
+// modules below root, unless cfg sets them already.
+func (b *Build) Configure(root string, cfg *config.Config) {
+	if cfg.Go.Module == "" {
+		cfg.Go.Module = b.ModulePath()
+	}
+	if len(cfg.Go.Packages) > 0 {
+		return
+	}
+	cfg.Go.Packages = make(map[string]string)
+	for _, m := range b.Modules {
+		cfg.Go.Packages[filepath.Join(root, m.Dir)] = b.PackageName(m)
+	}
+}
@@ -0,0 +1,68 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadPom(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "pom.xml"), `<project>
+  <groupId>com.example.platform</groupId>
+  <artifactId>demo</artifactId>
+  <modules>
+    <module>demo-device-api</module>
+    <module>demo-common</module>
+  </modules>
+</project>`)
+	write(t, filepath.Join(root, "demo-device-api", "pom.xml"), `<project>
+  <parent><groupId>com.example.platform</groupId></parent>
+  <artifactId>demo-device-api</artifactId>
+</project>`)
+	write(t, filepath.Join(root, "demo-common", "pom.xml"), `<project>
+  <artifactId>demo-common</artifactId>
+</project>`)
+	b, err := ReadBuild(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.ModulePath(); got != "example.com/platform/demo" {
+		t.Errorf("ModulePath() = %q", got)
+	}
+	if len(b.Modules) != 2 {
+		t.Fatalf("got modules %+v", b.Modules)
+	}
+	if got := b.PackageName(b.Modules[0]); got != "deviceapi" {
+		t.Errorf("PackageName(%s) = %q", b.Modules[0].Name, got)
+	}
+	if got := b.PackageName(b.Modules[1]); got != "common" {
+		t.Errorf("PackageName(%s) = %q", b.Modules[1].Name, got)
+	}
+}
+
+func TestReadGradle(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "settings.gradle"), "rootProject.name = 'shop'\ninclude 'shop-api', ':services:shop-order'\n")
+	write(t, filepath.Join(root, "build.gradle"), "allprojects {\n}\ngroup = 'io.acme'\n")
+	b, err := ReadBuild(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := b.ModulePath(); got != "acme.io/shop" {
+		t.Errorf("ModulePath() = %q", got)
+	}
+	if len(b.Modules) != 2 || b.Modules[1].Dir != "services/shop-order" || b.Modules[1].Name != "shop-order" {
+		t.Fatalf("got modules %+v", b.Modules)
+	}
+}
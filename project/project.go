@@ -0,0 +1,345 @@
+// Package project converts a whole Spring Boot project in one pass.
+package project
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/luobote55/java2go/cache"
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/ctl"
+	"github.com/luobote55/java2go/do"
+	"github.com/luobote55/java2go/emit"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/gen/ent"
+	"github.com/luobote55/java2go/gen/openapi"
+	"github.com/luobote55/java2go/gen/proto"
+	"github.com/luobote55/java2go/gen/service"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/mapper"
+	"github.com/luobote55/java2go/mapstruct"
+	"github.com/luobote55/java2go/sql"
+	"github.com/spf13/cobra"
+)
+
+// CmdProject represents the project command.
+var CmdProject = &cobra.Command{
+	Use:   "project <root> [output]",
+	Short: "Generate a Kratos layout from a whole Spring Boot project",
+	Long:  "Find the controllers, VO/request classes, DOs and schema SQL files of a Maven/Gradle project and generate api/ and internal/data/ent/schema/. Example: ./j2g.exe project ./demo ./demo-go",
+	Args:  cobra.RangeArgs(1, 2),
+	Run:   run,
+}
+
+const (
+	// SchemaDir is the directory of the ent schemas in the output tree.
+	SchemaDir = "internal/data/ent/schema"
+	// ServiceDir is the directory of the service stubs in the output tree.
+	ServiceDir = "internal/service"
+	// DataDir is the directory of the data-layer repositories.
+	DataDir = "internal/data"
+)
+
+// skip are the directories that never hold project sources.
+var skip = []string{"**/target/**", "**/build/**", "**/out/**", "**/.git/**", "**/.idea/**", "**/node_modules/**", "**/src/test/**"}
+
+var (
+	excludes []string
+	stubs    bool
+	repos    bool
+	convs    bool
+	docs     bool
+)
+
+func init() {
+	CmdProject.Flags().StringSliceVar(&excludes, "exclude", nil, "further glob patterns of the files to skip")
+	CmdProject.Flags().BoolVar(&stubs, "service", false, "also generate Kratos service stubs in "+ServiceDir)
+	CmdProject.Flags().BoolVar(&repos, "repo", false, "also generate data-layer repositories in "+DataDir)
+	CmdProject.Flags().BoolVar(&docs, "openapi", false, "also generate the OpenAPI document "+openapi.FileName)
+	CmdProject.Flags().BoolVar(&convs, "convert", false, "also generate VO and request <-> DO converters and MapStruct mappers in "+DataDir)
+	CmdProject.Flags().BoolVar(&cache.Disabled, "no_cache", false, "convert every source again, ignoring the cache")
+	CmdProject.Flags().BoolVar(&cache.Prune, "prune", false, "remove the unedited files generated from deleted sources")
+}
+
+func run(_ *cobra.Command, args []string) {
+	cfg, err := config.Load(config.File)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	output := "./"
+	if len(args) > 1 {
+		output = args[1]
+	}
+	build, err := ReadBuild(args[0])
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if build != nil {
+		build.Configure(args[0], cfg)
+		fmt.Println("go module：" + cfg.Go.Module)
+		for _, m := range build.Modules {
+			fmt.Printf("  %s -> %s\n", m.Dir, cfg.Package(filepath.Join(args[0], m.Dir), ""))
+		}
+	}
+	layout, err := Discover(args[0], excludes)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("controller：%d，vo/request：%d，do：%d，sql：%d，mapper：%d，mapstruct：%d\n",
+		len(layout.Controllers), len(layout.Models), len(layout.DOs), len(layout.SQL), len(layout.Mappers), len(layout.Converters))
+	if stubs && cfg.Ctl.Service == "" {
+		cfg.Ctl.Service = ServiceDir
+	}
+	if docs && cfg.Ctl.OpenAPI == "" {
+		cfg.Ctl.OpenAPI = openapi.FileName
+	}
+	if convs && cfg.Do.Convert == "" {
+		cfg.Do.Convert = DataDir
+	}
+	if repos && cfg.Do.Repo == "" {
+		cfg.Do.Repo = DataDir
+	}
+	c, err := cache.Open(cfg, "project", args[0], strings.Join(excludes, ","), output)
+	if err == nil {
+		err = c.Hash(layout.Sources())
+	}
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	if c.Unchanged() {
+		fmt.Println("输入没有变化，跳过")
+		return
+	}
+	out, err := Generate(layout, cfg, c)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	gen.PrintDiagnostics(out.Diagnostics)
+	if err = os.MkdirAll(output, 0755); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err = c.WriteFiles(output, out.Files, cfg.Overwrite); err != nil {
+		fmt.Println(err)
+		return
+	}
+	if err = c.Save(); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// A Layout holds the sources of a project by role.
+type Layout struct {
+	Controllers []gen.Source // @RestController and @Controller classes.
+	Models      []gen.Source // other classes, searched for VOs and requests.
+	DOs         []gen.Source // @TableName classes.
+	SQL         []gen.Source // files with CREATE TABLE statements.
+	Mappers     []gen.Source // MyBatis mapper XML files and interfaces.
+	Converters  []gen.Source // MapStruct mappers.
+}
+
+// Discover walks a Maven or Gradle project below root and sorts its Java
+// and SQL files by role. Build output and tests are skipped, as are the
+// files matching exclude.
+func Discover(root string, exclude []string) (*Layout, error) {
+	filter := &gen.Filter{Exclude: append(append([]string{}, skip...), exclude...)}
+	javas, err := gen.ReadSourceRoots([]string{root}, ".java", filter)
+	if err != nil {
+		return nil, err
+	}
+	layout := new(Layout)
+	for _, src := range javas {
+		b, err := io.ReadAll(src.R)
+		if err != nil {
+			return nil, err
+		}
+		src.R = bytes.NewReader(b)
+		switch classify(b) {
+		case "controller":
+			layout.Controllers = append(layout.Controllers, src)
+		case "do":
+			layout.DOs = append(layout.DOs, src)
+		case "mapper":
+			layout.Mappers = append(layout.Mappers, src)
+		case "mapstruct":
+			layout.Converters = append(layout.Converters, src)
+		default:
+			layout.Models = append(layout.Models, src)
+		}
+	}
+	sqls, err := gen.ReadSourceRoots([]string{root}, ".sql", filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range sqls {
+		b, err := io.ReadAll(src.R)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Contains(bytes.ToUpper(b), []byte("CREATE TABLE")) {
+			src.R = bytes.NewReader(b)
+			layout.SQL = append(layout.SQL, src)
+		}
+	}
+	xmls, err := gen.ReadSourceRoots([]string{root}, ".xml", filter)
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range xmls {
+		b, err := io.ReadAll(src.R)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Contains(b, []byte("<mapper")) {
+			src.R = bytes.NewReader(b)
+			layout.Mappers = append(layout.Mappers, src)
+		}
+	}
+	return layout, nil
+}
+
+// Sources returns every source of the layout.
+func (l *Layout) Sources() []gen.Source {
+	srcs := make([]gen.Source, 0)
+	for _, s := range [][]gen.Source{l.Controllers, l.Models, l.DOs, l.SQL, l.Mappers, l.Converters} {
+		srcs = append(srcs, s...)
+	}
+	return srcs
+}
+
+// classify returns the role of a Java class from its annotations. MyBatis
+// mappers import MyBatis or extend the BaseMapper of MyBatis-Plus;
+// MapStruct mappers import MapStruct.
+func classify(b []byte) string {
+	if mapstruct.IsMapper(b) {
+		return "mapstruct"
+	}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, len(b)+1)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if strings.HasPrefix(line, "@RestController") || strings.HasPrefix(line, "@Controller") {
+			return "controller"
+		}
+		if strings.HasPrefix(line, "@TableName") {
+			return "do"
+		}
+		if strings.HasPrefix(line, "import org.apache.ibatis.") || strings.Contains(line, " extends BaseMapper<") {
+			return "mapper"
+		}
+	}
+	return ""
+}
+
+// Generate converts the project into a Kratos layout: the protobuf files
+// below the directories of their go_package options, the ent schemas below
+// SchemaDir and, if cfg.Ctl.Service, cfg.Do.Repo and cfg.Do.Convert are
+// set, the service stubs, the repositories and mapper queries, and the
+// converters below them, and the OpenAPI document if cfg.Ctl.OpenAPI is.
+// A table found both as DO and in SQL is generated from the SQL. The
+// services whose files are fresh in c, which may be nil, are left out.
+func Generate(layout *Layout, cfg *config.Config, c *cache.Cache) (*gen.Output, error) {
+	out := new(gen.Output)
+	m, err := Parse(layout, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	for _, svc := range c.Stale(m).Services {
+		file := proto.Generate(svc, cfg)
+		file.Name = path.Join(proto.Dir(svc, cfg), file.Name)
+		out.Add(file)
+		if cfg.Ctl.Service != "" {
+			file = service.Generate(svc, cfg)
+			file.Name = path.Join(cfg.Ctl.Service, file.Name)
+			out.Add(file)
+		}
+	}
+	if cfg.Ctl.OpenAPI != "" {
+		emit.OpenAPI(m, cfg, cfg.Ctl.OpenAPI, out)
+	}
+	for _, e := range m.Entities {
+		if e.Table == "" {
+			continue
+		}
+		file := ent.Generate(e, cfg)
+		file.Name = path.Join(SchemaDir, file.Name)
+		out.Add(file)
+	}
+	if cfg.Do.Repo != "" {
+		repos := new(gen.Output)
+		emit.Data(m, cfg, repos)
+		emit.Queries(m, cfg, repos)
+		for _, file := range repos.Files {
+			file.Name = path.Join(cfg.Do.Repo, file.Name)
+			out.Add(file)
+		}
+		out.Diagnostics = append(out.Diagnostics, repos.Diagnostics...)
+	}
+	if cfg.Do.Convert != "" {
+		convs := new(gen.Output)
+		emit.Converters(m, cfg, convs)
+		for _, file := range convs.Files {
+			file.Name = path.Join(cfg.Do.Convert, file.Name)
+			out.Add(file)
+		}
+		out.Diagnostics = append(out.Diagnostics, convs.Diagnostics...)
+	}
+	return out, nil
+}
+
+// Parse parses the sources of the project into one model. The mappers
+// are only parsed if their queries are generated, i.e. cfg.Do.Repo is set,
+// and the MapStruct mappers if cfg.Do.Convert is.
+func Parse(layout *Layout, cfg *config.Config, out *gen.Output) (*ir.Model, error) {
+	m, err := ctl.Parse(layout.Controllers, layout.Models, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := sql.Parse(layout.SQL, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	dos, err := do.Parse(layout.DOs, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	defined := make(map[string]*ir.Entity)
+	for _, e := range tables.Entities {
+		defined[e.Table] = e
+	}
+	for _, e := range dos.Entities {
+		if t := defined[e.Table]; t != nil {
+			out.Warnf(e.Source, 0, "表 %s 同时出现在 DO 和 SQL 中，使用 SQL 的定义", e.Table)
+			// the mappers still refer to the table by the class of the DO,
+			// which is named by its file.
+			if t.Class == "" {
+				t.Class = strings.TrimSuffix(path.Base(e.Source), ".java")
+			}
+			continue
+		}
+		tables.Entities = append(tables.Entities, e)
+	}
+	m.Merge(tables)
+	if cfg.Do.Repo != "" {
+		if err = mapper.Add(layout.Mappers, m, cfg, out); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Do.Convert != "" {
+		if err = mapstruct.Add(layout.Converters, m, cfg, out); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,164 @@
+package project
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luobote55/java2go/config"
+)
+
+func TestProject(t *testing.T) {
+	layout, err := Discover("../test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layout.Controllers) != 1 || len(layout.Models) != 2 || len(layout.DOs) != 1 || len(layout.SQL) != 1 || len(layout.Mappers) != 2 || len(layout.Converters) != 1 {
+		t.Fatalf("got %d controllers, %d models, %d DOs, %d SQL files, %d mappers, %d MapStruct mappers",
+			len(layout.Controllers), len(layout.Models), len(layout.DOs), len(layout.SQL), len(layout.Mappers), len(layout.Converters))
+	}
+	out, err := Generate(layout, config.Default(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{
+		"api/device/v1/device_monitor.proto":      true,
+		"internal/data/ent/schema/device_list.go": true,
+		"internal/data/ent/schema/user_list.go":   true,
+	}
+	for _, file := range out.Files {
+		if !want[file.Name] {
+			t.Errorf("unexpected file %s", file.Name)
+		}
+		delete(want, file.Name)
+	}
+	for name := range want {
+		t.Errorf("missing file %s", name)
+	}
+}
+
+func TestProjectRepo(t *testing.T) {
+	layout, err := Discover("../test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Do.Repo = DataDir
+	out, err := Generate(layout, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make(map[string]bool)
+	var src string
+	for _, file := range out.Files {
+		names[file.Name] = true
+		if file.Name == "internal/data/device_list_mapper.go" {
+			src = string(file.Content())
+		}
+	}
+	for _, name := range []string{
+		"internal/data/device_list.go",
+		"internal/data/device_list_mapper.go",
+		"internal/data/mybatis.go",
+	} {
+		if !names[name] {
+			t.Errorf("missing file %s", name)
+		}
+	}
+	for _, d := range out.Diagnostics {
+		if strings.Contains(d.Message, "没有找到这个类") {
+			t.Errorf("unexpected diagnostic %s", d)
+		}
+	}
+	// the DO is replaced by the table of the same name defined in SQL.
+	for _, want := range []string{
+		"func (m *DeviceListMapper) SelectByCondition(ctx context.Context, p *DeviceListSelectByConditionParams) ([]*ent.DeviceList, error) {",
+		"func (m *DeviceListMapper) SelectLatest(ctx context.Context, deviceID int64) (*ent.DeviceList, error) {",
+		"func (m *DeviceListMapper) UpdateExecStatus(ctx context.Context, d *ent.DeviceList) (int64, error) {",
+		"args = append(args, d.ExecStatus, d.ID)",
+		"res, err := m.scanDeviceList(rows)",
+		`case "exec_status":
+				dest[i] = &v.ExecStatus`,
+		`case "machine_id":
+				dest[i] = &v.MachineID`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated mapper does not contain %q", want)
+		}
+	}
+	if strings.Contains(src, "interface{}, error)") || strings.Contains(src, "var v interface{}") {
+		t.Error("generated mapper returns untyped rows")
+	}
+}
+
+func TestProjectConvert(t *testing.T) {
+	layout, err := Discover("../test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := config.Default()
+	cfg.Do.Convert = DataDir
+	out, err := Generate(layout, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var src string
+	for _, file := range out.Files {
+		if file.Name == "internal/data/device_list_convert.go" {
+			src = string(file.Content())
+		}
+	}
+	if src == "" {
+		t.Fatal("missing file internal/data/device_list_convert.go")
+	}
+	for _, want := range []string{
+		"type DeviceListConvert struct{}",
+		"func (c DeviceListConvert) ToVO(d *ent.DeviceList) *devicev1.DeviceMonitorVO {",
+		"t.DeviceName = \"device\"",
+		"t.Status = *d.ExecStatus",
+		"res = append(res, c.ToVO(item))",
+		// inverted from toVO.
+		"t.ExecStatus = &v",
+		"func (c DeviceListConvert) Update(vo *devicev1.DeviceMonitorVO, t *ent.DeviceList) {",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated converter does not contain %q", want)
+		}
+	}
+	if strings.Contains(src, "t.Url") {
+		t.Error("ignored target url is set")
+	}
+}
+
+func TestProjectPackages(t *testing.T) {
+	layout, err := Discover("../test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// as Build.Configure sets them for a Maven module holding the controllers.
+	cfg := config.Default()
+	cfg.Go.Module = "example.com/demo"
+	cfg.Go.Packages = map[string]string{"../test/ctl": "monitor"}
+	cfg.Proto.Package = "api.{package}.v1"
+	cfg.Proto.GoPackage = "{module}/api/{package}/v1;v1"
+	out, err := Generate(layout, cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var src string
+	for _, file := range out.Files {
+		if file.Name == "api/monitor/v1/device_monitor.proto" {
+			src = string(file.Content())
+		}
+	}
+	if src == "" {
+		t.Fatal("missing file api/monitor/v1/device_monitor.proto")
+	}
+	for _, want := range []string{
+		"package api.monitor.v1;",
+		`option go_package = "example.com/demo/api/monitor/v1;v1";`,
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("generated proto does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/ir"
+)
+
+// A unit is a parsed service interface or class.
+type unit struct {
+	name       string // qualified name.
+	comment    string
+	iface      bool
+	service    bool     // annotated with @Service.
+	implements []string // interfaces as written in the file.
+	methods    []*method
+	scope      *java.Scope
+	source     string
+	line       int // line of the declaration.
+}
+
+// A method is a parsed method whose types are still as written in the file.
+type method struct {
+	m      *ir.Method
+	params []string // declared parameter types, in the order of m.Params.
+	result string   // declared result type, "void" if none.
+	line   int
+}
+
+// A Generator represents the state of a single service interface or class
+// being scanned for methods.
+type Generator struct {
+	r       io.Reader
+	path    string // full rooted path name.
+	out     *gen.Output
+	lineNum int // current line number.
+	cfg     *config.Config
+	unit    *unit
+}
+
+var declRe = regexp.MustCompile(`\b(class|interface)\s+(\w+)`)
+
+// run parses the file into a unit. It returns nil if the file declares
+// neither an interface nor a class, or declares a MyBatis mapper.
+func (g *Generator) run() *unit {
+	u := &unit{scope: java.NewScope(), source: g.path}
+	g.unit = u
+
+	// Can't use bufio.Scanner because it can't handle long lines.
+	input := bufio.NewReader(g.r)
+	depth := 0        // brace depth at the start of the line.
+	comment := ""     // first line of the last Javadoc comment.
+	javadoc := false  // inside a Javadoc comment.
+	first := false    // the next Javadoc line is its first.
+	mapper := false   // @Mapper or extends BaseMapper.
+	declared := false // the class or interface declaration was seen.
+	sig := ""         // method signature, which may span several lines.
+	sigLine := 0
+	for {
+		g.lineNum++ // 1-indexed.
+		buf, err := input.ReadSlice('\n')
+		if err != nil {
+			break
+		}
+		line := strings.TrimSpace(string(buf))
+		if javadoc || strings.HasPrefix(line, "/**") {
+			if strings.HasPrefix(line, "/**") {
+				javadoc, first, comment = true, true, ""
+			}
+			text := strings.TrimSpace(strings.TrimPrefix(strings.TrimSuffix(line, "*/"), "/**"))
+			text = strings.TrimSpace(strings.TrimPrefix(text, "*"))
+			if first && text != "" && !strings.HasPrefix(text, "@") {
+				comment, first = text, false
+			}
+			if strings.HasSuffix(line, "*/") {
+				javadoc = false
+			}
+			continue
+		}
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if !declared {
+			if u.scope.Line(line) {
+				continue
+			}
+			if strings.HasPrefix(line, "@Service") {
+				u.service = true
+			} else if strings.HasPrefix(line, "@Mapper") {
+				mapper = true
+			} else if m := declRe.FindStringSubmatch(line); m != nil && !strings.HasPrefix(line, "@") {
+				declared = true
+				u.iface = m[1] == "interface"
+				u.name = u.scope.Qualify(m[2])
+				u.comment, comment = comment, ""
+				u.line = g.lineNum
+				u.implements = supertypes(line, "implements")
+				if u.iface {
+					u.implements = supertypes(line, "extends")
+				}
+				for _, s := range u.implements {
+					if java.SimpleName(s) == "BaseMapper" {
+						mapper = true
+					}
+				}
+			}
+		} else if depth == 1 && sig == "" && isSignature(line) {
+			sig, sigLine = line, g.lineNum
+		} else if depth == 1 && sig == "" && line != "" && !strings.HasPrefix(line, "@") {
+			comment = "" // the comment of a field.
+		} else if sig != "" {
+			sig += " " + line
+		}
+		if sig != "" && signatureDone(sig) {
+			g.method(sig, sigLine, comment)
+			sig, comment = "", ""
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+	}
+	if !declared || mapper {
+		return nil
+	}
+	return u
+}
+
+func (g *Generator) warnf(line int, format string, args ...interface{}) {
+	g.out.Warnf(g.path, line, format, args...)
+}
+
+// supertypes returns the types listed after keyword in a declaration, e.g.
+// "DeviceService" for `public class DeviceServiceImpl implements DeviceService {`.
+func supertypes(decl, keyword string) []string {
+	i := strings.Index(decl, " "+keyword+" ")
+	if i < 0 {
+		return nil
+	}
+	list := decl[i+len(keyword)+2:]
+	for _, end := range []string{"{", " implements ", " extends "} {
+		if j := strings.Index(list, end); j >= 0 {
+			list = list[:j]
+		}
+	}
+	types := make([]string, 0)
+	for _, s := range java.SplitParams(list) {
+		s = strings.TrimSpace(s)
+		if j := strings.Index(s, "<"); j >= 0 {
+			s = s[:j]
+		}
+		if s != "" {
+			types = append(types, s)
+		}
+	}
+	return types
+}
+
+// isSignature reports whether a line in the body of a class starts a
+// method declaration rather than a field, annotation or statement.
+func isSignature(line string) bool {
+	if line == "" || strings.HasPrefix(line, "@") || strings.HasPrefix(line, "*") || strings.HasPrefix(line, "/") || strings.HasPrefix(line, "}") {
+		return false
+	}
+	open := strings.Index(line, "(")
+	return open > 0 && !strings.Contains(line[:open], "=")
+}
+
+// signatureDone reports whether sig holds a complete declaration, up to
+// the method body or the semicolon of an abstract method.
+func signatureDone(sig string) bool {
+	if !strings.Contains(sig, "(") || strings.Count(sig, "(") != strings.Count(sig, ")") {
+		return false
+	}
+	return strings.ContainsAny(sig[strings.LastIndex(sig, ")"):], "{;")
+}
+
+// method parses a method declaration such as
+// `DeviceVO getDevice(Long id);` or `public void remove(@Param("id") Long id) {`.
+// Private, protected and static methods, constructors and generic methods
+// are no part of the service contract and are skipped.
+func (g *Generator) method(sig string, line int, comment string) {
+	open := strings.Index(sig, "(")
+	end := strings.LastIndex(sig, ")")
+	words := strings.Fields(sig[:open])
+	public := g.unit.iface
+modifiers:
+	for len(words) > 0 {
+		switch words[0] {
+		case "public":
+			public = true
+		case "abstract", "default", "final", "synchronized":
+		case "private", "protected", "static":
+			return
+		default:
+			break modifiers
+		}
+		words = words[1:]
+	}
+	if !public || len(words) < 2 {
+		return
+	}
+	if strings.HasPrefix(words[0], "<") {
+		g.warnf(line, "暂不支持泛型方法：%s", sig)
+		return
+	}
+	name := words[len(words)-1]
+	mt := &method{
+		m:      &ir.Method{Java: name, Comment: comment},
+		result: strings.Join(words[:len(words)-1], " "),
+		line:   line,
+	}
+	for _, s := range java.SplitParams(sig[open+1 : end]) {
+		_, s = java.StripAnnotations(s)
+		s = strings.TrimPrefix(s, "final ")
+		index := strings.LastIndex(s, " ")
+		if index < 0 {
+			g.warnf(line, "无法识别的参数：%s", s)
+			continue
+		}
+		typ := strings.Replace(strings.TrimSpace(s[:index]), "...", "[]", 1)
+		if strings.HasPrefix(typ, "HttpServlet") {
+			continue
+		}
+		mt.m.Params = append(mt.m.Params, &ir.Param{Name: s[index+1:]})
+		mt.params = append(mt.params, typ)
+	}
+	g.unit.methods = append(g.unit.methods, mt)
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/ir"
+)
+
+// types resolves the Java types of service methods against the entities
+// parsed from the model classes.
+type types struct {
+	cfg      *config.Config
+	m        *ir.Model
+	byClass  map[string]*ir.Entity
+	bySimple map[string][]*ir.Entity
+}
+
+func newTypes(m *ir.Model, cfg *config.Config) *types {
+	t := &types{
+		cfg:      cfg,
+		m:        m,
+		byClass:  make(map[string]*ir.Entity),
+		bySimple: make(map[string][]*ir.Entity),
+	}
+	for _, e := range m.Entities {
+		class := e.Class
+		if class == "" {
+			class = e.Name
+		}
+		t.byClass[class] = e
+		simple := java.SimpleName(class)
+		t.bySimple[simple] = append(t.bySimple[simple], e)
+	}
+	return t
+}
+
+// resolve returns the IR type of a Java type declared in a file with scope
+// s. Collections and arrays become repeated types of their elements, and
+// DataGrid and MyBatis-Plus pages the page entity of their elements.
+func (t *types) resolve(s *java.Scope, decl string, warnf func(format string, args ...interface{})) (typ ir.Type, repeated bool) {
+	decl = strings.TrimSpace(decl)
+	if strings.HasSuffix(decl, "[]") && decl != "byte[]" {
+		typ, _ = t.resolve(s, strings.TrimSuffix(decl, "[]"), warnf)
+		return typ, true
+	}
+	if i := strings.Index(decl, "<"); i >= 0 && strings.HasSuffix(decl, ">") {
+		args := java.SplitParams(decl[i+1 : len(decl)-1])
+		elem := "Object"
+		if len(args) > 0 {
+			elem = args[len(args)-1]
+		}
+		switch java.SimpleName(decl[:i]) {
+		case "List", "ArrayList", "LinkedList", "Set", "HashSet", "Collection", "Iterable":
+			typ, _ = t.resolve(s, elem, warnf)
+			return typ, true
+		case "Optional":
+			return t.resolve(s, elem, warnf)
+		case "DataGrid", "IPage", "Page":
+			typ, _ = t.resolve(s, elem, warnf)
+			return ir.Ref(t.page(typ).Name), false
+		}
+		warnf("暂不支持的类型：%s", decl)
+		return ir.Scalar(ir.String), false
+	}
+	if typ, err := t.cfg.JavaType(decl); err == nil {
+		return typ, false
+	}
+	e, candidates := t.entity(s, decl)
+	if e != nil {
+		return ir.Ref(e.Name), false
+	}
+	if len(candidates) > 1 {
+		warnf("类型不明确：%s 可能是 %s", decl, strings.Join(candidates, "、"))
+	} else {
+		warnf("没有找到这个message：%s", decl)
+	}
+	return ir.Ref(java.SimpleName(decl)), false
+}
+
+// entity binds a class name used in a file to a model entity, like
+// ctl binds the classes used by controllers. If it fails, it returns the
+// ambiguous candidates, if any.
+func (t *types) entity(s *java.Scope, name string) (*ir.Entity, []string) {
+	for _, q := range s.Candidates(name) {
+		if e, ok := t.byClass[q]; ok {
+			return e, nil
+		}
+	}
+	candidates := t.bySimple[java.SimpleName(name)]
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	names := make([]string, 0, len(candidates))
+	for _, e := range candidates {
+		names = append(names, e.Class)
+	}
+	sort.Strings(names)
+	return nil, names
+}
+
+// page returns the page entity of elem, adding it to the model once.
+func (t *types) page(elem ir.Type) *ir.Entity {
+	page := ir.Page(elem)
+	if e := t.m.Entity(page.Name); e != nil {
+		return e
+	}
+	t.m.Entities = append(t.m.Entities, page)
+	return page
+}
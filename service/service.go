@@ -0,0 +1,219 @@
+// Package service converts Spring service interfaces and implementations
+// into the Kratos biz layer.
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/ctl"
+	"github.com/luobote55/java2go/emit"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/internal/java"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
+	"github.com/spf13/cobra"
+)
+
+// CmdService represents the service command.
+var CmdService = &cobra.Command{
+	Use:   "service [java_dir] [go_dir]",
+	Short: "Generate Kratos biz usecases and repo interfaces from xxxService.java",
+	Long:  "Generate Kratos biz usecases and repo interfaces from xxxService.java and xxxServiceImpl.java. Example: ./j2g.exe service -m ./test/ctl/vo ./demo/service ./internal/biz",
+	Args:  cobra.MaximumNArgs(2),
+	Run:   run,
+}
+
+var (
+	javaPaths  []string
+	modelPaths []string
+	goPath     string
+)
+
+func init() {
+	CmdService.Flags().StringSliceVarP(&javaPaths, "java_path", "p", []string{"./"}, "java service source directories or sources jars")
+	CmdService.Flags().StringSliceVarP(&modelPaths, "model_path", "m", nil, "source directories or sources jars of the VO and DO classes used by the services")
+	CmdService.Flags().StringVarP(&goPath, "output", "o", "./", "biz directory")
+}
+
+// run resolves the paths from, in order of precedence, the arguments, the
+// flags given on the command line, java2go.yaml and the flag defaults.
+func run(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load(config.File)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	flags := cmd.Flags()
+	roots := cfg.Service.Sources
+	if flags.Changed("java_path") || len(roots) == 0 {
+		roots = javaPaths
+	}
+	models := cfg.Service.Models
+	if flags.Changed("model_path") {
+		models = modelPaths
+	}
+	goo := cfg.Service.Output
+	if flags.Changed("output") || goo == "" {
+		goo = goPath
+	}
+	if len(args) > 0 {
+		roots = []string{strings.TrimSpace(args[0])}
+	}
+	if len(args) > 1 {
+		goo = strings.TrimSpace(args[1])
+	}
+	srcs, err := gen.ReadSourceRoots(roots, ".java", nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	modelSrcs, err := gen.ReadSourceRoots(models, ".java", nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	out, err := Generate(srcs, modelSrcs, cfg)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	gen.PrintDiagnostics(out.Diagnostics)
+	if err = gen.WriteFiles(goo, out.Files, cfg.Overwrite); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Generate converts the service interfaces and implementations into biz
+// files. The types of their methods are looked up in the model classes.
+func Generate(srcs, models []gen.Source, cfg *config.Config) (*gen.Output, error) {
+	out := new(gen.Output)
+	m, err := Parse(srcs, models, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	emit.Biz(m, cfg, out)
+	return out, nil
+}
+
+// Parse parses the services into usecases and the model classes into the
+// entities they use. An interface becomes a usecase together with the
+// @Service class implementing it; a @Service class implementing no parsed
+// interface becomes a usecase of its own. MyBatis mappers are skipped.
+func Parse(srcs, models []gen.Source, cfg *config.Config, out *gen.Output) (*ir.Model, error) {
+	m, err := ctl.Parse(nil, models, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	units := make([]*unit, 0)
+	ifaces := make(map[string]*unit)
+	for _, src := range srcs {
+		u, err := generate(src, cfg, out)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			continue
+		}
+		units = append(units, u)
+		if u.iface {
+			ifaces[u.name] = u
+		}
+	}
+	impls := make(map[*unit]*unit)
+	implemented := make(map[*unit]bool)
+	for _, u := range units {
+		if u.iface || !u.service {
+			continue
+		}
+		for _, name := range u.implements {
+			for _, q := range u.scope.Candidates(name) {
+				if i, ok := ifaces[q]; ok {
+					impls[i], implemented[u] = u, true
+					break
+				}
+			}
+		}
+	}
+	t := newTypes(m, cfg)
+	names := naming.NewNamer()
+	for _, u := range units {
+		var uc *ir.Usecase
+		if u.iface {
+			uc = usecase(u, impls[u], t, cfg, names, out)
+		} else if u.service && !implemented[u] {
+			uc = usecase(u, u, t, cfg, names, out)
+		}
+		if uc != nil && len(uc.Methods) > 0 {
+			m.Usecases = append(m.Usecases, uc)
+		}
+	}
+	return m, nil
+}
+
+// usecase returns the usecase of a service interface, or class, u and its
+// implementation impl, which may be nil.
+func usecase(u, impl *unit, t *types, cfg *config.Config, names *naming.Namer, out *gen.Output) *ir.Usecase {
+	simple := java.SimpleName(u.name)
+	name, err := names.Assign(u.name, cfg.Naming.Usecases.Apply(simple), simple)
+	if err != nil {
+		out.Warnf(u.source, u.line, "%v", err)
+	}
+	uc := &ir.Usecase{Name: name, Comment: u.comment, Class: u.name, Source: u.source}
+	comments := make(map[string]string)
+	if impl != nil {
+		uc.Impl = impl.name
+		if uc.Comment == "" {
+			uc.Comment = impl.comment
+		}
+		for _, mt := range impl.methods {
+			if _, ok := comments[mt.m.Java]; !ok {
+				comments[mt.m.Java] = mt.m.Comment
+			}
+		}
+	}
+	methods := naming.NewNamer()
+	for _, mt := range u.methods {
+		line := mt.line
+		warnf := func(format string, args ...interface{}) {
+			out.Warnf(u.source, line, format, args...)
+		}
+		for i, p := range mt.m.Params {
+			p.Type, p.Repeated = t.resolve(u.scope, mt.params[i], warnf)
+		}
+		if mt.result != "void" {
+			typ, repeated := t.resolve(u.scope, mt.result, warnf)
+			mt.m.Result = &ir.Param{Type: typ, Repeated: repeated}
+		}
+		// Go has no overloading.
+		base := strs.GoCamelCase(mt.m.Java)
+		mt.m.Name, err = methods.Assign(mt.m.Java+"("+strings.Join(mt.params, ", ")+")", base, base+ir.ParamsSuffix(mt.m.Params))
+		if err != nil {
+			warnf("%v", err)
+		}
+		if mt.m.Comment == "" {
+			mt.m.Comment = comments[mt.m.Java]
+		}
+		uc.Methods = append(uc.Methods, mt.m)
+	}
+	return uc
+}
+
+// generate parses the specified java file into a unit.
+func generate(src gen.Source, cfg *config.Config, out *gen.Output) (*unit, error) {
+	b, err := io.ReadAll(src.R)
+	if err != nil {
+		return nil, err
+	}
+	g := &Generator{
+		r:    bytes.NewReader(b),
+		path: src.Path,
+		out:  out,
+		cfg:  cfg,
+	}
+	return g.run(), nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/emit"
+	"github.com/luobote55/java2go/gen"
+)
+
+// source returns an in-memory source named path.
+func source(path, src string) gen.Source {
+	return gen.Source{Path: path, R: bytes.NewReader([]byte(src))}
+}
+
+func TestServices(t *testing.T) {
+	iface := source("DeviceService.java", "package com.example.service;\n"+
+		"\n"+
+		"import com.example.vo.DeviceVO;\n"+
+		"\n"+
+		"public interface DeviceService {\n"+
+		"    /**\n"+
+		"     * 查询设备\n"+
+		"     */\n"+
+		"    DeviceVO getDevice(Long id);\n"+
+		"\n"+
+		"    List<DeviceVO> getDevice(List<Long> ids);\n"+
+		"\n"+
+		"    DataGrid<DeviceVO> page(String name,\n"+
+		"                            int size);\n"+
+		"}\n")
+	impl := source("DeviceServiceImpl.java", "package com.example.service.impl;\n"+
+		"\n"+
+		"import com.example.service.DeviceService;\n"+
+		"\n"+
+		"@Service\n"+
+		"public class DeviceServiceImpl implements DeviceService {\n"+
+		"    @Override\n"+
+		"    public DeviceVO getDevice(Long id) {\n"+
+		"        return null;\n"+
+		"    }\n"+
+		"}\n")
+	mapper := source("DeviceMapper.java", "public interface DeviceMapper extends BaseMapper<DeviceDO> {\n"+
+		"    DeviceDO selectByName(String name);\n"+
+		"}\n")
+	vo := source("DeviceVO.java", "package com.example.vo;\n"+
+		"\n"+
+		"public class DeviceVO {\n"+
+		"    private Long id;\n"+
+		"}\n")
+	out := new(gen.Output)
+	m, err := Parse([]gen.Source{iface, impl, mapper}, []gen.Source{vo}, config.Default(), out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Usecases) != 1 {
+		t.Fatalf("got %d usecases, want 1", len(m.Usecases))
+	}
+	uc := m.Usecases[0]
+	if uc.Name != "Device" || uc.Impl != "com.example.service.impl.DeviceServiceImpl" {
+		t.Errorf("usecase = %s implemented by %s", uc.Name, uc.Impl)
+	}
+	var names []string
+	for _, meth := range uc.Methods {
+		names = append(names, meth.Name)
+	}
+	if got, want := strings.Join(names, " "), "GetDevice GetDeviceByInt64List Page"; got != want {
+		t.Errorf("methods = %q, want %q", got, want)
+	}
+	if uc.Methods[0].Comment != "查询设备" || uc.Methods[2].Result.Type.Name != "PageDeviceVO" {
+		t.Errorf("methods = %+v", uc.Methods)
+	}
+	if len(out.Diagnostics) != 1 {
+		t.Errorf("got diagnostics %v, want the overload rename", out.Diagnostics)
+	}
+	biz := new(gen.Output)
+	emit.Biz(m, config.Default(), biz)
+	file := biz.Files[0]
+	for _, want := range []string{
+		"type DeviceRepo interface {",
+		"GetDeviceByInt64List(ctx context.Context, ids []int64) ([]*DeviceVO, error)",
+		"// TODO: port DeviceServiceImpl.getDevice",
+		"return uc.repo.Page(ctx, name, size)",
+	} {
+		if !strings.Contains(string(file.Content()), want) {
+			t.Errorf("generated biz does not contain %q", want)
+		}
+	}
+}
@@ -3,48 +3,102 @@ package sql
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/emit"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/ir"
+	"github.com/luobote55/java2go/naming"
 	"github.com/spf13/cobra"
 )
 
 // CmdSql represents the source command.
 var CmdSql = &cobra.Command{
-	Use:   "sql",
+	Use:   "sql [sql_dir] [go_dir]",
 	Short: "Generate the ent schema code from init-schema.sql",
 	Long:  "Generate the ent schema code from init-schema.sql. Example: ./j2g.exe sql ./test/sql ./test/sql ",
+	Args:  cobra.MaximumNArgs(2),
 	Run:   run,
 }
 
-var sqlPath string
+var (
+	sqlPath string
+	goPath  string
+)
 
 func init() {
 	CmdSql.Flags().StringVarP(&sqlPath, "sql_path", "p", "./", "sql source directory")
+	CmdSql.Flags().StringVarP(&goPath, "output", "o", "./", "ent schema directory")
 }
 
-func run(_ *cobra.Command, args []string) {
-	if len(args) == 0 {
-		fmt.Println("Please enter the sql file or directory")
+// run resolves the paths from, in order of precedence, the arguments, the
+// flags given on the command line, java2go.yaml and the flag defaults.
+func run(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load(config.File)
+	if err != nil {
+		fmt.Println(err)
 		return
 	}
-	var (
-		err error
-		sql = strings.TrimSpace(args[0])
+	sql := cfg.Sql.Sources
+	if cmd.Flags().Changed("sql_path") || len(sql) == 0 {
+		sql = []string{sqlPath}
+	}
+	goo := cfg.Sql.Output
+	if cmd.Flags().Changed("output") || goo == "" {
+		goo = goPath
+	}
+	if len(args) > 0 {
+		sql = []string{strings.TrimSpace(args[0])}
+	}
+	if len(args) > 1 {
 		goo = strings.TrimSpace(args[1])
-	)
-	if strings.HasSuffix(sql, ".sql") {
-		err = generate(sql, goo, args)
-	} else {
-		err = walk(sql, goo, args)
 	}
+	srcs, err := gen.ReadSourceRoots(sql, ".sql", nil)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	out, err := Generate(srcs, cfg)
 	if err != nil {
 		fmt.Println(err)
+		return
+	}
+	gen.PrintDiagnostics(out.Diagnostics)
+	if err = gen.WriteFiles(goo, out.Files, cfg.Overwrite); err != nil {
+		fmt.Println(err)
 	}
 }
 
+// Generate converts the sql sources into ent schema files.
+func Generate(srcs []gen.Source, cfg *config.Config) (*gen.Output, error) {
+	out := new(gen.Output)
+	m, err := Parse(srcs, cfg, out)
+	if err != nil {
+		return nil, err
+	}
+	emit.Ent(m, cfg, out)
+	return out, nil
+}
+
+// Parse parses the CREATE TABLE statements into the intermediate model.
+// Problems found in the sources are recorded in out.
+func Parse(srcs []gen.Source, cfg *config.Config, out *gen.Output) (*ir.Model, error) {
+	m := new(ir.Model)
+	names := naming.NewNamer()
+	for _, src := range srcs {
+		entities, err := generate(src, cfg, names, out)
+		if err != nil {
+			return nil, err
+		}
+		m.Entities = append(m.Entities, entities...)
+	}
+	return m, nil
+}
+
 func look(name ...string) error {
 	for _, n := range name {
 		if _, err := exec.LookPath(n); err != nil {
@@ -54,37 +108,27 @@ func look(name ...string) error {
 	return nil
 }
 
-func walk(dir string, goo string, args []string) error {
-	if dir == "" {
-		dir = "."
-	}
-	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if ext := filepath.Ext(path); ext != ".sql" {
-			return nil
-		}
-		return generate(path, goo, args)
-	})
-}
-
-// generate is used to execute the generate command for the specified proto file
-func generate(sql string, goo string, args []string) error {
-	protoBytes, err := os.ReadFile(sql)
-	if err != nil && len(protoBytes) < 0 {
+// generate parses the tables of the specified sql file.
+func generate(src gen.Source, cfg *config.Config, names *naming.Namer, out *gen.Output) ([]*ir.Entity, error) {
+	protoBytes, err := io.ReadAll(src.R)
+	if err != nil {
+		return nil, err
 	}
 	g := &Generator{
 		r:        bytes.NewReader(protoBytes),
-		path:     sql,
+		path:     src.Path,
 		dir:      "",
 		file:     "",
 		target:   "",
-		goo:      goo,
+		out:      out,
 		pkg:      "",
 		commands: nil,
 		lineNum:  0,
 		env:      nil,
+		cfg:      cfg,
+		names:    names,
 	}
-	g.run()
-	return nil
+	return g.run(), nil
 }
 
 func pathExists(path string) bool {
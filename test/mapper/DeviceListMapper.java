@@ -0,0 +1,55 @@
+package com.example.device.mapper;
+
+import com.baomidou.mybatisplus.core.mapper.BaseMapper;
+import com.example.device.entity.DeviceListDO;
+import org.apache.ibatis.annotations.*;
+
+import java.util.List;
+
+/**
+ * 设备执行记录
+ */
+@Mapper
+public interface DeviceListMapper extends BaseMapper<DeviceListDO> {
+
+    String COLUMNS = "id, device_id, exec_status";
+
+    List<DeviceListDO> selectByCondition(DeviceListDO condition);
+
+    /**
+     * 设备最新的一条记录
+     */
+    @Select("SELECT * FROM device_list WHERE device_id = #{deviceId} AND deleted = 0 " +
+            "ORDER BY create_time DESC LIMIT 1")
+    DeviceListDO selectLatest(@Param("deviceId") Long deviceId);
+
+    // 按状态查询id
+    @Select({"<script>",
+            "SELECT id FROM device_list",
+            "<where>",
+            "  <if test='status != null'>exec_status = #{status}</if>",
+            "  <if test='ids != null and ids.size() > 0'>",
+            "    AND id IN <foreach collection='ids' item='id' open='(' separator=',' close=')'>#{id}</foreach>",
+            "  </if>",
+            "</where>",
+            "</script>"})
+    List<Long> selectIds(@Param("status") Integer status, @Param("ids") List<Long> ids);
+
+    @Insert("INSERT INTO device_list (device_id, exec_status, xxx_path) VALUES (#{deviceId}, #{execStatus}, #{xxxPath})")
+    @Options(useGeneratedKeys = true, keyProperty = "id")
+    int insertOne(DeviceListDO device);
+
+    @Update("UPDATE device_list SET exec_status = #{d.execStatus} WHERE id = #{d.id}")
+    int updateExecStatus(@Param("d") DeviceListDO d);
+
+    @Delete("DELETE FROM ${table} WHERE id = #{id}")
+    int deleteFrom(@Param("table") String table, @Param("id") Long id);
+
+    @SelectProvider(type = DeviceListSqlProvider.class, method = "search")
+    List<DeviceListDO> search(String keyword);
+
+    default DeviceListDO latestOrNull(Long deviceId) {
+        List<Long> ids = selectIds(null, null);
+        return ids.isEmpty() ? null : selectLatest(deviceId);
+    }
+}
@@ -0,0 +1,37 @@
+package com.example.device.convert;
+
+import com.example.device.entity.DeviceListDO;
+import com.example.device.vo.DeviceMonitorVO;
+import org.mapstruct.BeanMapping;
+import org.mapstruct.InheritInverseConfiguration;
+import org.mapstruct.Mapper;
+import org.mapstruct.Mapping;
+import org.mapstruct.MappingTarget;
+import org.mapstruct.factory.Mappers;
+
+import java.util.List;
+
+/**
+ * 设备执行记录转换
+ */
+@Mapper(componentModel = "spring")
+public interface DeviceListConvert {
+
+    DeviceListConvert INSTANCE = Mappers.getMapper(DeviceListConvert.class);
+
+    @Mapping(source = "execStatus", target = "status")
+    @Mapping(target = "deviceName", constant = "device")
+    @Mapping(target = "url", ignore = true)
+    DeviceMonitorVO toVO(DeviceListDO d);
+
+    List<DeviceMonitorVO> toVOs(List<DeviceListDO> list);
+
+    @InheritInverseConfiguration
+    @Mapping(target = "exitCode", constant = "0")
+    DeviceListDO toDO(DeviceMonitorVO vo);
+
+    @BeanMapping(ignoreByDefault = true)
+    @Mapping(source = "status", target = "execStatus")
+    @Mapping(target = "userId", expression = "java(vo.getId())")
+    void update(DeviceMonitorVO vo, @MappingTarget DeviceListDO d);
+}
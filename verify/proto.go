@@ -0,0 +1,410 @@
+package verify
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// A protoFile is what verify reads from a .proto file: the services with
+// the HTTP bindings of their RPCs, and the messages with their fields.
+type protoFile struct {
+	Package  string
+	Services []*protoService
+	Messages []*protoMessage // nested messages are named Outer.Inner.
+}
+
+type protoService struct {
+	Name string
+	RPCs []*protoRPC
+	File string
+	Line int
+}
+
+type protoRPC struct {
+	Name    string
+	Request string
+	Reply   string
+	Method  string // lower-case HTTP verb of the google.api.http option.
+	Path    string
+	Body    string
+	Line    int
+}
+
+type protoMessage struct {
+	Name   string
+	Fields []*protoField
+	File   string
+	Line   int
+}
+
+type protoField struct {
+	Name     string
+	Type     string
+	Repeated bool
+	Line     int
+}
+
+// A token is a word, a quoted string or a punctuation character of a
+// .proto file.
+type token struct {
+	text   string
+	quoted bool
+	line   int
+}
+
+// tokenize splits a .proto file into tokens, dropping comments.
+func tokenize(src string) ([]token, error) {
+	toks := make([]token, 0)
+	line := 1
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, errors.Errorf("%d: 注释没有结束", line)
+			}
+			line += strings.Count(src[i:i+2+end], "\n")
+			i += end + 4
+		case c == '"' || c == '\'':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(src) {
+				return nil, errors.Errorf("%d: 字符串没有结束", line)
+			}
+			s, err := strconv.Unquote(`"` + strings.Replace(src[i+1:j], `"`, `\"`, -1) + `"`)
+			if err != nil {
+				s = src[i+1 : j]
+			}
+			toks = append(toks, token{text: s, quoted: true, line: line})
+			i = j + 1
+		case isWord(c):
+			j := i
+			for j < len(src) && isWord(src[j]) {
+				j++
+			}
+			toks = append(toks, token{text: src[i:j], line: line})
+			i = j
+		default:
+			toks = append(toks, token{text: string(c), line: line})
+			i++
+		}
+	}
+	return toks, nil
+}
+
+func isWord(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '+' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// A protoParser reads the declarations verify needs and skips the rest.
+type protoParser struct {
+	toks []token
+	pos  int
+}
+
+// parseProto parses the content of a .proto file.
+func parseProto(src string) (*protoFile, error) {
+	toks, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &protoParser{toks: toks}
+	f := new(protoFile)
+	for !p.done() {
+		switch t := p.next(); t.text {
+		case "package":
+			f.Package = p.next().text
+			p.skipStatement()
+		case "service":
+			svc, err := p.service(t.line)
+			if err != nil {
+				return nil, err
+			}
+			f.Services = append(f.Services, svc)
+		case "message":
+			if err := p.message(f, "", t.line); err != nil {
+				return nil, err
+			}
+		case "enum", "extend":
+			p.next()
+			if err := p.skipBlock(); err != nil {
+				return nil, err
+			}
+		case ";":
+		default:
+			p.skipStatement()
+		}
+	}
+	return f, nil
+}
+
+func (p *protoParser) done() bool {
+	return p.pos >= len(p.toks)
+}
+
+func (p *protoParser) peek() token {
+	if p.done() {
+		return token{}
+	}
+	return p.toks[p.pos]
+}
+
+func (p *protoParser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *protoParser) expect(text string) error {
+	if t := p.next(); t.text != text || t.quoted {
+		return errors.Errorf("%d: 应该是 %s，实际是 %s", t.line, text, t.text)
+	}
+	return nil
+}
+
+// skipStatement skips to the end of a statement, including a block it
+// may have, e.g. an option with an aggregate value.
+func (p *protoParser) skipStatement() {
+	for !p.done() {
+		switch t := p.next(); t.text {
+		case ";":
+			return
+		case "{":
+			p.pos--
+			if p.skipBlock() != nil {
+				return
+			}
+			if p.peek().text != ";" {
+				return
+			}
+		}
+	}
+}
+
+// skipBlock skips a {...} block and everything nested in it.
+func (p *protoParser) skipBlock() error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for depth := 1; depth > 0; {
+		if p.done() {
+			return errors.New("缺少 }")
+		}
+		t := p.next()
+		if t.quoted {
+			continue
+		}
+		switch t.text {
+		case "{":
+			depth++
+		case "}":
+			depth--
+		}
+	}
+	return nil
+}
+
+func (p *protoParser) service(line int) (*protoService, error) {
+	svc := &protoService{Name: p.next().text, Line: line}
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	for !p.done() {
+		switch t := p.next(); t.text {
+		case "}":
+			return svc, nil
+		case "rpc":
+			rpc, err := p.rpc(t.line)
+			if err != nil {
+				return nil, err
+			}
+			svc.RPCs = append(svc.RPCs, rpc)
+		case ";":
+		default:
+			p.skipStatement()
+		}
+	}
+	return nil, errors.Errorf("%d: service %s 缺少 }", line, svc.Name)
+}
+
+// rpc parses `rpc Name(Request) returns (Reply)` and its options.
+func (p *protoParser) rpc(line int) (*protoRPC, error) {
+	rpc := &protoRPC{Name: p.next().text, Line: line}
+	var err error
+	if rpc.Request, err = p.rpcType(); err != nil {
+		return nil, err
+	}
+	if err = p.expect("returns"); err != nil {
+		return nil, err
+	}
+	if rpc.Reply, err = p.rpcType(); err != nil {
+		return nil, err
+	}
+	if p.peek().text != "{" {
+		return rpc, p.expect(";")
+	}
+	p.next()
+	for !p.done() {
+		t := p.next()
+		switch t.text {
+		case "}":
+			if p.peek().text == ";" {
+				p.next()
+			}
+			return rpc, nil
+		case "option":
+			if err = p.rpcOption(rpc); err != nil {
+				return nil, err
+			}
+		case ";":
+		default:
+			p.skipStatement()
+		}
+	}
+	return nil, errors.Errorf("%d: rpc %s 缺少 }", line, rpc.Name)
+}
+
+// rpcType parses `(Type)`, `(stream Type)`.
+func (p *protoParser) rpcType() (string, error) {
+	if err := p.expect("("); err != nil {
+		return "", err
+	}
+	typ := p.next().text
+	if typ == "stream" && p.peek().text != ")" {
+		typ = p.next().text
+	}
+	return typ, p.expect(")")
+}
+
+// rpcOption parses an option of an rpc, reading the HTTP binding of
+// `option (google.api.http) = {...};` and skipping the others.
+func (p *protoParser) rpcOption(rpc *protoRPC) error {
+	if p.peek().text != "(" {
+		p.skipStatement()
+		return nil
+	}
+	p.next()
+	name := p.next().text
+	if err := p.expect(")"); err != nil {
+		return err
+	}
+	if name != "google.api.http" {
+		p.skipStatement()
+		return nil
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	for !p.done() {
+		key := p.next()
+		switch key.text {
+		case "}":
+			if p.peek().text == ";" {
+				p.next()
+			}
+			return nil
+		case ",", ";":
+			continue
+		}
+		if p.peek().text == ":" {
+			p.next()
+		}
+		if p.peek().text == "{" {
+			// additional_bindings and custom.
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+			continue
+		}
+		value := p.next().text
+		switch key.text {
+		case "get", "put", "post", "delete", "patch":
+			rpc.Method, rpc.Path = key.text, value
+		case "body":
+			rpc.Body = value
+		}
+	}
+	return errors.Errorf("%d: option (google.api.http) 缺少 }", rpc.Line)
+}
+
+// message parses a message and the messages nested in it into f.
+func (p *protoParser) message(f *protoFile, outer string, line int) error {
+	msg := &protoMessage{Name: outer + p.next().text, Line: line}
+	f.Messages = append(f.Messages, msg)
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	return p.fields(f, msg)
+}
+
+// fields parses the body of a message or oneof up to its closing brace.
+func (p *protoParser) fields(f *protoFile, msg *protoMessage) error {
+	for !p.done() {
+		t := p.next()
+		switch t.text {
+		case "}":
+			return nil
+		case ";":
+		case "message":
+			if err := p.message(f, msg.Name+".", t.line); err != nil {
+				return err
+			}
+		case "enum", "extend":
+			p.next()
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+		case "oneof":
+			p.next()
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			if err := p.fields(f, msg); err != nil {
+				return err
+			}
+		case "option", "reserved", "extensions":
+			p.skipStatement()
+		default:
+			field := &protoField{Type: t.text, Line: t.line}
+			switch t.text {
+			case "repeated":
+				field.Repeated = true
+				field.Type = p.next().text
+			case "optional", "required":
+				field.Type = p.next().text
+			case "map":
+				// map<K, V> is a repeated entry message in the wire format.
+				typ := "map"
+				for !p.done() && p.peek().text != ">" {
+					typ += p.next().text
+				}
+				field.Type = typ + p.next().text
+			}
+			field.Name = p.next().text
+			msg.Fields = append(msg.Fields, field)
+			p.skipStatement()
+		}
+	}
+	return errors.Errorf("%d: message %s 缺少 }", msg.Line, msg.Name)
+}
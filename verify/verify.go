@@ -0,0 +1,461 @@
+// Package verify reports the drift between Spring controllers and the
+// protobuf files converted from them, so that the Java and the Go services
+// can be kept in step while both are running.
+package verify
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/ctl"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/gen/proto"
+	"github.com/luobote55/java2go/internal/strs"
+	"github.com/luobote55/java2go/ir"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdVerify represents the verify command.
+var CmdVerify = &cobra.Command{
+	Use:   "verify [java_dir] [proto_dir]",
+	Short: "Report the drift between xxxController.java and existing protobuf files",
+	Long: "Parse the controllers and VOs as ctl does and compare the services, rpcs, HTTP bindings and message fields with the protobuf files below proto_dir. " +
+		"Exits with status 1 if they differ and 2 on errors. Example: ./j2g.exe verify ./test/ctl ./api",
+	Args: cobra.MaximumNArgs(2),
+	Run:  run,
+}
+
+var (
+	controllerPaths []string
+	modelPaths      []string
+	sourcePaths     []string
+	apiDocPaths     []string
+	protoPath       string
+)
+
+func init() {
+	CmdVerify.Flags().StringSliceVarP(&controllerPaths, "controller_path", "c", []string{"./"}, "java controller source directories")
+	CmdVerify.Flags().StringSliceVarP(&modelPaths, "vo_path", "v", []string{"./"}, "java vo and request source directories")
+	CmdVerify.Flags().StringSliceVarP(&sourcePaths, "source", "s", nil, "further source roots or sources jars searched for referenced classes")
+	CmdVerify.Flags().StringSliceVarP(&apiDocPaths, "api_docs", "a", nil, "saved Swagger 2 or OpenAPI 3 JSON documents read instead of the java sources")
+	CmdVerify.Flags().StringVarP(&protoPath, "proto_path", "p", "./", "protobuf file directory")
+}
+
+// run resolves the paths as ctl does and exits with status 1 on drift.
+func run(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load(config.File)
+	if err != nil {
+		fail(err)
+	}
+	flags := cmd.Flags()
+	controllerRoots := cfg.Ctl.Controllers
+	if flags.Changed("controller_path") || len(controllerRoots) == 0 {
+		controllerRoots = controllerPaths
+	}
+	modelRoots := cfg.Ctl.Models
+	if flags.Changed("vo_path") || len(modelRoots) == 0 {
+		modelRoots = modelPaths
+	}
+	sourceRoots := cfg.Ctl.Sources
+	if flags.Changed("source") {
+		sourceRoots = sourcePaths
+	}
+	apiDocs := cfg.Ctl.APIDocs
+	if flags.Changed("api_docs") {
+		apiDocs = apiDocPaths
+	}
+	protoDir := cfg.Ctl.Output
+	if flags.Changed("proto_path") || protoDir == "" {
+		protoDir = protoPath
+	}
+	if len(args) > 0 && strings.HasSuffix(args[0], ".json") {
+		apiDocs = args[:1]
+	} else if len(args) > 0 {
+		controllerRoots = args[:1]
+		modelRoots = args[:1]
+	}
+	if len(args) > 1 {
+		protoDir = args[1]
+	}
+	filter := &gen.Filter{Include: cfg.Ctl.Include, Exclude: cfg.Ctl.Exclude}
+	out := new(gen.Output)
+	var m *ir.Model
+	if len(apiDocs) > 0 {
+		var docs []gen.Source
+		if docs, err = gen.ReadSourceRoots(apiDocs, ".json", nil); err == nil {
+			m, err = ctl.ParseAPIDocs(docs, cfg, out)
+		}
+	} else {
+		var controllers, models []gen.Source
+		if controllers, err = gen.ReadSourceRoots(controllerRoots, ".java", filter); err != nil {
+			fail(err)
+		}
+		if models, err = gen.ReadSourceRoots(append(modelRoots, sourceRoots...), ".java", filter); err != nil {
+			fail(err)
+		}
+		m, err = ctl.Parse(controllers, models, cfg, out)
+	}
+	if err != nil {
+		fail(err)
+	}
+	gen.PrintDiagnostics(out.Diagnostics)
+	drifts, err := Duplicates(m, cfg)
+	if err != nil {
+		fail(err)
+	}
+	found, err := Verify(m, protoDir)
+	if err != nil {
+		fail(err)
+	}
+	drifts = append(drifts, found...)
+	for _, d := range drifts {
+		fmt.Println(d.String())
+	}
+	if len(drifts) > 0 {
+		fmt.Printf("发现 %d 处差异（+ 只在 java 中，- 只在 proto 中，~ 不一致，! 同一 proto 包中重复定义）\n", len(drifts))
+		os.Exit(1)
+	}
+	fmt.Println("没有差异")
+}
+
+func fail(err error) {
+	fmt.Println(err)
+	os.Exit(2)
+}
+
+// Changes of a Drift.
+const (
+	Added     = "+" // only in the Java sources.
+	Removed   = "-" // only in the protobuf files.
+	Changed   = "~" // in both, but different.
+	Duplicate = "!" // defined by several files of one proto package.
+)
+
+// A Drift is a difference between the model parsed from the Java sources
+// and the protobuf files.
+type Drift struct {
+	Change string // Added, Removed, Changed or Duplicate.
+	Kind   string // service, rpc, message or field.
+	Name   string // e.g. DeviceMonitor.GetMonitorConfig or DeviceVO.name.
+	Detail string
+	// File and Line locate the declaration in a protobuf file, or in the
+	// Java sources for additions.
+	File string
+	Line int
+}
+
+func (d *Drift) String() string {
+	msg := d.Change + " " + d.Kind + " " + d.Name
+	if d.Detail != "" {
+		msg += "：" + d.Detail
+	}
+	return gen.Diagnostic{File: d.File, Line: d.Line, Message: msg}.String()
+}
+
+// Check compares the services of m and the messages they use with the
+// protobuf files. Services and rpcs are matched by name, an rpc that was
+// renamed by its HTTP binding; fields are matched by their JSON names, so
+// deviceName and device_name are the same field. Only the messages used by
+// the services are compared.
+//
+// A message defined by several files of one proto package, which protoc
+// rejects, is a Duplicate drift.
+func Check(m *ir.Model, protos []gen.Source) ([]*Drift, error) {
+	c := newChecker()
+	for _, src := range protos {
+		b, err := io.ReadAll(src.R)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parseProto(string(b))
+		if err != nil {
+			return nil, errors.Wrap(err, src.Path)
+		}
+		c.add(src.Path, f)
+	}
+	matched := make(map[*protoService]bool)
+	javaMsgs := make(map[string]bool)
+	for _, svc := range m.Services {
+		for _, msg := range svc.Messages {
+			javaMsgs[msg.Name] = true
+		}
+	}
+	for _, svc := range m.Services {
+		ps := c.services[svc.Name]
+		if ps == nil {
+			c.drift(Added, "service", svc.Name, "", svc.Source, 0)
+			continue
+		}
+		matched[ps] = true
+		c.service(svc, ps)
+	}
+	for _, f := range c.order {
+		for _, ps := range f.Services {
+			if !matched[ps] {
+				c.drift(Removed, "service", ps.Name, "", ps.File, ps.Line)
+			}
+		}
+	}
+	for _, f := range c.order {
+		for _, ps := range f.Services {
+			if matched[ps] {
+				c.unused(f, ps, javaMsgs)
+			}
+		}
+	}
+	return c.drifts, nil
+}
+
+// A checker holds the protobuf files being compared and the drift found.
+type checker struct {
+	order    []*protoFile
+	services map[string]*protoService
+	files    map[*protoService]*protoFile
+	messages map[string]map[string]*protoMessage // by package and name.
+	compared map[*protoMessage]bool
+	drifts   []*Drift
+}
+
+func newChecker() *checker {
+	return &checker{
+		services: make(map[string]*protoService),
+		files:    make(map[*protoService]*protoFile),
+		messages: make(map[string]map[string]*protoMessage),
+		compared: make(map[*protoMessage]bool),
+	}
+}
+
+func (c *checker) add(path string, f *protoFile) {
+	c.order = append(c.order, f)
+	for _, ps := range f.Services {
+		if _, ok := c.services[ps.Name]; !ok {
+			c.services[ps.Name] = ps
+		}
+		c.files[ps] = f
+		ps.File = path
+	}
+	if c.messages[f.Package] == nil {
+		c.messages[f.Package] = make(map[string]*protoMessage)
+	}
+	for _, msg := range f.Messages {
+		msg.File = path
+		if other, ok := c.messages[f.Package][msg.Name]; ok && other.File != path {
+			c.drift(Duplicate, "message", f.Package+"."+msg.Name, "也定义在 "+other.File, path, msg.Line)
+			continue
+		}
+		c.messages[f.Package][msg.Name] = msg
+	}
+}
+
+func (c *checker) drift(change, kind, name, detail, file string, line int) {
+	c.drifts = append(c.drifts, &Drift{Change: change, Kind: kind, Name: name, Detail: detail, File: file, Line: line})
+}
+
+// message returns the message named by a type of package pkg, looking in
+// the other packages if pkg has none.
+func (c *checker) message(pkg, typ string) *protoMessage {
+	typ = strings.TrimPrefix(strings.TrimPrefix(typ, "."), pkg+".")
+	if msg, ok := c.messages[pkg][typ]; ok {
+		return msg
+	}
+	name := simple(typ)
+	for _, f := range c.order {
+		if msg, ok := c.messages[f.Package][name]; ok {
+			return msg
+		}
+	}
+	return nil
+}
+
+// simple strips the package from a type name.
+func simple(typ string) string {
+	return typ[strings.LastIndex(typ, ".")+1:]
+}
+
+// binding returns the HTTP binding of an rpc, e.g. "get /device/list".
+func binding(method, path string) string {
+	if method == "" {
+		return "无 http 选项"
+	}
+	return method + " " + path
+}
+
+// service compares the rpcs of a service and the messages they use.
+func (c *checker) service(svc *ir.Service, ps *protoService) {
+	f := c.files[ps]
+	file := ps.File
+	rpcs := make(map[string]*protoRPC)
+	for _, rpc := range ps.RPCs {
+		rpcs[rpc.Name] = rpc
+	}
+	bound := make(map[string]*protoRPC)
+	for _, rpc := range ps.RPCs {
+		if rpc.Method != "" {
+			bound[rpc.Method+" "+rpc.Path] = rpc
+		}
+	}
+	seen := make(map[*protoRPC]bool)
+	pending := make([]*ir.Endpoint, 0)
+	for _, ep := range svc.Endpoints {
+		if rpc := rpcs[ep.Name]; rpc != nil && !seen[rpc] {
+			seen[rpc] = true
+			c.rpc(svc, ep, rpc, file)
+			continue
+		}
+		pending = append(pending, ep)
+	}
+	// An rpc renamed on either side still has its HTTP binding.
+	for _, ep := range pending {
+		method, path := httpBinding(svc, ep)
+		if rpc := bound[method+" "+path]; rpc != nil && method != "" && !seen[rpc] {
+			seen[rpc] = true
+			c.drift(Changed, "rpc", svc.Name+"."+ep.Name, "名称 proto: "+rpc.Name+"，java: "+ep.Name, file, rpc.Line)
+			c.rpc(svc, ep, rpc, file)
+			continue
+		}
+		c.drift(Added, "rpc", svc.Name+"."+ep.Name, binding(method, path), svc.Source, 0)
+	}
+	for _, rpc := range ps.RPCs {
+		if !seen[rpc] {
+			c.drift(Removed, "rpc", svc.Name+"."+rpc.Name, binding(rpc.Method, rpc.Path), file, rpc.Line)
+		}
+	}
+	for _, msg := range svc.Messages {
+		pm := c.message(f.Package, msg.Name)
+		if pm == nil {
+			c.drift(Added, "message", msg.Name, "", msg.Source, 0)
+			continue
+		}
+		if !c.compared[pm] {
+			c.compared[pm] = true
+			c.fields(msg, pm)
+		}
+	}
+}
+
+// httpBinding returns the HTTP method and path proto generates for ep.
+func httpBinding(svc *ir.Service, ep *ir.Endpoint) (string, string) {
+	if ep.Path == "" {
+		return "", ""
+	}
+	return ep.Method, svc.Path + ep.Path
+}
+
+// rpc compares an endpoint with its rpc.
+func (c *checker) rpc(svc *ir.Service, ep *ir.Endpoint, rpc *protoRPC, file string) {
+	name := svc.Name + "." + ep.Name
+	method, path := httpBinding(svc, ep)
+	if method != rpc.Method {
+		c.drift(Changed, "rpc", name, "HTTP 方法 proto: "+rpc.Method+"，java: "+method, file, rpc.Line)
+	}
+	if path != rpc.Path {
+		c.drift(Changed, "rpc", name, "路径 proto: "+rpc.Path+"，java: "+path, file, rpc.Line)
+	}
+	body := ""
+	if method != "" && ep.Body() {
+		body = "*"
+	}
+	if body != rpc.Body {
+		c.drift(Changed, "rpc", name, "body proto: "+rpc.Body+"，java: "+body, file, rpc.Line)
+	}
+	if simple(rpc.Request) != ep.Request {
+		c.drift(Changed, "rpc", name, "请求 proto: "+rpc.Request+"，java: "+ep.Request, file, rpc.Line)
+	}
+	if simple(rpc.Reply) != ep.Reply {
+		c.drift(Changed, "rpc", name, "响应 proto: "+rpc.Reply+"，java: "+ep.Reply, file, rpc.Line)
+	}
+}
+
+// jsonName returns the JSON name of a field, by which fields are matched.
+func jsonName(name string) string {
+	return strs.JSONCamelCase(strs.LetterCamelCase(name))
+}
+
+// fieldType returns the type of a field as written in a .proto file.
+func fieldType(repeated bool, typ string) string {
+	if repeated {
+		return "repeated " + typ
+	}
+	return typ
+}
+
+// fields compares the fields of a message.
+func (c *checker) fields(msg *ir.Entity, pm *protoMessage) {
+	file := pm.File
+	fields := make(map[string]*protoField)
+	for _, pf := range pm.Fields {
+		fields[jsonName(pf.Name)] = pf
+	}
+	seen := make(map[*protoField]bool)
+	for _, f := range msg.Fields {
+		name := msg.Name + "." + strs.LetterCamelCase(f.Name)
+		want := fieldType(f.Repeated, simple(proto.TypeName(f.Type)))
+		pf := fields[jsonName(f.Name)]
+		if pf == nil {
+			c.drift(Added, "field", name, want, msg.Source, 0)
+			continue
+		}
+		seen[pf] = true
+		if got := fieldType(pf.Repeated, simple(pf.Type)); got != want {
+			c.drift(Changed, "field", name, "类型 proto: "+got+"，java: "+want, file, pf.Line)
+		}
+	}
+	for _, pf := range pm.Fields {
+		if !seen[pf] {
+			c.drift(Removed, "field", msg.Name+"."+pf.Name, fieldType(pf.Repeated, pf.Type), file, pf.Line)
+		}
+	}
+}
+
+// unused reports the messages used by the rpcs of a protobuf service,
+// directly or through their fields, that the Java services do not use.
+func (c *checker) unused(f *protoFile, ps *protoService, javaMsgs map[string]bool) {
+	queue := make([]string, 0)
+	for _, rpc := range ps.RPCs {
+		queue = append(queue, rpc.Request, rpc.Reply)
+	}
+	for len(queue) > 0 {
+		typ := queue[0]
+		queue = queue[1:]
+		pm := c.message(f.Package, typ)
+		if pm == nil || c.compared[pm] {
+			continue
+		}
+		c.compared[pm] = true
+		if !javaMsgs[pm.Name] {
+			c.drift(Removed, "message", pm.Name, "", pm.File, pm.Line)
+		}
+		for _, pf := range pm.Fields {
+			queue = append(queue, pf.Type)
+		}
+	}
+}
+
+// Duplicates reports the messages that several of the protobuf files
+// generated from m define in one proto package, as Check does for existing
+// files.
+func Duplicates(m *ir.Model, cfg *config.Config) ([]*Drift, error) {
+	c := newChecker()
+	for _, svc := range m.Services {
+		file := proto.Generate(svc, cfg)
+		f, err := parseProto(string(file.Content()))
+		if err != nil {
+			return nil, errors.Wrap(err, file.Name)
+		}
+		c.add(file.Name, f)
+	}
+	return c.drifts, nil
+}
+
+// Verify parses the protobuf files below dir and checks m against them.
+func Verify(m *ir.Model, dir string) ([]*Drift, error) {
+	protos, err := gen.ReadSourceRoots([]string{dir}, ".proto", nil)
+	if err != nil {
+		return nil, err
+	}
+	return Check(m, protos)
+}
@@ -0,0 +1,169 @@
+package verify
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/luobote55/java2go/config"
+	"github.com/luobote55/java2go/ctl"
+	"github.com/luobote55/java2go/gen"
+	"github.com/luobote55/java2go/gen/proto"
+	"github.com/luobote55/java2go/ir"
+)
+
+func parse(t *testing.T) (*ir.Model, string) {
+	cfg := config.Default()
+	controllers, err := gen.ReadSources("../test/ctl/controller", ".java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	models, err := gen.ReadSources("../test/ctl", ".java")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(gen.Output)
+	m, err := ctl.Parse(controllers, models, cfg, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := proto.Generate(m.Services[0], cfg)
+	return m, string(file.Content())
+}
+
+func check(t *testing.T, m *ir.Model, src string) []string {
+	drifts, err := Check(m, []gen.Source{{Path: "device_monitor.proto", R: bytes.NewReader([]byte(src))}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(drifts))
+	for _, d := range drifts {
+		got = append(got, d.Change+" "+d.Kind+" "+d.Name+" "+d.Detail)
+	}
+	return got
+}
+
+func TestCheck(t *testing.T) {
+	m, src := parse(t)
+	if got := check(t, m, src); len(got) != 0 {
+		t.Errorf("generated proto drifts:\n%s", strings.Join(got, "\n"))
+	}
+
+	edited := strings.NewReplacer(
+		// renamed rpc with a changed verb
+		"rpc GetMonitorConfig(", "rpc GetConfig(",
+		"get: \"/device/api/monitor/get-config\"", "get: \"/device/api/monitor/config\"",
+		// a field added on the proto side, one changed and one removed
+		"  int32 status = 3;", "  int64 status = 3;\n  /* audit */ string operator = 5 [json_name = \"op\"];",
+		"  string url = 4;                                 // 请求url\n", "",
+	).Replace(src)
+	edited += "\nmessage Unused { string a = 1; }\n"
+	got := check(t, m, edited)
+	want := []string{
+		"+ rpc DeviceMonitor.GetMonitorConfig get /device/api/monitor/get-config",
+		"- rpc DeviceMonitor.GetConfig get /device/api/monitor/config",
+		"+ field DeviceMonitorRequest.url string",
+		"~ field DeviceMonitorVO.status 类型 proto: int64，java: int32",
+		"- field DeviceMonitorVO.operator string",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("drift =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseProto(t *testing.T) {
+	f, err := parseProto(`syntax = "proto3";
+package api.user.v1;
+option go_package = "example.com/api/user/v1;v1";
+
+service User {
+  option (google.api.default_host) = "user";
+  rpc Get(GetRequest) returns (api.user.v1.UserReply) {
+    option (google.api.http) = {
+      get: "/user/{id}"
+      additional_bindings { get: "/v2/user/{id}" }
+    };
+  }
+  rpc Watch(stream GetRequest) returns (stream UserReply);
+}
+
+message UserReply {
+  message Address { string city = 1; }
+  optional string name = 1;
+  map<string, int64> scores = 2;
+  oneof contact {
+    string email = 3;
+    Address address = 4;
+  }
+  reserved 5, 6;
+  enum Kind { A = 0; }
+}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Package != "api.user.v1" || len(f.Services) != 1 {
+		t.Fatalf("file = %+v", f)
+	}
+	rpcs := f.Services[0].RPCs
+	if len(rpcs) != 2 || rpcs[0].Method != "get" || rpcs[0].Path != "/user/{id}" || rpcs[0].Reply != "api.user.v1.UserReply" || rpcs[1].Request != "GetRequest" {
+		t.Errorf("rpcs = %+v %+v", rpcs[0], rpcs[1])
+	}
+	var names []string
+	for _, msg := range f.Messages {
+		for _, field := range msg.Fields {
+			names = append(names, msg.Name+"."+field.Name+":"+field.Type)
+		}
+	}
+	if got, want := strings.Join(names, ","), "UserReply.name:string,UserReply.scores:map<string,int64>,UserReply.email:string,UserReply.address:Address,UserReply.Address.city:string"; got != want {
+		t.Errorf("fields = %s, want %s", got, want)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	file := func(svc string) gen.Source {
+		return gen.Source{Path: svc + ".proto", R: strings.NewReader("syntax = \"proto3\";\n" +
+			"package api.demo.v1;\n" +
+			"service " + svc + " {\n" +
+			"  rpc Get(GetRequest) returns (ItemVO);\n" +
+			"}\n" +
+			"message GetRequest { int64 id = 1; }\n" +
+			"message ItemVO { int64 id = 1; }\n")}
+	}
+	drifts, err := Check(new(ir.Model), []gen.Source{file("Device"), file("User")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, d := range drifts {
+		if d.Change == Duplicate {
+			got = append(got, d.Name+" "+d.File)
+		}
+	}
+	if want := "api.demo.v1.GetRequest User.proto,api.demo.v1.ItemVO User.proto"; strings.Join(got, ",") != want {
+		t.Errorf("duplicates = %q, want %q", strings.Join(got, ","), want)
+	}
+
+	// the files generated from a model whose services share a package.
+	cfg := config.Default()
+	cfg.Proto.Package = "api.demo.v1"
+	m := new(ir.Model)
+	for _, name := range []string{"Device", "User"} {
+		m.Services = append(m.Services, &ir.Service{
+			Name:      name,
+			Path:      "/" + strings.ToLower(name),
+			Endpoints: []*ir.Endpoint{{Name: "Get", Request: "GetRequest", Reply: "ItemVO"}},
+			Messages:  []*ir.Entity{{Name: "GetRequest"}, {Name: "ItemVO"}},
+		})
+	}
+	if drifts, err = Duplicates(m, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(drifts) != 2 || drifts[0].Change != Duplicate || drifts[0].File != "user.proto" {
+		t.Errorf("got drifts %v, want GetRequest and ItemVO of user.proto", drifts)
+	}
+	cfg.Proto.Package = config.Default().Proto.Package
+	if drifts, err = Duplicates(m, cfg); err != nil || len(drifts) != 0 {
+		t.Errorf("got drifts %v, %v for distinct packages", drifts, err)
+	}
+}